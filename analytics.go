@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HoleStats is the scoring breakdown for a single hole.
+type HoleStats struct {
+	Hole       int     `json:"hole"`
+	Par        int     `json:"par"`
+	Handicap   int     `json:"handicap,omitempty"`
+	Rounds     int     `json:"rounds"`
+	Average    float64 `json:"average"`
+	ToPar      float64 `json:"toPar"`
+	Eagles     int     `json:"eagles"` // Eagle or better
+	Birdies    int     `json:"birdies"`
+	Pars       int     `json:"pars"`
+	Bogeys     int     `json:"bogeys"`
+	DoublePlus int     `json:"doublePlus"`
+	Difficulty int     `json:"difficulty"` // 1 is the hardest hole
+
+	// Populated from the skins leaderboard when one was posted.
+	LowScore   string `json:"lowScore,omitempty"`
+	SkinWinner string `json:"skinWinner,omitempty"`
+	SkinsWon   int    `json:"skinsWon"`
+}
+
+type HoleDifficulty struct {
+	EventID  string      `json:"eventID,omitempty"`
+	CourseID uint        `json:"courseID,omitempty"`
+	Course   string      `json:"course"`
+	Events   []string    `json:"events"`
+	Rounds   int         `json:"rounds"`
+	Holes    []HoleStats `json:"holes"`
+	Hardest  []int       `json:"hardest"`
+	Easiest  []int       `json:"easiest"`
+}
+
+// buildHoleDifficulty aggregates gross hole scores from scorecards and skins
+// hole results into per hole statistics. Course holes, when given, supply the
+// par and stroke index for holes that have no scores.
+func buildHoleDifficulty(cards []Scorecard, skins []SkinsHolesResult, courseHoles []CourseHole) HoleDifficulty {
+	stats := make(map[int]*HoleStats)
+	get := func(hole int) *HoleStats {
+		h, ok := stats[hole]
+		if !ok {
+			h = &HoleStats{Hole: hole}
+			stats[hole] = h
+		}
+		return h
+	}
+
+	for _, ch := range courseHoles {
+		h := get(ch.Hole)
+		h.Par = ch.Par
+		h.Handicap = ch.Handicap
+	}
+
+	var rounds int
+	totals := make(map[int]int)
+	overPar := make(map[int]int)
+	for _, card := range cards {
+		rounds++
+		for _, hs := range card.Holes {
+			if hs.Gross <= 0 || hs.Par <= 0 {
+				continue
+			}
+			h := get(hs.Hole)
+			if h.Par == 0 {
+				h.Par = hs.Par
+			}
+			if h.Handicap == 0 {
+				h.Handicap = hs.Handicap
+			}
+			h.Rounds++
+			totals[hs.Hole] += hs.Gross
+			overPar[hs.Hole] += hs.Gross - hs.Par
+			switch diff := hs.Gross - hs.Par; {
+			case diff <= -2:
+				h.Eagles++
+			case diff == -1:
+				h.Birdies++
+			case diff == 0:
+				h.Pars++
+			case diff == 1:
+				h.Bogeys++
+			default:
+				h.DoublePlus++
+			}
+		}
+	}
+
+	for _, sk := range skins {
+		hole, err := strconv.Atoi(strings.TrimSpace(sk.Hole))
+		if err != nil {
+			continue
+		}
+		h := get(hole)
+		if h.Par == 0 {
+			h.Par, _ = strconv.Atoi(strings.TrimSpace(sk.Par))
+		}
+		h.LowScore = sk.Score
+		h.SkinWinner = sk.Won
+		if sk.Won != "" {
+			h.SkinsWon++
+		}
+	}
+
+	result := HoleDifficulty{Rounds: rounds, Holes: []HoleStats{}, Hardest: []int{}, Easiest: []int{}}
+	for hole, h := range stats {
+		if h.Rounds > 0 {
+			h.Average = roundTo(float64(totals[hole])/float64(h.Rounds), 2)
+			h.ToPar = roundTo(float64(overPar[hole])/float64(h.Rounds), 2)
+		}
+		result.Holes = append(result.Holes, *h)
+	}
+	sort.Slice(result.Holes, func(i, j int) bool { return result.Holes[i].Hole < result.Holes[j].Hole })
+
+	// Rank the holes that have scores from hardest to easiest.
+	var ranked []*HoleStats
+	for i := range result.Holes {
+		if result.Holes[i].Rounds > 0 {
+			ranked = append(ranked, &result.Holes[i])
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].ToPar != ranked[j].ToPar {
+			return ranked[i].ToPar > ranked[j].ToPar
+		}
+		return ranked[i].Hole < ranked[j].Hole
+	})
+	for i, h := range ranked {
+		h.Difficulty = i + 1
+	}
+	// Split the top three each way, so short rounds don't list a hole as
+	// both.
+	for i := 0; i < len(ranked)/2 && i < 3; i++ {
+		result.Hardest = append(result.Hardest, ranked[i].Hole)
+		result.Easiest = append(result.Easiest, ranked[len(ranked)-1-i].Hole)
+	}
+	return result
+}
+
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
+
+func (s *Server) loadScorecards(eventIDs []string) ([]Scorecard, error) {
+	var cards []Scorecard
+	err := s.db.Preload("Holes").Where("event_id IN ?", eventIDs).Find(&cards).Error
+	return cards, err
+}
+
+// GET /api/results/holes/{eventID}
+func (s *Server) GETEventHoleStats(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	var event Event
+	if err := s.visibleEvents(r).First(&event, "event_id = ?", eventID).Error; err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	cards, err := s.loadScorecards([]string{eventID})
+	if err != nil {
+		http.Error(w, "Error fetching scorecards", http.StatusInternalServerError)
+		return
+	}
+	var skins []SkinsHolesResult
+	if err := s.db.Where("event_id = ?", eventID).Find(&skins).Error; err != nil {
+		http.Error(w, "Error fetching skins results", http.StatusInternalServerError)
+		return
+	}
+
+	var courseHoles []CourseHole
+	if _, tee, err := s.loadEventTee(&event); err == nil {
+		courseHoles = tee.Holes
+	}
+
+	result := buildHoleDifficulty(cards, skins, courseHoles)
+	result.EventID = eventID
+	result.CourseID = event.CourseID
+	result.Course = event.Course
+	result.Events = []string{eventID}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GET /api/courses/{courseID}/holes
+// Aggregates every event played at the course, matching events either by
+// their linked course or, for older events, by course name.
+func (s *Server) GETCourseHoleStats(w http.ResponseWriter, r *http.Request) {
+	id, err := courseIDParam(r)
+	if err != nil {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+	course, err := s.loadCourse(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Course not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Par and stroke index come from the requested tee, defaulting to the
+	// highest rated one.
+	var courseHoles []CourseHole
+	if tee := r.URL.Query().Get("tee"); tee != "" {
+		found := false
+		for _, t := range course.Tees {
+			if strings.EqualFold(t.Name, tee) {
+				courseHoles, found = t.Holes, true
+			}
+		}
+		if !found {
+			http.Error(w, "Tee not found", http.StatusBadRequest)
+			return
+		}
+	} else if len(course.Tees) > 0 {
+		courseHoles = course.Tees[0].Holes
+	}
+
+	var events []Event
+	if err := s.visibleEvents(r).Where("course_id = ? OR LOWER(course) = LOWER(?)", course.ID, course.Name).
+		Order("date_string ASC").Find(&events).Error; err != nil {
+		http.Error(w, "Error fetching events", http.StatusInternalServerError)
+		return
+	}
+	eventIDs := make([]string, 0, len(events))
+	for _, e := range events {
+		eventIDs = append(eventIDs, e.EventID)
+	}
+
+	cards, err := s.loadScorecards(eventIDs)
+	if err != nil {
+		http.Error(w, "Error fetching scorecards", http.StatusInternalServerError)
+		return
+	}
+	var skins []SkinsHolesResult
+	if err := s.db.Where("event_id IN ?", eventIDs).Find(&skins).Error; err != nil {
+		http.Error(w, "Error fetching skins results", http.StatusInternalServerError)
+		return
+	}
+
+	result := buildHoleDifficulty(cards, skins, courseHoles)
+	for i := range result.Holes {
+		// Low scores and winners only make sense for a single event.
+		result.Holes[i].LowScore = ""
+		result.Holes[i].SkinWinner = ""
+	}
+	result.CourseID = course.ID
+	result.Course = course.Name
+	result.Events = eventIDs
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_buildHoleDifficulty(t *testing.T) {
+	cards := []Scorecard{
+		{Holes: []HoleScore{{Hole: 1, Par: 4, Gross: 6}, {Hole: 2, Par: 5, Gross: 3}, {Hole: 3, Par: 3, Gross: 3}}},
+		{Holes: []HoleScore{{Hole: 1, Par: 4, Gross: 5}, {Hole: 2, Par: 5, Gross: 4}, {Hole: 3, Par: 3, Gross: 4}}},
+		{Holes: []HoleScore{{Hole: 1, Par: 4, Gross: 4}, {Hole: 2, Par: 5, Gross: 5}, {Hole: 3, Par: 3, Gross: 0}}},
+	}
+	skins := []SkinsHolesResult{{Hole: "2", Par: "5", Score: "3", Won: "Connor Shaw"}}
+
+	d := buildHoleDifficulty(cards, skins, nil)
+	assert.Equal(t, 3, d.Rounds)
+	assert.Len(t, d.Holes, 3)
+
+	h1 := d.Holes[0]
+	assert.Equal(t, 3, h1.Rounds)
+	assert.Equal(t, 5.0, h1.Average)
+	assert.Equal(t, 1.0, h1.ToPar)
+	assert.Equal(t, []int{1, 1, 1}, []int{h1.Pars, h1.Bogeys, h1.DoublePlus})
+	assert.Equal(t, 1, h1.Difficulty)
+
+	h2 := d.Holes[1]
+	assert.Equal(t, []int{1, 1, 1}, []int{h2.Eagles, h2.Birdies, h2.Pars})
+	assert.Equal(t, "Connor Shaw", h2.SkinWinner)
+	assert.Equal(t, 1, h2.SkinsWon)
+	assert.Equal(t, 3, h2.Difficulty)
+
+	// Incomplete holes are not counted.
+	assert.Equal(t, 2, d.Holes[2].Rounds)
+	assert.Equal(t, 0.5, d.Holes[2].ToPar)
+
+	// Three holes only leave room for one each way.
+	assert.Equal(t, []int{1}, d.Hardest)
+	assert.Equal(t, []int{2}, d.Easiest)
+}
+
+func TestServer_GETCourseHoleStats(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	course := testCourse()
+	assert.NoError(t, db.Create(&course).Error)
+
+	r := chi.NewRouter()
+	r.Get("/api/courses/{courseID}/holes", s.GETCourseHoleStats)
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/courses/%d/holes%s", course.ID, query), nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, get("").Code)
+	assert.Equal(t, http.StatusOK, get("?tee=blue").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?tee=Red").Code)
+}
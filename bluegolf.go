@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// blueGolfSite is a club's season on BlueGolf. The club is the subdomain
+// (nhgaclub.bluegolf.com) and every page of the season lives under
+// /bluegolfw/{season}/, e.g. nhgaclublivefreegc25.
+type blueGolfSite struct {
+	Club   string `json:"club"`
+	Season string `json:"season"`
+}
+
+func (b blueGolfSite) base() string {
+	return fmt.Sprintf("https://%s.bluegolf.com/bluegolfw/%s/", b.Club, b.Season)
+}
+
+// EventURL is a page of a BlueGolf event, e.g. "index.htm" or "pairings.htm".
+func (b blueGolfSite) EventURL(eventID, page string) string {
+	return fmt.Sprintf("%sevent/%s/%s", b.base(), eventID, page)
+}
+
+func (b blueGolfSite) PairingsURL(eventID string) string {
+	return b.EventURL(eventID, "pairings.htm")
+}
+
+func (b blueGolfSite) HandicapURL() string {
+	return b.base() + "handicap/index.htm"
+}
+
+// scorecardURL is a player's scorecard for the contest behind a leaderboard.
+func scorecardURL(leaderboardURL, playerID string) string {
+	base := strings.TrimSuffix(leaderboardURL, "leaderboard.htm")
+	return fmt.Sprintf("%scontestant/%s/scorecard.htm", base, playerID)
+}
+
+func parseBlueGolf(raw string) (club, contest string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Split into path segments
+	segs := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+
+	// Find the segment after "bluegolfw" and after "poy"
+	var iBlue, iPoy = -1, -1
+	for i, s := range segs {
+		if s == "bluegolfw" {
+			iBlue = i
+		} else if s == "poy" {
+			iPoy = i
+		}
+	}
+
+	if iBlue < 0 || iBlue+1 >= len(segs) || iPoy < 0 || iPoy+1 >= len(segs) {
+		return "", "", fmt.Errorf("unexpected path format: %q", u.Path)
+	}
+
+	return segs[iBlue+1], segs[iPoy+1], nil
+}
+
+// parseBlueGolfSite reads the club and season from a season standings URL.
+func parseBlueGolfSite(raw string) (blueGolfSite, error) {
+	season, _, err := parseBlueGolf(raw)
+	if err != nil {
+		return blueGolfSite{}, err
+	}
+	u, _ := url.Parse(raw)
+	club, _, _ := strings.Cut(u.Hostname(), ".")
+	if club == "" {
+		return blueGolfSite{}, fmt.Errorf("no BlueGolf club in %q", raw)
+	}
+	return blueGolfSite{Club: club, Season: season}, nil
+}
+
+// blueGolfEventID is the BlueGolf ID of an event, taken from the segment
+// after "event" or "events" in its BlueGolf URL.
+func blueGolfEventID(event *Event) (string, error) {
+	u, err := url.Parse(event.BlueGolfUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid BlueGolf URL: %w", err)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, p := range parts {
+		if (p == "event" || p == "events") && i+1 < len(parts) {
+			return parts[i+1], nil
+		}
+	}
+	return "", fmt.Errorf("no BlueGolf event ID in %q", event.BlueGolfUrl)
+}
+
+// eventBlueGolfSite resolves an event's BlueGolf site. The event's own
+// club and season win, then those of the standings for the event's year.
+// Failing both, the club is taken from the event's BlueGolf URL and, when
+// it points inside a season, the season too.
+func eventBlueGolfSite(db *gorm.DB, event *Event) (blueGolfSite, error) {
+	site := blueGolfSite{Club: event.BlueGolfClub, Season: event.BlueGolfSeason}
+	if site.Club == "" || site.Season == "" {
+		var standings Standings
+		if len(event.DateString) >= 4 {
+			if err := db.Where("calendar_year = ?", event.DateString[:4]).Limit(1).Find(&standings).Error; err != nil {
+				return site, err
+			}
+		}
+		if site.Club == "" {
+			site.Club = standings.BlueGolfClub
+		}
+		if site.Season == "" {
+			site.Season = standings.BlueGolfSeason
+		}
+	}
+	if site.Club == "" || site.Season == "" {
+		if u, err := url.Parse(event.BlueGolfUrl); err == nil {
+			if site.Club == "" {
+				site.Club, _, _ = strings.Cut(u.Hostname(), ".")
+			}
+			parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+			if site.Season == "" && len(parts) > 2 && parts[0] == "bluegolfw" && parts[2] == "event" {
+				site.Season = parts[1]
+			}
+		}
+	}
+	if site.Club == "" || site.Season == "" {
+		return site, fmt.Errorf("no BlueGolf season configured for %s", event.Name)
+	}
+	return site, nil
+}
+
+// GET /api/events/{eventID}/bluegolf
+func (s *Server) GETEventBlueGolf(w http.ResponseWriter, r *http.Request) {
+	var event Event
+	if err := s.visibleEvents(r).First(&event, "event_id = ?", chi.URLParam(r, "eventID")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Event not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	blueGolfID, err := blueGolfEventID(&event)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	site, err := eventBlueGolfSite(s.db, &event)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"club":        site.Club,
+		"season":      site.Season,
+		"eventID":     blueGolfID,
+		"eventUrl":    site.EventURL(blueGolfID, "index.htm"),
+		"pairingsUrl": site.PairingsURL(blueGolfID),
+		"handicapUrl": site.HandicapURL(),
+	})
+}
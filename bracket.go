@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+)
+
+// BracketSlot is one side of a bracket match. FeederMatchID is the match in
+// the previous round whose winner fills the slot.
+type BracketSlot struct {
+	Player        string `json:"player"`
+	Seed          int    `json:"seed,omitempty"`
+	FeederMatchID *uint  `json:"feederMatchID"`
+}
+
+type BracketMatch struct {
+	ID          uint           `json:"id"`
+	MatchNum    int            `json:"matchNum"`
+	Slots       [2]BracketSlot `json:"slots"`
+	Winner      string         `json:"winner"`
+	Score       string         `json:"score"`
+	Status      string         `json:"status"`
+	NextMatchID *uint          `json:"nextMatchID"`
+}
+
+type BracketRound struct {
+	Round   int            `json:"round"`
+	Name    string         `json:"name"`
+	Matches []BracketMatch `json:"matches"`
+}
+
+// Bracket is a season's match play bracket arranged round by round.
+type Bracket struct {
+	Year     string         `json:"year"`
+	Rounds   []BracketRound `json:"rounds"`
+	Champion string         `json:"champion"`
+}
+
+// buildBracket arranges a season's matches into a bracket. Generated
+// brackets carry their round numbers. Scraped brackets only have round
+// labels, so rounds are taken in the order they were stored.
+func buildBracket(year string, matches []MatchPlayMatch) Bracket {
+	b := Bracket{Year: year, Rounds: []BracketRound{}}
+	if len(matches) == 0 {
+		return b
+	}
+
+	sorted := make([]MatchPlayMatch, len(matches))
+	copy(sorted, matches)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	roundOf := make(map[string]int)
+	for _, m := range sorted {
+		if m.RoundNum > 0 {
+			continue
+		}
+		if _, ok := roundOf[m.Round]; !ok {
+			roundOf[m.Round] = len(roundOf) + 1
+		}
+	}
+
+	byRound := make(map[int][]MatchPlayMatch)
+	names := make(map[int]string)
+	maxRound := 0
+	for _, m := range sorted {
+		r := m.RoundNum
+		if r == 0 {
+			r = roundOf[m.Round]
+		}
+		byRound[r] = append(byRound[r], m)
+		names[r] = m.Round
+		if r > maxRound {
+			maxRound = r
+		}
+	}
+
+	for r := 1; r <= maxRound; r++ {
+		ms := byRound[r]
+		sort.SliceStable(ms, func(i, j int) bool { return ms[i].MatchNum < ms[j].MatchNum })
+		round := BracketRound{Round: r, Name: names[r], Matches: make([]BracketMatch, 0, len(ms))}
+		for _, m := range ms {
+			round.Matches = append(round.Matches, BracketMatch{
+				ID:       m.ID,
+				MatchNum: m.MatchNum,
+				Slots: [2]BracketSlot{
+					{Player: m.Player1, Seed: m.Seed1},
+					{Player: m.Player2, Seed: m.Seed2},
+				},
+				Winner: m.Winner,
+				Score:  m.Score,
+				Status: m.Status,
+			})
+		}
+		b.Rounds = append(b.Rounds, round)
+	}
+
+	// Link each match to the two matches that feed it.
+	for r := 1; r < len(b.Rounds); r++ {
+		prev := make(map[int]*BracketMatch)
+		for i := range b.Rounds[r-1].Matches {
+			prev[b.Rounds[r-1].Matches[i].MatchNum] = &b.Rounds[r-1].Matches[i]
+		}
+		for i := range b.Rounds[r].Matches {
+			m := &b.Rounds[r].Matches[i]
+			for slot := 0; slot < 2; slot++ {
+				feeder, ok := prev[m.MatchNum*2+slot]
+				if !ok {
+					continue
+				}
+				m.Slots[slot].FeederMatchID = &feeder.ID
+				feeder.NextMatchID = &m.ID
+			}
+		}
+	}
+
+	last := b.Rounds[len(b.Rounds)-1]
+	if len(last.Matches) == 1 {
+		b.Champion = last.Matches[0].Winner
+	}
+	return b
+}
+
+const (
+	svgPad      = 20
+	svgHeader   = 50
+	svgBoxW     = 190
+	svgRowH     = 22
+	svgColGap   = 40
+	svgMatchGap = 16
+)
+
+// renderBracketSVG draws the bracket as a printable SVG with one column per
+// round and a final column for the champion.
+func renderBracketSVG(b Bracket) []byte {
+	first := 1
+	if len(b.Rounds) > 0 && len(b.Rounds[0].Matches) > first {
+		first = len(b.Rounds[0].Matches)
+	}
+	slotH := float64(2*svgRowH + svgMatchGap)
+	bodyH := float64(first) * slotH
+	width := svgPad*2 + (len(b.Rounds)+1)*(svgBoxW+svgColGap) - svgColGap
+	height := svgPad*2 + svgHeader + int(bodyH)
+
+	colX := func(r int) int { return svgPad + r*(svgBoxW+svgColGap) }
+	centerY := func(r, i int) float64 {
+		n := len(b.Rounds[r].Matches)
+		return float64(svgPad+svgHeader) + (float64(i)+0.5)*bodyH/float64(n)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif" font-size="12">`+"\n", width, height, width, height)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+	fmt.Fprintf(&buf, `<text x="%d" y="%d" font-size="18" font-weight="bold">%s Match Play</text>`+"\n", svgPad, svgPad+14, html.EscapeString(b.Year))
+
+	for r, round := range b.Rounds {
+		x := colX(r)
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" font-weight="bold" fill="#555555">%s</text>`+"\n", x, svgPad+svgHeader-12, html.EscapeString(round.Name))
+		for i, m := range round.Matches {
+			cy := centerY(r, i)
+			top := cy - svgRowH
+			fmt.Fprintf(&buf, `<rect x="%d" y="%.1f" width="%d" height="%d" fill="none" stroke="#999999"/>`+"\n", x, top, svgBoxW, 2*svgRowH)
+			fmt.Fprintf(&buf, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#dddddd"/>`+"\n", x, cy, x+svgBoxW, cy)
+			for slot, s := range m.Slots {
+				name := s.Player
+				if s.Seed > 0 && name != "" {
+					name = fmt.Sprintf("%d %s", s.Seed, name)
+				}
+				weight := "normal"
+				if m.Winner != "" && m.Winner == s.Player {
+					weight = "bold"
+				}
+				fmt.Fprintf(&buf, `<text x="%d" y="%.1f" font-weight="%s">%s</text>`+"\n", x+6, top+float64(slot*svgRowH)+15, weight, html.EscapeString(name))
+			}
+			if m.Score != "" {
+				fmt.Fprintf(&buf, `<text x="%d" y="%.1f" text-anchor="end" font-size="10" fill="#555555">%s</text>`+"\n", x+svgBoxW-4, top+float64(2*svgRowH)+12, html.EscapeString(m.Score))
+			}
+			if m.NextMatchID == nil || r+1 >= len(b.Rounds) {
+				continue
+			}
+			// Connect to the slot this match feeds in the next round.
+			for j, next := range b.Rounds[r+1].Matches {
+				if next.ID != *m.NextMatchID {
+					continue
+				}
+				ny := centerY(r+1, j) - svgRowH/2
+				if m.MatchNum%2 == 1 {
+					ny += svgRowH
+				}
+				mid := x + svgBoxW + svgColGap/2
+				fmt.Fprintf(&buf, `<path d="M%d %.1f H%d V%.1f H%d" fill="none" stroke="#999999"/>`+"\n", x+svgBoxW, cy, mid, ny, colX(r+1))
+			}
+		}
+	}
+
+	if len(b.Rounds) > 0 {
+		x := colX(len(b.Rounds))
+		cy := float64(svgPad+svgHeader) + bodyH/2
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" font-weight="bold" fill="#555555">Champion</text>`+"\n", x, svgPad+svgHeader-12)
+		fmt.Fprintf(&buf, `<rect x="%d" y="%.1f" width="%d" height="%d" fill="none" stroke="#333333" stroke-width="2"/>`+"\n", x, cy-svgRowH/2, svgBoxW, svgRowH)
+		fmt.Fprintf(&buf, `<text x="%d" y="%.1f" font-weight="bold">%s</text>`+"\n", x+6, cy+4, html.EscapeString(b.Champion))
+		last := len(b.Rounds) - 1
+		if len(b.Rounds[last].Matches) == 1 {
+			fmt.Fprintf(&buf, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#999999"/>`+"\n", colX(last)+svgBoxW, centerY(last, 0), x, cy)
+		}
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
+
+// loadBracket loads the bracket for the requested year, or the most recent
+// year when none is given.
+func (s *Server) loadBracket(w http.ResponseWriter, r *http.Request) (Bracket, bool) {
+	year := r.URL.Query().Get("year")
+	if year == "" {
+		var years []string
+		if err := s.db.Model(&MatchPlayMatch{}).Distinct().Order("year DESC").Pluck("year", &years).Error; err != nil {
+			http.Error(w, "Failed to fetch match play years", http.StatusInternalServerError)
+			return Bracket{}, false
+		}
+		if len(years) == 0 {
+			http.Error(w, "No match play bracket", http.StatusNotFound)
+			return Bracket{}, false
+		}
+		year = years[0]
+	}
+
+	var matches []MatchPlayMatch
+	if err := s.db.Where("year = ?", year).Find(&matches).Error; err != nil {
+		http.Error(w, "Failed to fetch match play results", http.StatusInternalServerError)
+		return Bracket{}, false
+	}
+	if len(matches) == 0 {
+		http.Error(w, "Requested year not found", http.StatusNotFound)
+		return Bracket{}, false
+	}
+	return buildBracket(year, matches), true
+}
+
+// GET /api/match-play/bracket?year=2025
+func (s *Server) GETMatchPlayBracket(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.loadBracket(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b)
+}
+
+// GET /api/match-play/bracket.svg?year=2025
+func (s *Server) GETMatchPlayBracketSVG(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.loadBracket(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s-match-play.svg"`, b.Year))
+	w.Write(renderBracketSVG(b))
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	colonyCupOverall = "Colony Cup"
+	colonyCupHalved  = "Halved"
+	colonyCupHoles   = 18
+)
+
+// colonyCupSide returns which side won a match: 1 or 2, 0 when it was halved
+// and -1 when it is undecided. Winners may be given as the side's players or
+// as "team1"/"team2".
+func colonyCupSide(r *ColonyCupResult) int {
+	w := strings.ToLower(strings.TrimSpace(r.Winner))
+	switch {
+	case w == "":
+		return -1
+	case w == strings.ToLower(strings.TrimSpace(r.TeamOne)) || w == "team1" || w == "team 1":
+		return 1
+	case w == strings.ToLower(strings.TrimSpace(r.TeamTwo)) || w == "team2" || w == "team 2":
+		return 2
+	case w == "halved" || w == "tie" || w == "tied" || w == "as" || w == "all square":
+		return 0
+	}
+	return -1
+}
+
+// scoreColonyCupMatch sets the status, and once the match is decided the
+// winner and score, from its hole by hole results.
+func scoreColonyCupMatch(r *ColonyCupResult) error {
+	if len(r.Holes) > colonyCupHoles {
+		return fmt.Errorf("a match has at most %d holes", colonyCupHoles)
+	}
+	lead := 0
+	for i, h := range r.Holes {
+		switch h {
+		case 1:
+			lead++
+		case 2:
+			lead--
+		case 0:
+		default:
+			return fmt.Errorf("hole %d must be 0 (halved), 1 or 2", i+1)
+		}
+		remaining := colonyCupHoles - i - 1
+		if abs(lead) > remaining && i+1 < len(r.Holes) {
+			return fmt.Errorf("match was decided on hole %d", i+1)
+		}
+	}
+
+	played := len(r.Holes)
+	remaining := colonyCupHoles - played
+	r.Winner, r.Score = "", ""
+	switch {
+	case played == 0:
+		r.Status = ""
+	case lead == 0 && remaining == 0:
+		r.Status, r.Winner, r.Score = colonyCupHalved, colonyCupHalved, "AS"
+	case lead == 0:
+		r.Status = fmt.Sprintf("AS thru %d", played)
+	case abs(lead) > remaining || remaining == 0:
+		r.Winner = r.TeamOne
+		if lead < 0 {
+			r.Winner = r.TeamTwo
+		}
+		if remaining == 0 {
+			r.Score = fmt.Sprintf("%d up", abs(lead))
+		} else {
+			r.Score = fmt.Sprintf("%d&%d", abs(lead), remaining)
+		}
+		r.Status = r.Score
+	default:
+		r.Status = fmt.Sprintf("%d UP thru %d", abs(lead), played)
+	}
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+type ColonyCupTeamStanding struct {
+	Team      string   `json:"team"`
+	Points    float64  `json:"points"`
+	Projected float64  `json:"projected"` // Counting current leaders in live matches
+	ToWin     float64  `json:"toWin"`
+	ToRetain  *float64 `json:"toRetain,omitempty"` // Only for the team holding the cup
+}
+
+type ColonyCupMatchStatus struct {
+	ID      uint    `json:"id"`
+	Session string  `json:"session"`
+	TeamOne string  `json:"team1"`
+	TeamTwo string  `json:"team2"`
+	Points  float64 `json:"points"`
+	Status  string  `json:"status"`
+	Leader  string  `json:"leader,omitempty"`
+	Thru    int     `json:"thru"`
+	Winner  string  `json:"winner"`
+	Score   string  `json:"score"`
+}
+
+// ColonyCupScoreboard is the live state of a Colony Cup.
+type ColonyCupScoreboard struct {
+	EventID   string                  `json:"eventID"`
+	Holder    string                  `json:"holder,omitempty"`
+	Total     float64                 `json:"total"`     // Points available
+	Remaining float64                 `json:"remaining"` // Points still to be decided
+	Winner    string                  `json:"winner"`
+	Teams     []ColonyCupTeamStanding `json:"teams"`
+	Matches   []ColonyCupMatchStatus  `json:"matches"`
+}
+
+// buildColonyCupScoreboard totals the points for each team. Matches are worth
+// their session's points, or one point when the session is not set up, split
+// when halved. A team needs more than half the points to win the cup
+// outright, while the holder keeps it with exactly half.
+func buildColonyCupScoreboard(eventID string, rows []ColonyCupResult, teams [2]string, holder string, sessionPoints map[string]float64) ColonyCupScoreboard {
+	sb := ColonyCupScoreboard{EventID: eventID, Matches: []ColonyCupMatchStatus{}}
+	var points, projected [2]float64
+	for i := range rows {
+		r := &rows[i]
+		if strings.EqualFold(r.EventName, colonyCupOverall) {
+			if r.TeamOne != "" {
+				teams[0] = r.TeamOne
+			}
+			if r.TeamTwo != "" {
+				teams[1] = r.TeamTwo
+			}
+			continue
+		}
+		value := sessionPoints[strings.ToLower(r.EventName)]
+		if value <= 0 {
+			value = 1
+		}
+		sb.Total += value
+
+		ms := ColonyCupMatchStatus{
+			ID:      r.ID,
+			Session: r.EventName,
+			Points:  value,
+			TeamOne: r.TeamOne,
+			TeamTwo: r.TeamTwo,
+			Status:  r.Status,
+			Thru:    len(r.Holes),
+			Winner:  r.Winner,
+			Score:   r.Score,
+		}
+		lead := 0
+		for _, h := range r.Holes {
+			if h == 1 {
+				lead++
+			} else if h == 2 {
+				lead--
+			}
+		}
+
+		switch colonyCupSide(r) {
+		case 1:
+			points[0] += value
+			projected[0] += value
+		case 2:
+			points[1] += value
+			projected[1] += value
+		case 0:
+			points[0] += value / 2
+			points[1] += value / 2
+			projected[0] += value / 2
+			projected[1] += value / 2
+		default:
+			sb.Remaining += value
+			switch {
+			case lead > 0:
+				ms.Leader = r.TeamOne
+				projected[0] += value
+			case lead < 0:
+				ms.Leader = r.TeamTwo
+				projected[1] += value
+			case len(r.Holes) > 0:
+				projected[0] += value / 2
+				projected[1] += value / 2
+			}
+		}
+		sb.Matches = append(sb.Matches, ms)
+	}
+
+	half := sb.Total / 2
+	for i := range teams {
+		standing := ColonyCupTeamStanding{
+			Team:      teams[i],
+			Points:    points[i],
+			Projected: projected[i],
+			ToWin:     max(0, half+0.5-points[i]),
+		}
+		if strings.EqualFold(teams[i], holder) && holder != "" {
+			toRetain := max(0, half-points[i])
+			standing.ToRetain = &toRetain
+			sb.Holder = teams[i]
+		}
+		sb.Teams = append(sb.Teams, standing)
+	}
+
+	switch {
+	case sb.Total == 0:
+	case points[0] > half:
+		sb.Winner = teams[0]
+	case points[1] > half:
+		sb.Winner = teams[1]
+	case sb.Remaining == 0 && sb.Holder != "":
+		sb.Winner = sb.Holder
+	case sb.Remaining == 0:
+		sb.Winner = colonyCupHalved
+	}
+	return sb
+}
+
+// formatPoints formats a points total such as 8.5 without trailing zeros.
+func formatPoints(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// colonyCupHolder returns the winner of the most recent Colony Cup played
+// before the event.
+func colonyCupHolder(db *gorm.DB, eventID string) string {
+	var event Event
+	if err := db.Where("event_id = ?", eventID).Limit(1).Find(&event).Error; err != nil || event.ID == 0 {
+		return ""
+	}
+	var prev ColonyCupResult
+	db.Joins("JOIN events ON events.event_id = colony_cup_results.event_id").
+		Where("colony_cup_results.event_name = ? AND colony_cup_results.winner NOT IN ?", colonyCupOverall, []string{"", colonyCupHalved}).
+		Where("events.date < ?", event.Date).
+		Order("events.date DESC").Limit(1).Find(&prev)
+	return prev.Winner
+}
+
+// loadColonyCupScoreboard loads an event's results and totals them.
+func loadColonyCupScoreboard(db *gorm.DB, eventID string) (ColonyCupScoreboard, []ColonyCupResult, error) {
+	var rows []ColonyCupResult
+	if err := db.Where("event_id = ?", eventID).Order("match_index ASC, id ASC").Find(&rows).Error; err != nil {
+		return ColonyCupScoreboard{}, nil, err
+	}
+	var sessions []ColonyCupSession
+	if err := db.Where("event_id = ?", eventID).Find(&sessions).Error; err != nil {
+		return ColonyCupScoreboard{}, nil, err
+	}
+	sessionPoints := make(map[string]float64, len(sessions))
+	for _, session := range sessions {
+		sessionPoints[strings.ToLower(session.Name)] = session.Points
+	}
+
+	// Rosters name the teams when there is no overall row yet.
+	names := [2]string{"Team 1", "Team 2"}
+	teams, err := eventColonyCupTeams(db, eventID)
+	if err != nil {
+		return ColonyCupScoreboard{}, nil, err
+	}
+	for _, team := range teams {
+		names[team.Side-1] = team.Name
+	}
+	return buildColonyCupScoreboard(eventID, rows, names, colonyCupHolder(db, eventID), sessionPoints), rows, nil
+}
+
+// updateColonyCupOverall rewrites the overall "Colony Cup" row from the
+// match results so the team totals never need to be entered by hand.
+func updateColonyCupOverall(tx *gorm.DB, eventID string) (ColonyCupScoreboard, error) {
+	sb, rows, err := loadColonyCupScoreboard(tx, eventID)
+	if err != nil {
+		return sb, err
+	}
+	var overall *ColonyCupResult
+	for i := range rows {
+		if strings.EqualFold(rows[i].EventName, colonyCupOverall) {
+			overall = &rows[i]
+		}
+	}
+	if overall == nil {
+		overall = &ColonyCupResult{EventID: eventID, EventName: colonyCupOverall, TeamOne: sb.Teams[0].Team, TeamTwo: sb.Teams[1].Team}
+	}
+	overall.Winner = sb.Winner
+	overall.Score = fmt.Sprintf("%s - %s", formatPoints(sb.Teams[0].Points), formatPoints(sb.Teams[1].Points))
+	return sb, tx.Save(overall).Error
+}
+
+// publishColonyCup pushes the latest scoreboard to live subscribers and
+// webhooks.
+func (s *Server) publishColonyCup(sb ColonyCupScoreboard) {
+	s.liveColonyCup.publish(sb.EventID, sb)
+	s.emitWebhook(webhookColonyCupUpdated, sb)
+}
+
+// colonyCupHub fans scoreboard updates out to clients following an event.
+type colonyCupHub struct {
+	mtx  sync.Mutex
+	subs map[string]map[chan []byte]struct{}
+}
+
+func newColonyCupHub() *colonyCupHub {
+	return &colonyCupHub{subs: make(map[string]map[chan []byte]struct{})}
+}
+
+func (h *colonyCupHub) subscribe(eventID string) chan []byte {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	ch := make(chan []byte, 4)
+	if h.subs[eventID] == nil {
+		h.subs[eventID] = make(map[chan []byte]struct{})
+	}
+	h.subs[eventID][ch] = struct{}{}
+	return ch
+}
+
+func (h *colonyCupHub) unsubscribe(eventID string, ch chan []byte) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	delete(h.subs[eventID], ch)
+	if len(h.subs[eventID]) == 0 {
+		delete(h.subs, eventID)
+	}
+}
+
+func (h *colonyCupHub) publish(eventID string, v any) {
+	if h == nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("error encoding colony cup update: %s", err)
+		return
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	for ch := range h.subs[eventID] {
+		select {
+		case ch <- data:
+		default:
+			// Slow clients miss intermediate updates but get the next one.
+		}
+	}
+}
+
+// GET /api/results/colony-cup/{eventID}/live
+func (s *Server) GETColonyCupScoreboard(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	if s.colonyCupEventHidden(w, r, eventID) {
+		return
+	}
+	sb, _, err := loadColonyCupScoreboard(s.db, eventID)
+	if err != nil {
+		http.Error(w, "Error fetching Colony Cup results", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sb)
+}
+
+// GET /api/results/colony-cup/{eventID}/stream
+// Server-sent events with the scoreboard each time a match is updated.
+func (s *Server) GETColonyCupStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || s.liveColonyCup == nil {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	eventID := chi.URLParam(r, "eventID")
+	if s.colonyCupEventHidden(w, r, eventID) {
+		return
+	}
+	sb, _, err := loadColonyCupScoreboard(s.db, eventID)
+	if err != nil {
+		http.Error(w, "Error fetching Colony Cup results", http.StatusInternalServerError)
+		return
+	}
+	ch := s.liveColonyCup.subscribe(eventID)
+	defer s.liveColonyCup.unsubscribe(eventID, ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	initial, _ := json.Marshal(sb)
+	fmt.Fprintf(w, "data: %s\n\n", initial)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-ch:
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+// PUT /api/results/colony-cup/{eventID}/matches/{matchID}
+// Body: {"holes":[1,0,2,...]} with the result of each hole played so far, or
+// {"winner":"...","score":"..."} to enter a final result directly.
+func (s *Server) PUTColonyCupMatch(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	id, err := strconv.ParseUint(chi.URLParam(r, "matchID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid match ID", http.StatusBadRequest)
+		return
+	}
+	var match ColonyCupResult
+	if err := s.db.Where("event_id = ?", eventID).First(&match, id).Error; err != nil {
+		http.Error(w, "Match not found", http.StatusNotFound)
+		return
+	}
+	if strings.EqualFold(match.EventName, colonyCupOverall) {
+		http.Error(w, "The overall result is calculated from the matches", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Holes  []int   `json:"holes"`
+		Winner *string `json:"winner"`
+		Score  string  `json:"score"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if input.Winner != nil {
+		match.Holes, match.Winner, match.Score = nil, *input.Winner, input.Score
+		match.Status = input.Score
+		if match.Winner != "" && colonyCupSide(&match) < 0 {
+			http.Error(w, "Winner must be one of the sides or Halved", http.StatusBadRequest)
+			return
+		}
+	} else {
+		match.Holes = input.Holes
+		if err := scoreColonyCupMatch(&match); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var sb ColonyCupScoreboard
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&match).Error; err != nil {
+			return err
+		}
+		sb, err = updateColonyCupOverall(tx, eventID)
+		return err
+	}); err != nil {
+		http.Error(w, "Failed to save Colony Cup match", http.StatusInternalServerError)
+		return
+	}
+	s.publishColonyCup(sb)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sb)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func Test_scoreColonyCupMatch(t *testing.T) {
+	m := ColonyCupResult{TeamOne: "Shaw/Lee", TeamTwo: "Ross/Tokanel"}
+
+	m.Holes = []int{1, 1, 0, 2, 1, 0, 0, 0, 0, 0, 0, 0}
+	assert.NoError(t, scoreColonyCupMatch(&m))
+	assert.Equal(t, "2 UP thru 12", m.Status)
+	assert.Equal(t, "", m.Winner)
+
+	m.Holes = []int{2, 2, 2, 2, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	assert.NoError(t, scoreColonyCupMatch(&m))
+	assert.Equal(t, "Ross/Tokanel", m.Winner)
+	assert.Equal(t, "5&4", m.Score)
+
+	m.Holes = make([]int, 18)
+	assert.NoError(t, scoreColonyCupMatch(&m))
+	assert.Equal(t, colonyCupHalved, m.Winner)
+	assert.Equal(t, 0, colonyCupSide(&m))
+
+	m.Holes = append(make([]int, 17), 1)
+	assert.NoError(t, scoreColonyCupMatch(&m))
+	assert.Equal(t, "1 up", m.Score)
+	assert.Equal(t, 1, colonyCupSide(&m))
+
+	m.Holes = []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+	assert.EqualError(t, scoreColonyCupMatch(&m), "match was decided on hole 10")
+	m.Holes = []int{3}
+	assert.Error(t, scoreColonyCupMatch(&m))
+}
+
+func TestServer_updateColonyCupOverall(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+
+	last := &Event{Name: "Colony Cup", DateString: "2024-09-20"}
+	this := &Event{Name: "Colony Cup", DateString: "2025-09-19"}
+	assert.NoError(t, db.Create(last).Error)
+	assert.NoError(t, db.Create(this).Error)
+	assert.NoError(t, db.Create(&ColonyCupResult{EventID: last.EventID, EventName: colonyCupOverall, TeamOne: "Red", TeamTwo: "Blue", Winner: "Blue"}).Error)
+
+	rows := []ColonyCupResult{
+		{EventID: this.EventID, EventName: colonyCupOverall, TeamOne: "Red", TeamTwo: "Blue"},
+		{EventID: this.EventID, EventName: "Best Ball", MatchIndex: 1, TeamOne: "A/B", TeamTwo: "C/D", Winner: "A/B"},
+		{EventID: this.EventID, EventName: "Best Ball", MatchIndex: 1, TeamOne: "E/F", TeamTwo: "G/H", Winner: "Halved"},
+		{EventID: this.EventID, EventName: "Singles", MatchIndex: 2, TeamOne: "A", TeamTwo: "C", Holes: []int{2, 2, 0}},
+		{EventID: this.EventID, EventName: "Singles", MatchIndex: 2, TeamOne: "B", TeamTwo: "D"},
+	}
+	for i := range rows[3:] {
+		assert.NoError(t, scoreColonyCupMatch(&rows[3+i]))
+	}
+	assert.NoError(t, db.Create(&rows).Error)
+
+	sb, err := updateColonyCupOverall(db, this.EventID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Blue", sb.Holder)
+	assert.Equal(t, 4.0, sb.Total)
+	assert.Equal(t, 2.0, sb.Remaining)
+	assert.Equal(t, 1.5, sb.Teams[0].Points)
+	assert.Equal(t, 1.0, sb.Teams[0].ToWin)
+	assert.Equal(t, 0.5, sb.Teams[1].Points)
+	assert.Equal(t, 1.5, *sb.Teams[1].ToRetain)
+	assert.Equal(t, 1.5, sb.Teams[1].Projected)
+	assert.Equal(t, "C", sb.Matches[2].Leader)
+	assert.Equal(t, "2 UP thru 3", sb.Matches[2].Status)
+
+	var overall ColonyCupResult
+	assert.NoError(t, db.Where("event_id = ? AND event_name = ?", this.EventID, colonyCupOverall).First(&overall).Error)
+	assert.Equal(t, "1.5 - 0.5", overall.Score)
+	assert.Equal(t, "", overall.Winner)
+
+	// Blue wins both singles matches.
+	assert.NoError(t, db.Model(&ColonyCupResult{}).Where("team_one IN ?", []string{"A", "B"}).Update("winner", "team2").Error)
+	sb, err = updateColonyCupOverall(db, this.EventID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Blue", sb.Winner)
+	assert.NoError(t, db.First(&overall, overall.ID).Error)
+	assert.Equal(t, "1.5 - 2.5", overall.Score)
+}
+
+func Test_validateColonyCupTeams(t *testing.T) {
+	teams := []ColonyCupTeam{
+		{Side: 2, Name: "Blue", Captain: "Mike Ross", Players: []ColonyCupPlayer{{Player: "Mike Ross"}, {Player: "Jim Tokanel"}}},
+		{Side: 1, Name: "Red", Captain: "Connor Shaw", Players: []ColonyCupPlayer{{Player: "Connor Shaw"}, {Player: "Andy Lee"}}},
+	}
+	assert.NoError(t, validateColonyCupTeams(teams))
+	assert.Equal(t, "Red", teams[0].Name)
+
+	teams[1].Players = append(teams[1].Players, ColonyCupPlayer{Player: "andy lee"})
+	assert.EqualError(t, validateColonyCupTeams(teams), "andy lee is on Red and Blue")
+
+	teams[1].Players = teams[1].Players[:2]
+	teams[1].Captain = "Andy Lee"
+	assert.EqualError(t, validateColonyCupTeams(teams), "Blue captain Andy Lee is not on the roster")
+
+	assert.Equal(t, []string{"Connor Shaw", "Andy Lee"}, colonyCupSidePlayers(" Connor Shaw / Andy Lee "))
+	assert.Equal(t, []string{"Sandy Anderson", "Bo Li"}, colonyCupSidePlayers("Sandy Anderson and Bo Li"))
+}
+
+func TestServer_validateColonyCupResults(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+
+	event := &Event{Name: "Colony Cup", DateString: "2025-09-19"}
+	assert.NoError(t, db.Create(event).Error)
+
+	// Without rosters or sessions anything goes.
+	rows := []ColonyCupResult{{EventName: "Fourball", TeamOne: "A / B", TeamTwo: "C"}}
+	assert.NoError(t, validateColonyCupResults(db, event.EventID, rows))
+
+	teams := []ColonyCupTeam{
+		{Year: "2025", Side: 1, Name: "Red", Players: []ColonyCupPlayer{{Player: "A"}, {Player: "B"}}},
+		{Year: "2025", Side: 2, Name: "Blue", Players: []ColonyCupPlayer{{Player: "C"}, {Player: "D"}}},
+	}
+	assert.NoError(t, db.Create(&teams).Error)
+	sessions := []ColonyCupSession{
+		{Name: "Best Ball", Format: formatBestBall, Points: 1},
+		{Name: "Singles", Format: formatSingles, Points: 2},
+	}
+	assert.NoError(t, validateColonyCupSessions(sessions))
+	for i := range sessions {
+		sessions[i].EventID = event.EventID
+	}
+	assert.NoError(t, db.Create(&sessions).Error)
+
+	assert.EqualError(t, validateColonyCupResults(db, event.EventID, rows), "Fourball is not a session of this event")
+	rows = []ColonyCupResult{{EventName: "best ball", TeamOne: "A / B", TeamTwo: "C"}}
+	assert.EqualError(t, validateColonyCupResults(db, event.EventID, rows), `best ball matches need 2 player(s) a side: "C"`)
+	rows = []ColonyCupResult{{EventName: "Singles", TeamOne: "C", TeamTwo: "D"}}
+	assert.EqualError(t, validateColonyCupResults(db, event.EventID, rows), "C is not on team 1")
+	rows = []ColonyCupResult{
+		{EventID: event.EventID, EventName: "Best Ball", TeamOne: "A / B", TeamTwo: "C & D", Winner: "Halved"},
+		{EventID: event.EventID, EventName: "Singles", TeamOne: "A", TeamTwo: "D", Winner: "A"},
+	}
+	assert.NoError(t, validateColonyCupResults(db, event.EventID, rows))
+	assert.NoError(t, db.Create(&rows).Error)
+
+	sb, err := updateColonyCupOverall(db, event.EventID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Red", sb.Teams[0].Team)
+	assert.Equal(t, 3.0, sb.Total)
+	assert.Equal(t, 2.5, sb.Teams[0].Points)
+	assert.Equal(t, "Red", sb.Winner)
+
+	records := colonyCupRecords(rows, sessions)
+	assert.Equal(t, ColonyCupRecord{Player: "A", Appearances: 1, Matches: 2, Wins: 1, Halves: 1, Points: 2.5}, records[0])
+	assert.Equal(t, ColonyCupRecord{Player: "D", Appearances: 1, Matches: 2, Losses: 1, Halves: 1, Points: 0.5}, records[3])
+}
+
+func Test_proposePairings(t *testing.T) {
+	ratings := map[string]pairingRating{}
+	for i, p := range []string{"a1", "a2", "a3", "a4", "b1", "b2", "b3", "b4"} {
+		idx := float64(i%4) * 6 // 0, 6, 12 and 18 on each side
+		ratings[p] = pairingRating{Index: idx, Strength: playerStrength(ColonyCupRecord{}, idx)}
+	}
+	// a1 and a4 partnered in the morning.
+	history := newPairingHistory([]ColonyCupResult{{EventName: "Morning", TeamOne: "a1 / a4", TeamTwo: "b1 / b4"}})
+
+	one, err := buildPartnerships([]string{"a1", "a2", "a3", "a4"}, 2, 2, nil, ratings, history)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1", "a3"}, one[0].Players)
+	assert.Equal(t, []string{"a2", "a4"}, one[1].Players)
+
+	two, err := buildPartnerships([]string{"b1", "b2", "b3", "b4"}, 2, 2, [][]string{{"b1", "b2"}}, ratings, history)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b1", "b2"}, two[0].Players)
+	assert.Equal(t, []string{"b3", "b4"}, two[1].Players)
+
+	_, err = buildPartnerships([]string{"b1", "b2"}, 2, 2, nil, ratings, history)
+	assert.EqualError(t, err, "not enough players for 2 matches")
+
+	// Balanced pairings put the closest handicaps together.
+	pairings := proposePairings(one, two, nil, pairByHandicap, 0, history)
+	assert.Len(t, pairings, 2)
+	assert.Equal(t, "a1 / a3", pairings[0].TeamOne)
+	assert.Equal(t, "b1 / b2", pairings[0].TeamTwo)
+	assert.True(t, pairings[0].Repeat) // a1 and b1 met in the morning either way
+
+	// Strength pairings maximize the expected points for the chosen side.
+	singles := func(names ...string) []pairingUnit {
+		var units []pairingUnit
+		for _, n := range names {
+			units = append(units, newPairingUnit([]string{n}, ratings))
+		}
+		return units
+	}
+	a, b := singles("a1", "a4"), singles("b2", "b3")
+	expected := func(side int) float64 {
+		total := 0.0
+		for _, p := range proposePairings(singles("a1", "a4"), singles("b2", "b3"), nil, pairByStrength, side, pairingHistory{}) {
+			total += p.WinProbability
+		}
+		return total
+	}
+	straight := winProbability(a[0], b[0]) + winProbability(a[1], b[1])
+	crossed := winProbability(a[0], b[1]) + winProbability(a[1], b[0])
+	assert.InDelta(t, max(straight, crossed), expected(1), 0.011)
+	assert.InDelta(t, min(straight, crossed), expected(2), 0.011)
+
+	assert.Equal(t, []int{2, 0, 1}, bestAssignment(3, func(i, j int) float64 {
+		return float64(abs((i+2)%3 - j))
+	}))
+}
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	pairByHandicap = "handicap"
+	pairByStrength = "strength"
+
+	// Assignments of up to this many matches are searched exhaustively.
+	pairingSearchLimit = 8
+	// Added to a matchup's cost for each opponent faced in an earlier session.
+	repeatPenalty = 0.25
+	// Index assumed for players with no handicap on file.
+	defaultPairingIndex = 18.0
+)
+
+// pairingLock is a captain's lock. With one side set it fixes a partnership,
+// with both sides set it fixes a match.
+type pairingLock struct {
+	TeamOne []string `json:"team1"`
+	TeamTwo []string `json:"team2"`
+}
+
+// pairingUnit is a side in a proposed match: one player for singles, two
+// otherwise.
+type pairingUnit struct {
+	Players  []string
+	Index    float64 // Mean handicap index
+	Strength float64 // Mean strength, 0 to 1
+}
+
+func (u pairingUnit) label() string {
+	return strings.Join(u.Players, " / ")
+}
+
+type ProposedPairing struct {
+	TeamOne        string  `json:"team1"`
+	TeamTwo        string  `json:"team2"`
+	TeamOneIndex   float64 `json:"team1Index"`
+	TeamTwoIndex   float64 `json:"team2Index"`
+	WinProbability float64 `json:"winProbability"` // For team1
+	Locked         bool    `json:"locked"`         // The match was fixed by the captains
+	Repeat         bool    `json:"repeat"`         // The sides have met in an earlier session
+}
+
+// pairingRating holds what is known about a player when pairing.
+type pairingRating struct {
+	Index    float64
+	Strength float64
+}
+
+// pairingHistory is who has partnered and faced whom in earlier sessions.
+type pairingHistory struct {
+	partners  map[string]bool
+	opponents map[string]bool
+	played    map[string]int
+}
+
+func pairKey(a, b string) string {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	if a > b {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
+
+func newPairingHistory(rows []ColonyCupResult) pairingHistory {
+	h := pairingHistory{partners: map[string]bool{}, opponents: map[string]bool{}, played: map[string]int{}}
+	for _, r := range rows {
+		if strings.EqualFold(r.EventName, colonyCupOverall) {
+			continue
+		}
+		one, two := colonyCupSidePlayers(r.TeamOne), colonyCupSidePlayers(r.TeamTwo)
+		for _, side := range [][]string{one, two} {
+			for i, p := range side {
+				h.played[strings.ToLower(p)]++
+				for _, q := range side[i+1:] {
+					h.partners[pairKey(p, q)] = true
+				}
+			}
+		}
+		for _, p := range one {
+			for _, q := range two {
+				h.opponents[pairKey(p, q)] = true
+			}
+		}
+	}
+	return h
+}
+
+// repeats counts the opponents across a matchup who have met before.
+func (h pairingHistory) repeats(a, b pairingUnit) int {
+	n := 0
+	for _, p := range a.Players {
+		for _, q := range b.Players {
+			if h.opponents[pairKey(p, q)] {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// winProbability is the chance side a beats side b under a simple
+// Bradley-Terry model of their strengths.
+func winProbability(a, b pairingUnit) float64 {
+	if a.Strength+b.Strength == 0 {
+		return 0.5
+	}
+	return a.Strength / (a.Strength + b.Strength)
+}
+
+// playerStrength rates a player from their Colony Cup record and handicap.
+// The record is smoothed towards .500 so new players are rated on their
+// handicap.
+func playerStrength(rec ColonyCupRecord, index float64) float64 {
+	winRate := (float64(rec.Wins) + float64(rec.Halves)/2 + 1) / float64(rec.Matches+2)
+	handicapScore := math.Min(1, math.Max(0.05, 1-index/36))
+	return (winRate + handicapScore) / 2
+}
+
+func newPairingUnit(players []string, ratings map[string]pairingRating) pairingUnit {
+	u := pairingUnit{Players: players}
+	for _, p := range players {
+		r := ratings[strings.ToLower(p)]
+		u.Index += r.Index
+		u.Strength += r.Strength
+	}
+	u.Index /= float64(len(players))
+	u.Strength /= float64(len(players))
+	return u
+}
+
+// buildPartnerships groups a side's players into units of size players.
+// Locked partnerships are kept and the rest are paired strongest with
+// weakest, avoiding partnerships from earlier sessions where possible. When
+// there are more players than matches, those who have played the most sit
+// out.
+func buildPartnerships(players []string, size, matches int, locks [][]string, ratings map[string]pairingRating, history pairingHistory) ([]pairingUnit, error) {
+	used := make(map[string]bool)
+	var units []pairingUnit
+	for _, lock := range locks {
+		if len(lock) != size {
+			return nil, fmt.Errorf("locked side %q needs %d player(s)", strings.Join(lock, " / "), size)
+		}
+		for _, p := range lock {
+			if used[strings.ToLower(p)] {
+				return nil, fmt.Errorf("%s is locked more than once", p)
+			}
+			used[strings.ToLower(p)] = true
+		}
+		units = append(units, newPairingUnit(lock, ratings))
+	}
+	if len(units) > matches {
+		return nil, errors.New("more locks than matches")
+	}
+
+	var free []string
+	for _, p := range players {
+		if !used[strings.ToLower(p)] {
+			free = append(free, p)
+		}
+	}
+	need := (matches - len(units)) * size
+	if len(free) < need {
+		return nil, fmt.Errorf("not enough players for %d matches", matches)
+	}
+	sort.SliceStable(free, func(i, j int) bool {
+		return history.played[strings.ToLower(free[i])] < history.played[strings.ToLower(free[j])]
+	})
+	free = free[:need]
+	sort.SliceStable(free, func(i, j int) bool {
+		return ratings[strings.ToLower(free[i])].Strength > ratings[strings.ToLower(free[j])].Strength
+	})
+
+	for len(free) > 0 {
+		if size == 1 {
+			units = append(units, newPairingUnit([]string{free[0]}, ratings))
+			free = free[1:]
+			continue
+		}
+		// Pair the strongest with the weakest they have not partnered before.
+		partner := len(free) - 1
+		for j := len(free) - 1; j > 0; j-- {
+			if !history.partners[pairKey(free[0], free[j])] {
+				partner = j
+				break
+			}
+		}
+		units = append(units, newPairingUnit([]string{free[0], free[partner]}, ratings))
+		free = append(free[1:partner], free[partner+1:]...)
+	}
+	return units, nil
+}
+
+// bestAssignment returns the assignment of rows to columns with the lowest
+// total cost. Small problems are searched exhaustively and larger ones
+// greedily.
+func bestAssignment(n int, cost func(i, j int) float64) []int {
+	best := make([]int, n)
+	if n > pairingSearchLimit {
+		used := make([]bool, n)
+		for i := 0; i < n; i++ {
+			pick := -1
+			for j := 0; j < n; j++ {
+				if !used[j] && (pick < 0 || cost(i, j) < cost(i, pick)) {
+					pick = j
+				}
+			}
+			used[pick] = true
+			best[i] = pick
+		}
+		return best
+	}
+
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	bestCost := math.Inf(1)
+	var permute func(k int, total float64)
+	permute = func(k int, total float64) {
+		if total >= bestCost {
+			return
+		}
+		if k == n {
+			bestCost = total
+			copy(best, perm)
+			return
+		}
+		for i := k; i < n; i++ {
+			perm[k], perm[i] = perm[i], perm[k]
+			permute(k+1, total+cost(k, perm[k]))
+			perm[k], perm[i] = perm[i], perm[k]
+		}
+	}
+	permute(0, 0)
+	return best
+}
+
+// proposePairings pairs the two sides' units into matches. Balanced pairings
+// match sides of similar handicap; strength pairings maximize the expected
+// points of the given side. Either way sides that met in an earlier session
+// are avoided.
+func proposePairings(one, two []pairingUnit, locked [][2]pairingUnit, strategy string, side int, history pairingHistory) []ProposedPairing {
+	var out []ProposedPairing
+	add := func(a, b pairingUnit, locked bool) {
+		out = append(out, ProposedPairing{
+			TeamOne:        a.label(),
+			TeamTwo:        b.label(),
+			TeamOneIndex:   roundTo(a.Index, 1),
+			TeamTwoIndex:   roundTo(b.Index, 1),
+			WinProbability: roundTo(winProbability(a, b), 2),
+			Locked:         locked,
+			Repeat:         history.repeats(a, b) > 0,
+		})
+	}
+	for _, l := range locked {
+		add(l[0], l[1], true)
+	}
+
+	cost := func(i, j int) float64 {
+		c := float64(history.repeats(one[i], two[j])) * repeatPenalty
+		if strategy == pairByStrength {
+			p := winProbability(one[i], two[j])
+			if side == 2 {
+				p = 1 - p
+			}
+			// Costs must not be negative for the search to prune correctly.
+			return c + 1 - p
+		}
+		return c + math.Abs(one[i].Index-two[j].Index)/10
+	}
+	if strategy != pairByStrength {
+		// Order by handicap so matches go out from the lowest handicaps.
+		sort.SliceStable(one, func(i, j int) bool { return one[i].Index < one[j].Index })
+	}
+	assignment := bestAssignment(len(one), cost)
+	for i, j := range assignment {
+		add(one[i], two[j], false)
+	}
+	return out
+}
+
+// POST /api/results/colony-cup/{eventID}/sessions/{session}/pairings
+// Body: {"strategy":"handicap"|"strength","side":1,"matches":4,"locks":[{"team1":["A","B"]}],"save":false}
+// Proposes the session's pairings from the year's rosters. Side is the team
+// whose expected points are maximized by the strength strategy. With save the
+// pairings replace the session's matches in the results.
+func (s *Server) POSTColonyCupPairings(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	var event Event
+	if err := s.db.First(&event, "event_id = ?", eventID).Error; err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	var session ColonyCupSession
+	if err := s.db.Where("event_id = ? AND LOWER(name) = LOWER(?)", eventID, chi.URLParam(r, "session")).First(&session).Error; err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Strategy string        `json:"strategy"`
+		Side     int           `json:"side"`
+		Matches  int           `json:"matches"`
+		Locks    []pairingLock `json:"locks"`
+		Save     bool          `json:"save"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Strategy == "" {
+		req.Strategy = pairByHandicap
+	}
+	if req.Strategy != pairByHandicap && req.Strategy != pairByStrength {
+		http.Error(w, `strategy must be "handicap" or "strength"`, http.StatusBadRequest)
+		return
+	}
+	if req.Strategy == pairByStrength && req.Side != 1 && req.Side != 2 {
+		http.Error(w, "side must be 1 or 2 for the strength strategy", http.StatusBadRequest)
+		return
+	}
+
+	teams, err := eventColonyCupTeams(s.db, eventID)
+	if err != nil {
+		http.Error(w, "Error fetching teams", http.StatusInternalServerError)
+		return
+	}
+	if len(teams) != 2 {
+		http.Error(w, "Both team rosters must be set before pairing", http.StatusConflict)
+		return
+	}
+
+	// Earlier sessions of this event decide repeats and who sits out. All
+	// other Colony Cups feed the strength model.
+	var earlier []ColonyCupSession
+	if err := s.db.Where("event_id = ? AND \"order\" < ?", eventID, session.Order).Find(&earlier).Error; err != nil {
+		http.Error(w, "Error fetching sessions", http.StatusInternalServerError)
+		return
+	}
+	var names []string
+	for _, e := range earlier {
+		names = append(names, e.Name)
+	}
+	var eventRows, pastRows []ColonyCupResult
+	if len(names) > 0 {
+		if err := s.db.Where("event_id = ? AND event_name IN ?", eventID, names).Find(&eventRows).Error; err != nil {
+			http.Error(w, "Error fetching Colony Cup results", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := s.db.Where("event_id <> ?", eventID).Find(&pastRows).Error; err != nil {
+		http.Error(w, "Error fetching Colony Cup results", http.StatusInternalServerError)
+		return
+	}
+	var pastSessions []ColonyCupSession
+	if err := s.db.Where("event_id <> ?", eventID).Find(&pastSessions).Error; err != nil {
+		http.Error(w, "Error fetching sessions", http.StatusInternalServerError)
+		return
+	}
+	history := newPairingHistory(eventRows)
+
+	records := make(map[string]ColonyCupRecord)
+	for _, rec := range colonyCupRecords(pastRows, pastSessions) {
+		records[strings.ToLower(rec.Player)] = rec
+	}
+	ratings := make(map[string]pairingRating)
+	for _, t := range teams {
+		for _, p := range t.Players {
+			idx, ok := parseHandicapIndex(p.Handicap)
+			if !ok {
+				if idx, ok = handicapIndexBefore(s.db, p.Player, event.DateString); !ok {
+					idx = defaultPairingIndex
+				}
+			}
+			key := strings.ToLower(p.Player)
+			ratings[key] = pairingRating{Index: idx, Strength: playerStrength(records[key], idx)}
+		}
+	}
+
+	size := colonyCupFormats[session.Format]
+	var rosters [2][]string
+	for _, t := range teams {
+		for _, p := range t.Players {
+			rosters[t.Side-1] = append(rosters[t.Side-1], p.Player)
+		}
+	}
+	matches := req.Matches
+	if matches <= 0 {
+		matches = min(len(rosters[0]), len(rosters[1])) / size
+	}
+	if matches == 0 {
+		http.Error(w, "Rosters are too small for this format", http.StatusConflict)
+		return
+	}
+
+	// Split the locks into partnerships for each side and fixed matches.
+	var partnerLocks [2][][]string
+	var fixed [][2][]string
+	for _, l := range req.Locks {
+		for side, players := range [][]string{l.TeamOne, l.TeamTwo} {
+			for _, p := range players {
+				if !contains(rosters[side], p) {
+					http.Error(w, fmt.Sprintf("%s is not on team %d", p, side+1), http.StatusBadRequest)
+					return
+				}
+			}
+			if len(players) > 0 {
+				partnerLocks[side] = append(partnerLocks[side], players)
+			}
+		}
+		if len(l.TeamOne) > 0 && len(l.TeamTwo) > 0 {
+			fixed = append(fixed, [2][]string{l.TeamOne, l.TeamTwo})
+		}
+	}
+
+	var units [2][]pairingUnit
+	for side := range units {
+		if units[side], err = buildPartnerships(rosters[side], size, matches, partnerLocks[side], ratings, history); err != nil {
+			http.Error(w, fmt.Sprintf("Team %d: %s", side+1, err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Take the fixed matches out before assigning the rest.
+	var locked [][2]pairingUnit
+	for _, f := range fixed {
+		var pair [2]pairingUnit
+		for side := range pair {
+			for i, u := range units[side] {
+				if u.label() == strings.Join(f[side], " / ") {
+					pair[side] = u
+					units[side] = append(units[side][:i], units[side][i+1:]...)
+					break
+				}
+			}
+		}
+		locked = append(locked, pair)
+	}
+
+	pairings := proposePairings(units[0], units[1], locked, req.Strategy, req.Side, history)
+
+	if req.Save {
+		var started int64
+		if err := s.db.Model(&ColonyCupResult{}).Where("event_id = ? AND event_name = ? AND (winner <> '' OR status <> '')", eventID, session.Name).Count(&started).Error; err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if started > 0 {
+			http.Error(w, "Matches in this session have already started", http.StatusConflict)
+			return
+		}
+		var sb ColonyCupScoreboard
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("event_id = ? AND event_name = ?", eventID, session.Name).Delete(&ColonyCupResult{}).Error; err != nil {
+				return err
+			}
+			rows := make([]ColonyCupResult, 0, len(pairings))
+			for _, p := range pairings {
+				rows = append(rows, ColonyCupResult{
+					EventID:    eventID,
+					EventName:  session.Name,
+					MatchIndex: session.Order,
+					TeamOne:    p.TeamOne,
+					TeamTwo:    p.TeamTwo,
+				})
+			}
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+			var err error
+			sb, err = updateColonyCupOverall(tx, eventID)
+			return err
+		}); err != nil {
+			http.Error(w, "Could not save pairings", http.StatusInternalServerError)
+			return
+		}
+		s.publishColonyCup(sb)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"session":  session.Name,
+		"format":   session.Format,
+		"strategy": req.Strategy,
+		"saved":    req.Save,
+		"pairings": pairings,
+	})
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	formatBestBall      = "best-ball"
+	formatAlternateShot = "alternate-shot"
+	formatScramble      = "scramble"
+	formatShamble       = "shamble"
+	formatSingles       = "singles"
+)
+
+// colonyCupFormats maps each session format to the number of players on a
+// side.
+var colonyCupFormats = map[string]int{
+	formatBestBall:      2,
+	formatAlternateShot: 2,
+	formatScramble:      2,
+	formatShamble:       2,
+	formatSingles:       1,
+}
+
+var colonyCupSideSplit = regexp.MustCompile(`\s*(?:/|&|,|\band\b)\s*`)
+
+// colonyCupSidePlayers splits a side such as "Connor Shaw / Andy Lee" into
+// its players.
+func colonyCupSidePlayers(side string) []string {
+	var players []string
+	for _, p := range colonyCupSideSplit.Split(strings.TrimSpace(side), -1) {
+		if p = strings.TrimSpace(p); p != "" {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+// validateColonyCupTeams checks both rosters for a year.
+func validateColonyCupTeams(teams []ColonyCupTeam) error {
+	if len(teams) != 2 {
+		return errors.New("exactly two teams are required")
+	}
+	seen := make(map[string]string)
+	sides := make(map[int]bool)
+	for i := range teams {
+		t := &teams[i]
+		t.Name = strings.TrimSpace(t.Name)
+		if t.Name == "" {
+			return errors.New("team name is required")
+		}
+		if t.Side != 1 && t.Side != 2 || sides[t.Side] {
+			return errors.New("teams must be sides 1 and 2")
+		}
+		sides[t.Side] = true
+
+		captain := false
+		for j := range t.Players {
+			p := &t.Players[j]
+			p.Player = strings.TrimSpace(p.Player)
+			if p.Player == "" {
+				return fmt.Errorf("%s has a player without a name", t.Name)
+			}
+			key := strings.ToLower(p.Player)
+			if other, ok := seen[key]; ok {
+				return fmt.Errorf("%s is on %s and %s", p.Player, other, t.Name)
+			}
+			seen[key] = t.Name
+			if strings.EqualFold(p.Player, t.Captain) {
+				captain = true
+			}
+		}
+		if t.Captain != "" && !captain {
+			return fmt.Errorf("%s captain %s is not on the roster", t.Name, t.Captain)
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Side < teams[j].Side })
+	return nil
+}
+
+// validateColonyCupSessions checks an event's sessions and numbers them 1 to n
+// by their order, or in the order given when none is set. Results are keyed
+// by these numbers.
+func validateColonyCupSessions(sessions []ColonyCupSession) error {
+	names := make(map[string]bool)
+	for i := range sessions {
+		s := &sessions[i]
+		s.Name = strings.TrimSpace(s.Name)
+		if s.Name == "" || strings.EqualFold(s.Name, colonyCupOverall) {
+			return fmt.Errorf("invalid session name %q", s.Name)
+		}
+		if names[strings.ToLower(s.Name)] {
+			return fmt.Errorf("duplicate session %s", s.Name)
+		}
+		names[strings.ToLower(s.Name)] = true
+		if _, ok := colonyCupFormats[s.Format]; !ok {
+			return fmt.Errorf("%s has an unknown format %q", s.Name, s.Format)
+		}
+		if s.Points <= 0 {
+			s.Points = 1
+		}
+		if s.Order == 0 {
+			s.Order = i + 1
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Order < sessions[j].Order })
+	for i := range sessions {
+		sessions[i].Order = i + 1
+	}
+	return nil
+}
+
+// eventColonyCupTeams returns the rosters for the year of a Colony Cup event.
+func eventColonyCupTeams(db *gorm.DB, eventID string) ([]ColonyCupTeam, error) {
+	var event Event
+	if err := db.Where("event_id = ?", eventID).Limit(1).Find(&event).Error; err != nil {
+		return nil, err
+	}
+	if len(event.DateString) < 4 {
+		return nil, nil
+	}
+	return loadColonyCupTeams(db, event.DateString[:4])
+}
+
+func loadColonyCupTeams(db *gorm.DB, year string) ([]ColonyCupTeam, error) {
+	var teams []ColonyCupTeam
+	err := db.Preload("Players", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Where("year = ?", year).Order("side ASC").Find(&teams).Error
+	return teams, err
+}
+
+// validateColonyCupResults checks that each side's players are on the right
+// roster and, when the event's sessions are set up, that every match belongs
+// to a session and has the right number of players for its format. Results
+// for years without rosters are not checked.
+func validateColonyCupResults(db *gorm.DB, eventID string, rows []ColonyCupResult) error {
+	teams, err := eventColonyCupTeams(db, eventID)
+	if err != nil {
+		return err
+	}
+	var sessions []ColonyCupSession
+	if err := db.Where("event_id = ?", eventID).Find(&sessions).Error; err != nil {
+		return err
+	}
+	formats := make(map[string]string, len(sessions))
+	for _, s := range sessions {
+		formats[strings.ToLower(s.Name)] = s.Format
+	}
+
+	rosters := make(map[int]map[string]bool)
+	for _, t := range teams {
+		rosters[t.Side] = make(map[string]bool)
+		for _, p := range t.Players {
+			rosters[t.Side][strings.ToLower(p.Player)] = true
+		}
+	}
+
+	for _, r := range rows {
+		if strings.EqualFold(r.EventName, colonyCupOverall) {
+			continue
+		}
+		format := ""
+		if len(sessions) > 0 {
+			var ok bool
+			if format, ok = formats[strings.ToLower(r.EventName)]; !ok {
+				return fmt.Errorf("%s is not a session of this event", r.EventName)
+			}
+		}
+		for side, players := range [][]string{colonyCupSidePlayers(r.TeamOne), colonyCupSidePlayers(r.TeamTwo)} {
+			if format != "" && len(players) != colonyCupFormats[format] {
+				return fmt.Errorf("%s matches need %d player(s) a side: %q", r.EventName, colonyCupFormats[format], strings.Join(players, " / "))
+			}
+			roster, ok := rosters[side+1]
+			if !ok {
+				continue
+			}
+			for _, p := range players {
+				if !roster[strings.ToLower(p)] {
+					return fmt.Errorf("%s is not on team %d", p, side+1)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ColonyCupRecord is a player's all-time Colony Cup record.
+type ColonyCupRecord struct {
+	Player      string  `json:"player"`
+	Appearances int     `json:"appearances"`
+	Matches     int     `json:"matches"`
+	Wins        int     `json:"wins"`
+	Losses      int     `json:"losses"`
+	Halves      int     `json:"halves"`
+	Points      float64 `json:"points"`
+}
+
+// colonyCupRecords totals every decided match for each player. Points are the
+// share of each match's points the player's side earned.
+func colonyCupRecords(rows []ColonyCupResult, sessions []ColonyCupSession) []ColonyCupRecord {
+	sessionPoints := make(map[[2]string]float64, len(sessions))
+	for _, s := range sessions {
+		sessionPoints[[2]string{s.EventID, strings.ToLower(s.Name)}] = s.Points
+	}
+
+	byPlayer := make(map[string]*ColonyCupRecord)
+	events := make(map[string]map[string]bool)
+	for i := range rows {
+		r := &rows[i]
+		if strings.EqualFold(r.EventName, colonyCupOverall) {
+			continue
+		}
+		result := colonyCupSide(r)
+		if result < 0 {
+			continue
+		}
+		value := sessionPoints[[2]string{r.EventID, strings.ToLower(r.EventName)}]
+		if value <= 0 {
+			value = 1
+		}
+		for side, players := range [][]string{colonyCupSidePlayers(r.TeamOne), colonyCupSidePlayers(r.TeamTwo)} {
+			for _, p := range players {
+				key := strings.ToLower(p)
+				rec, ok := byPlayer[key]
+				if !ok {
+					rec = &ColonyCupRecord{Player: p}
+					byPlayer[key] = rec
+					events[key] = make(map[string]bool)
+				}
+				events[key][r.EventID] = true
+				rec.Matches++
+				switch result {
+				case 0:
+					rec.Halves++
+					rec.Points += value / 2
+				case side + 1:
+					rec.Wins++
+					rec.Points += value
+				default:
+					rec.Losses++
+				}
+			}
+		}
+	}
+
+	records := make([]ColonyCupRecord, 0, len(byPlayer))
+	for key, rec := range byPlayer {
+		rec.Appearances = len(events[key])
+		records = append(records, *rec)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Points != records[j].Points {
+			return records[i].Points > records[j].Points
+		}
+		return records[i].Player < records[j].Player
+	})
+	return records
+}
+
+// GET /api/colony-cup/teams?year=2025
+func (s *Server) GETColonyCupTeams(w http.ResponseWriter, r *http.Request) {
+	year := r.URL.Query().Get("year")
+	if !validateYear(year) {
+		http.Error(w, "Malformed year", http.StatusBadRequest)
+		return
+	}
+	drafts, err := s.loadColonyCupDrafts(r)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if drafts.hidesYear(year) {
+		http.Error(w, "Year not found", http.StatusNotFound)
+		return
+	}
+	teams, err := loadColonyCupTeams(s.db, year)
+	if err != nil {
+		http.Error(w, "Error fetching teams", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(teams)
+}
+
+// PUT /api/colony-cup/teams
+// Body: {"year":"2025","teams":[{"side":1,"name":"...","captain":"...","players":[{"player":"..."}]}, ...]}
+// Replaces both rosters for the year.
+func (s *Server) PUTColonyCupTeams(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Year  string          `json:"year"`
+		Teams []ColonyCupTeam `json:"teams"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if !validateYear(input.Year) {
+		http.Error(w, "Malformed year", http.StatusBadRequest)
+		return
+	}
+	if err := validateColonyCupTeams(input.Teams); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&ColonyCupTeam{}).Where("year = ?", input.Year).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			if err := tx.Unscoped().Where("team_id IN ?", ids).Delete(&ColonyCupPlayer{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("id IN ?", ids).Delete(&ColonyCupTeam{}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range input.Teams {
+			input.Teams[i].ID = 0
+			input.Teams[i].Year = input.Year
+			for j := range input.Teams[i].Players {
+				input.Teams[i].Players[j].ID = 0
+			}
+		}
+		return tx.Create(&input.Teams).Error
+	}); err != nil {
+		http.Error(w, "Could not save teams", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(input.Teams)
+}
+
+// GET /api/results/colony-cup/{eventID}/sessions
+func (s *Server) GETColonyCupSessions(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	if s.colonyCupEventHidden(w, r, eventID) {
+		return
+	}
+	var sessions []ColonyCupSession
+	if err := s.db.Where("event_id = ?", eventID).Order(`"order" ASC`).Find(&sessions).Error; err != nil {
+		http.Error(w, "Error fetching sessions", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// PUT /api/results/colony-cup/{eventID}/sessions
+// Body: [{"name":"Best Ball","format":"best-ball","points":1}, ...]
+// Replaces the event's sessions. Results already entered under a session
+// take its order so they are shown in session order.
+func (s *Server) PUTColonyCupSessions(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	var event Event
+	if err := s.db.First(&event, "event_id = ?", eventID).Error; err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	var sessions []ColonyCupSession
+	if err := json.NewDecoder(r.Body).Decode(&sessions); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if err := validateColonyCupSessions(sessions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var sb ColonyCupScoreboard
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("event_id = ?", eventID).Delete(&ColonyCupSession{}).Error; err != nil {
+			return err
+		}
+		for i := range sessions {
+			sessions[i].ID = 0
+			sessions[i].EventID = eventID
+			if err := tx.Model(&ColonyCupResult{}).Where("event_id = ? AND event_name = ?", eventID, sessions[i].Name).
+				Update("match_index", sessions[i].Order).Error; err != nil {
+				return err
+			}
+		}
+		if len(sessions) > 0 {
+			if err := tx.Create(&sessions).Error; err != nil {
+				return err
+			}
+		}
+		var err error
+		sb, err = updateColonyCupOverall(tx, eventID)
+		return err
+	}); err != nil {
+		http.Error(w, "Could not save sessions", http.StatusInternalServerError)
+		return
+	}
+	s.publishColonyCup(sb)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// GET /api/colony-cup/records
+// Optional ?player= returns a single player's record.
+func (s *Server) GETColonyCupRecords(w http.ResponseWriter, r *http.Request) {
+	var rows []ColonyCupResult
+	if err := s.db.Find(&rows).Error; err != nil {
+		http.Error(w, "Error fetching Colony Cup results", http.StatusInternalServerError)
+		return
+	}
+	var sessions []ColonyCupSession
+	if err := s.db.Find(&sessions).Error; err != nil {
+		http.Error(w, "Error fetching sessions", http.StatusInternalServerError)
+		return
+	}
+	drafts, err := s.loadColonyCupDrafts(r)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	rows = slices.DeleteFunc(rows, func(row ColonyCupResult) bool { return drafts.hidesEvent(row.EventID) })
+	sessions = slices.DeleteFunc(sessions, func(session ColonyCupSession) bool { return drafts.hidesEvent(session.EventID) })
+	records := colonyCupRecords(rows, sessions)
+
+	w.Header().Set("Content-Type", "application/json")
+	if player := r.URL.Query().Get("player"); player != "" {
+		for _, rec := range records {
+			if strings.EqualFold(rec.Player, player) {
+				json.NewEncoder(w).Encode(rec)
+				return
+			}
+		}
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(records)
+}
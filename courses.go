@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var errNoEventTee = errors.New("event is not linked to a course and tee")
+
+// validateCourse checks the tee and hole data and fills in the par and
+// yardage totals for each tee.
+func validateCourse(c *Course) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return errors.New("Course name must be set")
+	}
+	if len(c.Tees) == 0 {
+		return errors.New("At least one tee must be provided")
+	}
+
+	teeNames := make(map[string]bool)
+	for i := range c.Tees {
+		t := &c.Tees[i]
+		t.Name = strings.TrimSpace(t.Name)
+		if t.Name == "" {
+			return errors.New("Tee name must be set")
+		}
+		if teeNames[strings.ToLower(t.Name)] {
+			return fmt.Errorf("Duplicate tee %q", t.Name)
+		}
+		teeNames[strings.ToLower(t.Name)] = true
+
+		if t.Slope < 55 || t.Slope > 155 {
+			return fmt.Errorf("%s tee slope must be between 55 and 155", t.Name)
+		}
+		n := len(t.Holes)
+		if n != 9 && n != 18 {
+			return fmt.Errorf("%s tee must have 9 or 18 holes", t.Name)
+		}
+		if t.Rating < 25 || t.Rating > 85 {
+			return fmt.Errorf("%s tee course rating is out of range", t.Name)
+		}
+
+		sort.Slice(t.Holes, func(a, b int) bool { return t.Holes[a].Hole < t.Holes[b].Hole })
+		seenHcp := make(map[int]bool)
+		t.Par, t.Yardage = 0, 0
+		for j, h := range t.Holes {
+			if h.Hole != j+1 {
+				return fmt.Errorf("%s tee holes must be numbered 1 to %d", t.Name, n)
+			}
+			if h.Par < 3 || h.Par > 6 {
+				return fmt.Errorf("%s tee hole %d par must be between 3 and 6", t.Name, h.Hole)
+			}
+			if h.Handicap < 1 || h.Handicap > n || seenHcp[h.Handicap] {
+				return fmt.Errorf("%s tee handicap indexes must be unique values from 1 to %d", t.Name, n)
+			}
+			seenHcp[h.Handicap] = true
+			t.Par += h.Par
+			t.Yardage += h.Yardage
+		}
+	}
+	return nil
+}
+
+func (s *Server) loadCourse(id uint) (*Course, error) {
+	var course Course
+	err := s.db.Preload("Tees", func(db *gorm.DB) *gorm.DB {
+		return db.Order("rating DESC")
+	}).Preload("Tees.Holes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("hole ASC")
+	}).First(&course, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+// loadTee returns a course and one of its tees.
+func (s *Server) loadTee(courseID, teeID uint) (*Course, *CourseTee, error) {
+	course, err := s.loadCourse(courseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range course.Tees {
+		if course.Tees[i].ID == teeID {
+			return course, &course.Tees[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("tee %d does not belong to %s", teeID, course.Name)
+}
+
+// loadEventTee returns the course and tee an event is played from.
+func (s *Server) loadEventTee(event *Event) (*Course, *CourseTee, error) {
+	if event.CourseID == 0 || event.TeeID == 0 {
+		return nil, nil, errNoEventTee
+	}
+	return s.loadTee(event.CourseID, event.TeeID)
+}
+
+// linkEventCourse validates the event's course and tee and fills in the free
+// text course, town and state from the course record when they are blank.
+func (s *Server) linkEventCourse(event *Event) error {
+	if event.CourseID == 0 {
+		event.TeeID = 0
+		return nil
+	}
+	if event.TeeID == 0 {
+		return errors.New("A tee must be selected with the course")
+	}
+	course, _, err := s.loadEventTee(event)
+	if err != nil {
+		return err
+	}
+	if event.Course == "" {
+		event.Course = course.Name
+	}
+	if event.Town == "" {
+		event.Town = course.Town
+	}
+	if event.State == "" {
+		event.State = course.State
+	}
+	return nil
+}
+
+func deleteCourseTees(tx *gorm.DB, courseID uint) error {
+	teeIDs := tx.Model(&CourseTee{}).Select("id").Where("course_id = ?", courseID)
+	if err := tx.Unscoped().Where("tee_id IN (?)", teeIDs).Delete(&CourseHole{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("course_id = ?", courseID).Delete(&CourseTee{}).Error
+}
+
+// clearIDs zeroes the tee and hole IDs so nested records are always inserted.
+func (c *Course) clearIDs() {
+	for i := range c.Tees {
+		c.Tees[i].ID = 0
+		c.Tees[i].CourseID = 0
+		for j := range c.Tees[i].Holes {
+			c.Tees[i].Holes[j].ID = 0
+			c.Tees[i].Holes[j].TeeID = 0
+		}
+	}
+}
+
+// GET /api/courses
+func (s *Server) GETCourses(w http.ResponseWriter, r *http.Request) {
+	var courses []Course
+	if err := s.db.Preload("Tees", func(db *gorm.DB) *gorm.DB {
+		return db.Order("rating DESC")
+	}).Preload("Tees.Holes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("hole ASC")
+	}).Order("name ASC").Find(&courses).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if courses == nil {
+		courses = []Course{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(courses)
+}
+
+func courseIDParam(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "courseID"), 10, 64)
+	return uint(id), err
+}
+
+// GET /api/courses/{courseID}
+func (s *Server) GETCourse(w http.ResponseWriter, r *http.Request) {
+	id, err := courseIDParam(r)
+	if err != nil {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+	course, err := s.loadCourse(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Course not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(course)
+}
+
+// POST /api/courses
+// Body: {"name":"...","town":"...","state":"NH","tees":[{"name":"Blue","rating":71.2,"slope":128,
+// "holes":[{"hole":1,"par":5,"handicap":3,"yardage":512}, ...]}]}
+func (s *Server) POSTCourse(w http.ResponseWriter, r *http.Request) {
+	var course Course
+	if err := json.NewDecoder(r.Body).Decode(&course); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	course.ID = 0
+	course.clearIDs()
+	if err := validateCourse(&course); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.Create(&course).Error; err != nil {
+		if isUniqueConstraintError(err) {
+			http.Error(w, "Course already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not save course", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(course)
+}
+
+// PUT /api/courses/{courseID}
+// Replaces the course details and all of its tees.
+func (s *Server) PUTCourse(w http.ResponseWriter, r *http.Request) {
+	id, err := courseIDParam(r)
+	if err != nil {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+	var existing Course
+	if err := s.db.First(&existing, id).Error; err != nil {
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	}
+
+	var input Course
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	input.clearIDs()
+	if err := validateCourse(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existing.Name = input.Name
+	existing.Town = input.Town
+	existing.State = input.State
+	for i := range input.Tees {
+		input.Tees[i].CourseID = existing.ID
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&existing).Error; err != nil {
+			return err
+		}
+		var oldTees []CourseTee
+		if err := tx.Where("course_id = ?", existing.ID).Find(&oldTees).Error; err != nil {
+			return err
+		}
+		oldNames := make(map[uint]string)
+		for _, t := range oldTees {
+			oldNames[t.ID] = strings.ToLower(t.Name)
+		}
+		if err := deleteCourseTees(tx, existing.ID); err != nil {
+			return err
+		}
+		if err := tx.Create(&input.Tees).Error; err != nil {
+			return err
+		}
+		// Tee IDs change when a course is replaced so point events and
+		// match play seasons at the new tee with the same name.
+		newTeeID := func(old uint) uint {
+			for _, t := range input.Tees {
+				if oldNames[old] == strings.ToLower(t.Name) {
+					return t.ID
+				}
+			}
+			return 0
+		}
+		var events []Event
+		if err := tx.Where("course_id = ?", existing.ID).Find(&events).Error; err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err := tx.Model(&Event{}).Where("event_id = ?", e.EventID).UpdateColumn("tee_id", newTeeID(e.TeeID)).Error; err != nil {
+				return err
+			}
+		}
+		var infos []MatchPlayInfo
+		if err := tx.Where("course_id = ?", existing.ID).Find(&infos).Error; err != nil {
+			return err
+		}
+		for _, info := range infos {
+			if err := tx.Model(&MatchPlayInfo{}).Where("id = ?", info.ID).Update("tee_id", newTeeID(info.TeeID)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		if isUniqueConstraintError(err) {
+			http.Error(w, "Course already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not update course", http.StatusInternalServerError)
+		return
+	}
+
+	course, err := s.loadCourse(existing.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(course)
+}
+
+// DELETE /api/courses/{courseID}
+func (s *Server) DELETECourse(w http.ResponseWriter, r *http.Request) {
+	id, err := courseIDParam(r)
+	if err != nil {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+
+	var inUse int64
+	if err := s.db.Model(&Event{}).Where("course_id = ?", id).Count(&inUse).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if inUse > 0 {
+		http.Error(w, "Course is used by events", http.StatusConflict)
+		return
+	}
+	if err := s.db.Model(&MatchPlayInfo{}).Where("course_id = ?", id).Count(&inUse).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if inUse > 0 {
+		http.Error(w, "Course is used by match play", http.StatusConflict)
+		return
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteCourseTees(tx, id); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&Course{}, id).Error
+	}); err != nil {
+		http.Error(w, "Could not delete course", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
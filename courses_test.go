@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testCourse returns an 18 hole par 72 course with a single tee.
+func testCourse() Course {
+	pars := []int{4, 5, 3, 4, 4, 3, 4, 5, 4, 4, 3, 5, 4, 4, 3, 4, 5, 4}
+	hcps := []int{7, 3, 17, 1, 11, 15, 9, 5, 13, 8, 18, 2, 12, 4, 16, 10, 6, 14}
+	holes := make([]CourseHole, 18)
+	for i := range holes {
+		holes[i] = CourseHole{Hole: i + 1, Par: pars[i], Handicap: hcps[i], Yardage: 350}
+	}
+	return Course{
+		Name:  "Beaver Meadow",
+		Town:  "Concord",
+		State: "NH",
+		Tees:  []CourseTee{{Name: "Blue", Rating: 70.8, Slope: 128, Holes: holes}},
+	}
+}
+
+func Test_validateCourse(t *testing.T) {
+	c := testCourse()
+	assert.NoError(t, validateCourse(&c))
+	assert.Equal(t, 72, c.Tees[0].Par)
+	assert.Equal(t, 18*350, c.Tees[0].Yardage)
+
+	c = testCourse()
+	c.Tees[0].Holes[1].Handicap = 7
+	assert.EqualError(t, validateCourse(&c), "Blue tee handicap indexes must be unique values from 1 to 18")
+
+	c = testCourse()
+	c.Tees[0].Holes = c.Tees[0].Holes[:10]
+	assert.EqualError(t, validateCourse(&c), "Blue tee must have 9 or 18 holes")
+
+	c = testCourse()
+	c.Tees[0].Slope = 160
+	assert.Error(t, validateCourse(&c))
+
+	c = testCourse()
+	c.Tees = append(c.Tees, c.Tees[0])
+	c.Tees[1].Name = "blue"
+	assert.EqualError(t, validateCourse(&c), `Duplicate tee "blue"`)
+}
+
+func TestServer_PUTCourse(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	course := testCourse()
+	assert.NoError(t, validateCourse(&course))
+	assert.NoError(t, db.Create(&course).Error)
+	assert.NoError(t, db.Create(&MatchPlayInfo{Year: "2025", CourseID: course.ID, TeeID: course.Tees[0].ID}).Error)
+	event := &Event{Name: "LFG Open", DateString: "2025-06-14", CourseID: course.ID, TeeID: course.Tees[0].ID}
+	assert.NoError(t, db.Create(event).Error)
+
+	r := chi.NewRouter()
+	r.Put("/api/courses/{courseID}", s.PUTCourse)
+	r.Delete("/api/courses/{courseID}", s.DELETECourse)
+	path := fmt.Sprintf("/api/courses/%d", course.ID)
+
+	edited := testCourse()
+	edited.Tees = append([]CourseTee{{Name: "White", Rating: 68.9, Slope: 121, Holes: edited.Tees[0].Holes}}, edited.Tees...)
+	body, err := json.Marshal(edited)
+	assert.NoError(t, err)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, path, bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Events and the season follow their tee to the new ID.
+	var blue CourseTee
+	assert.NoError(t, db.First(&blue, "course_id = ? AND name = ?", course.ID, "Blue").Error)
+	assert.NoError(t, db.First(event, "event_id = ?", event.EventID).Error)
+	assert.Equal(t, blue.ID, event.TeeID)
+	var info MatchPlayInfo
+	assert.NoError(t, db.First(&info).Error)
+	assert.Equal(t, blue.ID, info.TeeID)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "Course is used by events\n", rec.Body.String())
+
+	// The season still holds the course once the event moves elsewhere.
+	assert.NoError(t, db.Model(event).UpdateColumn("course_id", 0).Error)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "Course is used by match play\n", rec.Body.String())
+}
@@ -0,0 +1,600 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	digestStatusPending = "pending"
+	digestStatusSent    = "sent"
+	digestStatusFailed  = "failed"
+
+	// Results are usually followed by a standings refresh; waiting a few
+	// minutes lets the digest report the standings movement.
+	digestDelay        = 10 * time.Minute
+	digestPollInterval = time.Minute
+	digestTopNet       = 5
+	digestTopGross     = 3
+	digestTopStandings = 10
+
+	// A pending subscription isn't sent another confirmation until this
+	// long after the last one.
+	confirmResendInterval = time.Hour
+)
+
+// mailer sends multipart text/HTML email through an SMTP relay. Any SMTP
+// server works, including local stand-ins such as MailHog or smtp4dev.
+type mailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+func newMailer(host string, port int, username, password, from string) *mailer {
+	return &mailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (m *mailer) send(to, subject, textBody, htmlBody string, headers map[string]string) error {
+	msg, err := buildMIMEMessage(m.from, to, subject, textBody, htmlBody, headers)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	return smtp.SendMail(addr, auth, m.from, []string{to}, msg)
+}
+
+// buildMIMEMessage assembles a multipart/alternative message with
+// quoted-printable text and HTML parts.
+func buildMIMEMessage(from, to, subject, textBody, htmlBody string, headers map[string]string) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", textBody},
+		{"text/html; charset=utf-8", htmlBody},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&msg, "%s: %s\r\n", k, headers[k])
+	}
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+type standingsMove struct {
+	Rank   string
+	Player string
+	Points string
+	Change int  // Positions gained since the results were posted; negative if dropped
+	New    bool // Player was not in the standings before
+}
+
+func (m standingsMove) Arrow() string {
+	switch {
+	case m.New:
+		return "new"
+	case m.Change > 0:
+		return fmt.Sprintf("▲%d", m.Change)
+	case m.Change < 0:
+		return fmt.Sprintf("▼%d", -m.Change)
+	default:
+		return "—"
+	}
+}
+
+type digestData struct {
+	EventName      string
+	EventDate      string
+	EventLink      string
+	Net            []NetResult
+	Gross          []GrossResult
+	Skins          []SkinsPlayerResult
+	Standings      []standingsMove
+	StandingsYear  string
+	UnsubscribeURL string
+}
+
+const digestTextTemplate = `{{.EventName}} results ({{.EventDate}})
+{{if .Net}}
+Net
+{{range .Net}}  {{.Rank}}. {{.Player}} {{.Total}}{{if .Points}} ({{.Points}} pts){{end}}
+{{end}}{{end}}{{if .Gross}}
+Gross
+{{range .Gross}}  {{.Rank}}. {{.Player}} {{.Total}}
+{{end}}{{end}}{{if .Skins}}
+Skins
+{{range .Skins}}  {{.Player}}: {{.Skins}}
+{{end}}{{end}}{{if .Standings}}
+{{.StandingsYear}} Standings
+{{range .Standings}}  {{.Rank}}. {{.Player}} {{.Points}} pts ({{.Arrow}})
+{{end}}{{end}}
+Full results: {{.EventLink}}
+
+Unsubscribe: {{.UnsubscribeURL}}
+`
+
+const digestHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<h2>{{.EventName}} results</h2>
+<p>{{.EventDate}}</p>
+{{if .Net}}<h3>Net</h3>
+<table>{{range .Net}}<tr><td>{{.Rank}}</td><td>{{.Player}}</td><td>{{.Total}}</td><td>{{if .Points}}{{.Points}} pts{{end}}</td></tr>{{end}}</table>{{end}}
+{{if .Gross}}<h3>Gross</h3>
+<table>{{range .Gross}}<tr><td>{{.Rank}}</td><td>{{.Player}}</td><td>{{.Total}}</td></tr>{{end}}</table>{{end}}
+{{if .Skins}}<h3>Skins</h3>
+<table>{{range .Skins}}<tr><td>{{.Player}}</td><td>{{.Skins}}</td></tr>{{end}}</table>{{end}}
+{{if .Standings}}<h3>{{.StandingsYear}} Standings</h3>
+<table>{{range .Standings}}<tr><td>{{.Rank}}</td><td>{{.Player}}</td><td>{{.Points}} pts</td><td>{{.Arrow}}</td></tr>{{end}}</table>{{end}}
+<p><a href="{{.EventLink}}">Full results</a></p>
+<p style="font-size: small;"><a href="{{.UnsubscribeURL}}">Unsubscribe</a></p>
+</body>
+</html>
+`
+
+const confirmTextTemplate = `Please confirm your subscription to Live Free Golf results by visiting:
+
+{{.ConfirmURL}}
+
+If you did not request this you can ignore this email.
+`
+
+const confirmHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<p>Please confirm your subscription to Live Free Golf results.</p>
+<p><a href="{{.ConfirmURL}}">Confirm subscription</a></p>
+<p style="font-size: small;">If you did not request this you can ignore this email.</p>
+</body>
+</html>
+`
+
+var (
+	digestText  = texttemplate.Must(texttemplate.New("digest").Parse(digestTextTemplate))
+	digestHTML  = htmltemplate.Must(htmltemplate.New("digest").Parse(digestHTMLTemplate))
+	confirmText = texttemplate.Must(texttemplate.New("confirm").Parse(confirmTextTemplate))
+	confirmHTML = htmltemplate.Must(htmltemplate.New("confirm").Parse(confirmHTMLTemplate))
+)
+
+func renderEmail(text *texttemplate.Template, html *htmltemplate.Template, data any) (string, string, error) {
+	var tb, hb bytes.Buffer
+	if err := text.Execute(&tb, data); err != nil {
+		return "", "", err
+	}
+	if err := html.Execute(&hb, data); err != nil {
+		return "", "", err
+	}
+	return tb.String(), hb.String(), nil
+}
+
+func (s *Server) subscriptionURL(action, token string) string {
+	return fmt.Sprintf("%s/api/subscriptions/%s?token=%s", strings.TrimSuffix(s.publicURL, "/"), action, token)
+}
+
+// seasonRanks returns player -> rank for the season standings of the year.
+func (s *Server) seasonRanks(year string) (map[string]string, error) {
+	var season []SeasonRank
+	if err := s.db.Where("year = ?", year).Find(&season).Error; err != nil {
+		return nil, err
+	}
+	ranks := make(map[string]string, len(season))
+	for _, r := range season {
+		ranks[r.Player] = r.Rank
+	}
+	return ranks, nil
+}
+
+// scheduleDigest queues an email digest for the event. The current season
+// standings are snapshotted so the digest can report movement once the
+// standings are refreshed.
+func (s *Server) scheduleDigest(eventID string) {
+	if s.mailer == nil {
+		return
+	}
+
+	var pending int64
+	if err := s.db.Model(&EmailDigest{}).
+		Where("event_id = ? AND status = ?", eventID, digestStatusPending).
+		Count(&pending).Error; err != nil {
+		log.Printf("error checking pending digests for %s: %s", eventID, err)
+		return
+	}
+	if pending > 0 {
+		return
+	}
+
+	var event Event
+	if err := s.db.First(&event, "event_id = ?", eventID).Error; err != nil {
+		log.Printf("error loading event %s for digest: %s", eventID, err)
+		return
+	}
+	ranks, err := s.seasonRanks(strconv.Itoa(time.Time(event.Date).Year()))
+	if err != nil {
+		log.Printf("error loading standings for digest: %s", err)
+		return
+	}
+
+	digest := &EmailDigest{
+		EventID:       eventID,
+		Status:        digestStatusPending,
+		SendAfter:     time.Now().Add(digestDelay),
+		PreviousRanks: datatypes.NewJSONType(ranks),
+	}
+	if err := s.db.Create(digest).Error; err != nil {
+		log.Printf("error queueing digest for %s: %s", eventID, err)
+	}
+}
+
+// runDigestWorker sends queued digests once their delay has passed.
+func (s *Server) runDigestWorker() {
+	ticker := time.NewTicker(digestPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.sendDueDigests(time.Now())
+	}
+}
+
+func (s *Server) sendDueDigests(now time.Time) {
+	var due []EmailDigest
+	if err := s.db.Where("status = ? AND send_after <= ?", digestStatusPending, now).Find(&due).Error; err != nil {
+		log.Printf("error loading digests: %s", err)
+		return
+	}
+	for i := range due {
+		d := &due[i]
+		if err := s.sendDigest(d); err != nil {
+			d.Status = digestStatusFailed
+			d.Error = err.Error()
+		} else {
+			d.Status = digestStatusSent
+		}
+		if err := s.db.Save(d).Error; err != nil {
+			log.Printf("error saving digest: %s", err)
+		}
+	}
+}
+
+func (s *Server) buildDigestData(d *EmailDigest) (*digestData, error) {
+	var event Event
+	if err := s.db.First(&event, "event_id = ?", d.EventID).Error; err != nil {
+		return nil, err
+	}
+
+	var net []NetResult
+	var gross []GrossResult
+	var skins []SkinsPlayerResult
+	for _, q := range []any{&net, &gross, &skins} {
+		if err := s.db.Where("event_id = ?", d.EventID).Find(q).Error; err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(net, func(i, j int) bool { return parseRank(net[i].Rank) < parseRank(net[j].Rank) })
+	sort.Slice(gross, func(i, j int) bool { return parseRank(gross[i].Rank) < parseRank(gross[j].Rank) })
+	sort.Slice(skins, func(i, j int) bool { return parseRank(skins[i].Rank) < parseRank(skins[j].Rank) })
+	if len(net) > digestTopNet {
+		net = net[:digestTopNet]
+	}
+	if len(gross) > digestTopGross {
+		gross = gross[:digestTopGross]
+	}
+	var skinsWinners []SkinsPlayerResult
+	for _, p := range skins {
+		if parsePoints(p.Skins) > 0 {
+			skinsWinners = append(skinsWinners, p)
+		}
+	}
+
+	year := strconv.Itoa(time.Time(event.Date).Year())
+	var season []SeasonRank
+	if err := s.db.Where("year = ?", year).Find(&season).Error; err != nil {
+		return nil, err
+	}
+	sort.Slice(season, func(i, j int) bool {
+		return parseRank(season[i].Rank) < parseRank(season[j].Rank)
+	})
+	if len(season) > digestTopStandings {
+		season = season[:digestTopStandings]
+	}
+	previous := d.PreviousRanks.Data()
+	moves := make([]standingsMove, 0, len(season))
+	for _, r := range season {
+		move := standingsMove{Rank: r.Rank, Player: r.Player, Points: r.Points}
+		if before, ok := previous[r.Player]; ok {
+			move.Change = parseRank(before) - parseRank(r.Rank)
+		} else if len(previous) > 0 {
+			move.New = true
+		}
+		moves = append(moves, move)
+	}
+
+	return &digestData{
+		EventName:     event.Name,
+		EventDate:     event.DateString,
+		EventLink:     fmt.Sprintf("%s/events/%s", siteURL, event.EventID),
+		Net:           net,
+		Gross:         gross,
+		Skins:         skinsWinners,
+		Standings:     moves,
+		StandingsYear: year,
+	}, nil
+}
+
+func (s *Server) sendDigest(d *EmailDigest) error {
+	if s.mailer == nil {
+		return errors.New("email is not configured")
+	}
+	data, err := s.buildDigestData(d)
+	if err != nil {
+		return err
+	}
+
+	var subs []EmailSubscription
+	if err := s.db.Where("confirmed = ?", true).Find(&subs).Error; err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Live Free Golf: %s results", data.EventName)
+	var failures []string
+	for _, sub := range subs {
+		data.UnsubscribeURL = s.subscriptionURL("unsubscribe", sub.UnsubscribeToken)
+		text, html, err := renderEmail(digestText, digestHTML, data)
+		if err != nil {
+			return err
+		}
+		headers := map[string]string{
+			"List-Unsubscribe":      "<" + data.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+		if err := s.mailer.send(sub.Email, subject, text, html, headers); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", sub.Email, err))
+			continue
+		}
+		d.Recipients++
+	}
+	if len(failures) > 0 {
+		d.Error = strings.Join(failures, "; ")
+		if d.Recipients == 0 {
+			return errors.New(d.Error)
+		}
+	}
+	return nil
+}
+
+// POST /api/subscriptions
+// Body: {"email":"someone@example.com"}
+func (s *Server) POSTEmailSubscription(w http.ResponseWriter, r *http.Request) {
+	if s.mailer == nil {
+		http.Error(w, "Email is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	addr, err := mail.ParseAddress(strings.TrimSpace(payload.Email))
+	if err != nil {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+	email := strings.ToLower(addr.Address)
+
+	var sub EmailSubscription
+	err = s.db.First(&sub, "email = ?", email).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err == nil && sub.Confirmed {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	now := time.Now()
+	if sub.ConfirmSentAt != nil && now.Sub(*sub.ConfirmSentAt) < confirmResendInterval {
+		// The last confirmation is still on its way.
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// Confirmation emails count against the login rate limit so the form
+	// can't be used to flood an inbox.
+	key := loginRateLimitKey(r, "email-subscription")
+	lctx, err := s.loginRateLimiter.Get(r.Context(), key)
+	if err != nil {
+		http.Error(w, "Rate limiter error", http.StatusInternalServerError)
+		return
+	}
+	if lctx.Reached {
+		http.Error(w, "Too many subscription requests", http.StatusTooManyRequests)
+		return
+	}
+
+	sub.Email = email
+	sub.ConfirmToken = randomHex(16)
+	sub.ConfirmSentAt = &now
+	if sub.UnsubscribeToken == "" {
+		sub.UnsubscribeToken = randomHex(16)
+	}
+	if err := s.db.Save(&sub).Error; err != nil {
+		http.Error(w, "Could not save subscription", http.StatusInternalServerError)
+		return
+	}
+
+	text, html, err := renderEmail(confirmText, confirmHTML, map[string]string{
+		"ConfirmURL": s.subscriptionURL("confirm", sub.ConfirmToken),
+	})
+	if err != nil {
+		http.Error(w, "Could not render email", http.StatusInternalServerError)
+		return
+	}
+	if err := s.mailer.send(email, "Confirm your Live Free Golf subscription", text, html, nil); err != nil {
+		s.db.Model(&sub).Update("confirm_sent_at", nil)
+		http.Error(w, fmt.Sprintf("Could not send confirmation email: %s", err), http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// GET /api/subscriptions/confirm?token=...
+func (s *Server) GETConfirmEmailSubscription(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Missing token", http.StatusBadRequest)
+		return
+	}
+
+	var sub EmailSubscription
+	if err := s.db.First(&sub, "confirm_token = ?", token).Error; err != nil {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+	now := time.Now()
+	sub.Confirmed = true
+	sub.ConfirmedAt = &now
+	sub.ConfirmToken = ""
+	if err := s.db.Save(&sub).Error; err != nil {
+		http.Error(w, "Could not confirm subscription", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "Your subscription to Live Free Golf results is confirmed.")
+}
+
+// GET or POST /api/subscriptions/unsubscribe?token=...
+// POST supports one-click unsubscribe from mail clients (RFC 8058).
+func (s *Server) UnsubscribeEmailSubscription(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Missing token", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.Unscoped().Where("unsubscribe_token = ?", token).Delete(&EmailSubscription{}).Error; err != nil {
+		http.Error(w, "Could not unsubscribe", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "You have been unsubscribed from Live Free Golf results.")
+}
+
+// GET /api/subscriptions
+func (s *Server) GETEmailSubscriptions(w http.ResponseWriter, r *http.Request) {
+	var subs []EmailSubscription
+	if err := s.db.Order("email ASC").Find(&subs).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if subs == nil {
+		subs = []EmailSubscription{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subs)
+}
+
+// POST /api/digests/{eventID}
+// Sends the results digest for an event immediately.
+func (s *Server) POSTSendDigest(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	if eventID == "" {
+		http.Error(w, "Missing event ID", http.StatusBadRequest)
+		return
+	}
+	if s.mailer == nil {
+		http.Error(w, "Email is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var event Event
+	if err := s.db.First(&event, "event_id = ?", eventID).Error; err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	digest := &EmailDigest{EventID: eventID, SendAfter: time.Now()}
+	if err := s.sendDigest(digest); err != nil {
+		digest.Status = digestStatusFailed
+		digest.Error = err.Error()
+	} else {
+		digest.Status = digestStatusSent
+	}
+	if err := s.db.Create(digest).Error; err != nil {
+		http.Error(w, "Could not save digest", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(digest)
+}
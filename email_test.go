@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/ulule/limiter/v3"
+	memstore "github.com/ulule/limiter/v3/drivers/store/memory"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer accepts mail like a local SMTP stand-in and records the
+// DATA section of every message.
+type fakeSMTPServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	messages []string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	srv := &fakeSMTPServer{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+func (f *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	reply := func(line string) {
+		rw.WriteString(line + "\r\n")
+		rw.Flush()
+	}
+	reply("220 localhost ESMTP")
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			var msg strings.Builder
+			for {
+				l, err := rw.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				msg.WriteString(l)
+			}
+			f.mu.Lock()
+			f.messages = append(f.messages, msg.String())
+			f.mu.Unlock()
+			reply("250 queued")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (f *fakeSMTPServer) port() int {
+	return f.ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestServer_sendDueDigests(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = applyMigrations(db)
+	assert.NoError(t, err)
+
+	smtpServer := newFakeSMTPServer(t)
+	defer smtpServer.ln.Close()
+
+	s := &Server{
+		db:        db,
+		mailer:    newMailer("127.0.0.1", smtpServer.port(), "", "", "noreply@livefreegolf.com"),
+		publicURL: "https://lfg.example.com",
+	}
+
+	event := &Event{Name: "Impact Fire Open", DateString: "2025-06-14"}
+	assert.NoError(t, db.Create(event).Error)
+	assert.NoError(t, db.Create(&[]NetResult{
+		{EventID: event.EventID, Rank: "1", Player: "Connor Shaw", Total: "+2", Points: "115"},
+	}).Error)
+	assert.NoError(t, db.Create(&[]SeasonRank{
+		{Year: "2025", Player: "Chris Roussin", Rank: "1", Points: "193"},
+		{Year: "2025", Player: "Connor Shaw", Rank: "2", Points: "180"},
+	}).Error)
+	assert.NoError(t, db.Create(&[]EmailSubscription{
+		{Email: "member@example.com", Confirmed: true, UnsubscribeToken: "unsub1"},
+		{Email: "pending@example.com", Confirmed: false, UnsubscribeToken: "unsub2"},
+	}).Error)
+
+	// Results were posted when Connor was 5th.
+	assert.NoError(t, db.Create(&EmailDigest{
+		EventID:       event.EventID,
+		Status:        digestStatusPending,
+		SendAfter:     time.Now().Add(-time.Second),
+		PreviousRanks: datatypes.NewJSONType(map[string]string{"Chris Roussin": "1", "Connor Shaw": "5"}),
+	}).Error)
+
+	s.sendDueDigests(time.Now())
+
+	var digest EmailDigest
+	assert.NoError(t, db.First(&digest).Error)
+	assert.Equal(t, digestStatusSent, digest.Status)
+	assert.Equal(t, 1, digest.Recipients)
+
+	assert.Len(t, smtpServer.messages, 1)
+	raw := smtpServer.messages[0]
+	assert.Contains(t, raw, "To: member@example.com")
+	assert.Contains(t, raw, "List-Unsubscribe: <https://lfg.example.com/api/subscriptions/unsubscribe?token=unsub1>")
+
+	decoded, err := readQuotedPrintable(raw)
+	assert.NoError(t, err)
+	assert.Contains(t, decoded, "1. Connor Shaw +2 (115 pts)")
+	assert.Contains(t, decoded, "2. Connor Shaw 180 pts (▲3)")
+}
+
+func readQuotedPrintable(s string) (string, error) {
+	b, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(s)))
+	return string(b), err
+}
+
+func Test_standingsMoveArrow(t *testing.T) {
+	assert.Equal(t, "▲2", standingsMove{Change: 2}.Arrow())
+	assert.Equal(t, "▼1", standingsMove{Change: -1}.Arrow())
+	assert.Equal(t, "—", standingsMove{}.Arrow())
+	assert.Equal(t, "new", standingsMove{New: true}.Arrow())
+}
+
+func TestServer_POSTEmailSubscription(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+
+	smtpServer := newFakeSMTPServer(t)
+	defer smtpServer.ln.Close()
+
+	rate, err := limiter.NewRateFromFormatted(rateLimit)
+	assert.NoError(t, err)
+	s := &Server{
+		db:               db,
+		mailer:           newMailer("127.0.0.1", smtpServer.port(), "", "", "noreply@livefreegolf.com"),
+		publicURL:        "https://lfg.example.com",
+		loginRateLimiter: limiter.New(memstore.NewStore(), rate),
+	}
+
+	subscribe := func(email string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/subscriptions", strings.NewReader(`{"email": "`+email+`"}`))
+		req.RemoteAddr = "203.0.113.7:4000"
+		rec := httptest.NewRecorder()
+		s.POSTEmailSubscription(rec, req)
+		return rec.Code
+	}
+	sent := func() int {
+		smtpServer.mu.Lock()
+		defer smtpServer.mu.Unlock()
+		return len(smtpServer.messages)
+	}
+
+	assert.Equal(t, http.StatusAccepted, subscribe("member@example.com"))
+	var sub EmailSubscription
+	assert.NoError(t, db.First(&sub, "email = ?", "member@example.com").Error)
+	token := sub.ConfirmToken
+
+	// Asking again while the confirmation is recent doesn't resend it.
+	assert.Equal(t, http.StatusAccepted, subscribe("Member@example.com"))
+	assert.Equal(t, 1, sent())
+	assert.NoError(t, db.First(&sub, "email = ?", "member@example.com").Error)
+	assert.Equal(t, token, sub.ConfirmToken)
+
+	// One address can't be used to email many others.
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, http.StatusAccepted, subscribe(fmt.Sprintf("victim%d@example.com", i)))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, subscribe("victim4@example.com"))
+	assert.Equal(t, 5, sent())
+}
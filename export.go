@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// wantsCSV reports whether the client asked for CSV output either through
+// the ?format=csv query parameter or an Accept header preferring text/csv.
+func wantsCSV(r *http.Request) bool {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		return format == "csv"
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv", "application/csv":
+			return true
+		case "application/json":
+			return false
+		}
+	}
+	return false
+}
+
+// writeCSV streams the header and rows as a CSV attachment.
+func writeCSV(w http.ResponseWriter, filename string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(header)
+	_ = cw.WriteAll(rows)
+}
+
+func netResultsCSV(results []NetResult) ([]string, [][]string) {
+	header := []string{"Rank", "Player", "To Par", "Strokes", "Points"}
+	rows := make([][]string, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, []string{r.Rank, r.Player, r.Total, r.Strokes, r.Points})
+	}
+	return header, rows
+}
+
+func grossResultsCSV(results []GrossResult) ([]string, [][]string) {
+	header := []string{"Rank", "Player", "To Par", "Strokes"}
+	rows := make([][]string, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, []string{r.Rank, r.Player, r.Total, r.Strokes})
+	}
+	return header, rows
+}
+
+func skinsPlayersCSV(results []SkinsPlayerResult) ([]string, [][]string) {
+	header := []string{"Rank", "Player", "Skins"}
+	rows := make([][]string, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, []string{r.Rank, r.Player, r.Skins})
+	}
+	return header, rows
+}
+
+func skinsHolesCSV(results []SkinsHolesResult) ([]string, [][]string) {
+	header := []string{"Hole", "Par", "Score", "Won", "Tie"}
+	rows := make([][]string, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, []string{r.Hole, r.Par, r.Score, r.Won, r.Tie})
+	}
+	return header, rows
+}
+
+func teamResultsCSV(results []TeamResult) ([]string, [][]string) {
+	header := []string{"Rank", "Team", "To Par", "Strokes"}
+	rows := make([][]string, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, []string{r.Rank, r.Team, r.Total, r.Strokes})
+	}
+	return header, rows
+}
+
+func wgrResultsCSV(results []WGRResult) ([]string, [][]string) {
+	header := []string{"Rank", "Player", "To Par", "Strokes", "Points"}
+	rows := make([][]string, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, []string{r.Rank, r.Player, r.Total, r.Strokes, r.Points})
+	}
+	return header, rows
+}
+
+func standingsCSV(season []SeasonRank, wgr []WGRRank) ([]string, [][]string) {
+	header := []string{"Standings", "Rank", "Player", "Events", "Points"}
+	rows := make([][]string, 0, len(season)+len(wgr))
+	for _, r := range season {
+		rows = append(rows, []string{"Season", r.Rank, r.Player, r.Events, r.Points})
+	}
+	for _, r := range wgr {
+		rows = append(rows, []string{"WGR", r.Rank, r.Player, r.Events, r.Points})
+	}
+	return header, rows
+}
+
+func matchPlayCSV(matches []MatchPlayMatch) ([]string, [][]string) {
+	header := []string{"Round", "Match", "Player 1", "Player 2", "Winner", "Score"}
+	rows := make([][]string, 0, len(matches))
+	for _, m := range matches {
+		rows = append(rows, []string{m.Round, strconv.Itoa(m.MatchNum + 1), m.Player1, m.Player2, m.Winner, m.Score})
+	}
+	return header, rows
+}
+
+// GET /api/results/export?year=2025
+// Returns every net, gross, skins, team and WGR result for the season as a
+// single CSV with one row per player (or team) per contest.
+func (s *Server) GETSeasonResultsExport(w http.ResponseWriter, r *http.Request) {
+	year := r.URL.Query().Get("year")
+	if year == "" {
+		year = strconv.Itoa(time.Now().Year())
+	}
+	if !validateYear(year) {
+		http.Error(w, "Malformed year", http.StatusBadRequest)
+		return
+	}
+
+	var events []Event
+	if err := s.visibleEvents(r).Where("substr(date_string, 1, 4) = ?", year).Order("date ASC").Find(&events).Error; err != nil {
+		http.Error(w, "Error fetching events", http.StatusInternalServerError)
+		return
+	}
+
+	header := []string{"Event ID", "Event", "Date", "Contest", "Rank", "Player", "To Par", "Strokes", "Points", "Skins"}
+	var rows [][]string
+	for _, e := range events {
+		var net []NetResult
+		var gross []GrossResult
+		var skins []SkinsPlayerResult
+		var teams []TeamResult
+		var wgr []WGRResult
+		for _, q := range []any{&net, &gross, &skins, &teams, &wgr} {
+			if err := s.db.Where("event_id = ?", e.EventID).Find(q).Error; err != nil {
+				http.Error(w, "Error fetching results", http.StatusInternalServerError)
+				return
+			}
+		}
+		sort.Slice(net, func(i, j int) bool { return parseRank(net[i].Rank) < parseRank(net[j].Rank) })
+		sort.Slice(gross, func(i, j int) bool { return parseRank(gross[i].Rank) < parseRank(gross[j].Rank) })
+		sort.Slice(skins, func(i, j int) bool { return parseRank(skins[i].Rank) < parseRank(skins[j].Rank) })
+		sort.Slice(teams, func(i, j int) bool { return parseRank(teams[i].Rank) < parseRank(teams[j].Rank) })
+		sort.Slice(wgr, func(i, j int) bool { return parseRank(wgr[i].Rank) < parseRank(wgr[j].Rank) })
+
+		prefix := []string{e.EventID, e.Name, e.DateString}
+		row := func(cols ...string) []string {
+			return append(append([]string{}, prefix...), cols...)
+		}
+		for _, r := range net {
+			rows = append(rows, row("Net", r.Rank, r.Player, r.Total, r.Strokes, r.Points, ""))
+		}
+		for _, r := range gross {
+			rows = append(rows, row("Gross", r.Rank, r.Player, r.Total, r.Strokes, "", ""))
+		}
+		for _, r := range skins {
+			rows = append(rows, row("Skins", r.Rank, r.Player, "", "", "", r.Skins))
+		}
+		for _, r := range teams {
+			rows = append(rows, row("Teams", r.Rank, r.Team, r.Total, r.Strokes, "", ""))
+		}
+		for _, r := range wgr {
+			rows = append(rows, row("WGR", r.Rank, r.Player, r.Total, r.Strokes, r.Points, ""))
+		}
+	}
+
+	writeCSV(w, fmt.Sprintf("%s-results.csv", year), header, rows)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_wantsCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		want   bool
+	}{
+		{name: "default", want: false},
+		{name: "format csv", query: "?format=csv", want: true},
+		{name: "format json", query: "?format=json", accept: "text/csv", want: false},
+		{name: "accept csv", accept: "text/csv", want: true},
+		{name: "accept application csv", accept: "application/csv;q=0.9", want: true},
+		{name: "json preferred", accept: "application/json, text/csv", want: false},
+		{name: "csv preferred", accept: "text/csv, application/json", want: true},
+		{name: "browser", accept: "text/html,application/xhtml+xml,*/*;q=0.8", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/results/net/2025-lfg-open"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			assert.Equal(t, tt.want, wantsCSV(req))
+		})
+	}
+}
+
+func Test_resultsCSV(t *testing.T) {
+	render := func(header []string, rows [][]string) string {
+		rec := httptest.NewRecorder()
+		writeCSV(rec, "results.csv", header, rows)
+		assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="results.csv"`, rec.Header().Get("Content-Disposition"))
+		return rec.Body.String()
+	}
+
+	tests := []struct {
+		name   string
+		header []string
+		rows   [][]string
+		want   string
+	}{
+		{
+			name: "net",
+			header: func() []string {
+				h, _ := netResultsCSV(nil)
+				return h
+			}(),
+			rows: func() [][]string {
+				_, r := netResultsCSV([]NetResult{
+					{Rank: "1", Player: "Connor Shaw", Total: "-4", Strokes: "68", Points: "500"},
+					{Rank: "T2", Player: "Lee, Andy", Total: "E", Strokes: "72", Points: "300"},
+				})
+				return r
+			}(),
+			want: "Rank,Player,To Par,Strokes,Points\n" +
+				"1,Connor Shaw,-4,68,500\n" +
+				"T2,\"Lee, Andy\",E,72,300\n",
+		},
+		{
+			name: "teams",
+			header: func() []string {
+				h, _ := teamResultsCSV(nil)
+				return h
+			}(),
+			rows: func() [][]string {
+				_, r := teamResultsCSV([]TeamResult{{Rank: "1", Team: `Shaw / "Tiger" Lee`, Total: "-10", Strokes: "62"}})
+				return r
+			}(),
+			want: "Rank,Team,To Par,Strokes\n" +
+				"1,\"Shaw / \"\"Tiger\"\" Lee\",-10,62\n",
+		},
+		{
+			name: "standings",
+			header: func() []string {
+				h, _ := standingsCSV(nil, nil)
+				return h
+			}(),
+			rows: func() [][]string {
+				_, r := standingsCSV(
+					[]SeasonRank{{Rank: "1", Player: "Connor Shaw", Events: "4", Points: "1200"}},
+					[]WGRRank{{Rank: "1", Player: "Ross, Mike", Events: "3", Points: "80.5"}},
+				)
+				return r
+			}(),
+			want: "Standings,Rank,Player,Events,Points\n" +
+				"Season,1,Connor Shaw,4,1200\n" +
+				"WGR,1,\"Ross, Mike\",3,80.5\n",
+		},
+		{
+			name: "match play",
+			header: func() []string {
+				h, _ := matchPlayCSV(nil)
+				return h
+			}(),
+			rows: func() [][]string {
+				_, r := matchPlayCSV([]MatchPlayMatch{{Round: "1", MatchNum: 0, Player1: "Connor Shaw", Player2: "Andy Lee", Winner: "Connor Shaw", Score: "3&2"}})
+				return r
+			}(),
+			want: "Round,Match,Player 1,Player 2,Winner,Score\n" +
+				"1,1,Connor Shaw,Andy Lee,Connor Shaw,3&2\n",
+		},
+		{
+			name: "header only",
+			header: func() []string {
+				h, _ := skinsHolesCSV(nil)
+				return h
+			}(),
+			want: "Hole,Par,Score,Won,Tie\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, render(tt.header, tt.rows))
+		})
+	}
+}
+
+func TestServer_resultsExport(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	event := &Event{Name: "LFG Open", DateString: "2025-06-14"}
+	assert.NoError(t, db.Create(event).Error)
+	assert.NoError(t, db.Create(&[]NetResult{
+		{EventID: event.EventID, Rank: "T2", Player: "Lee, Andy", Total: "E", Strokes: "72", Points: "300"},
+		{EventID: event.EventID, Rank: "1", Player: "Connor Shaw", Total: "-4", Strokes: "68", Points: "500"},
+	}).Error)
+	assert.NoError(t, db.Create(&SkinsPlayerResult{EventID: event.EventID, Rank: "1", Player: "Connor Shaw", Skins: "2"}).Error)
+
+	r := chi.NewRouter()
+	r.Get("/api/results/net/{eventID}", s.GETNetResults)
+	r.Get("/api/results/export", s.GETSeasonResultsExport)
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	wantNet := "Rank,Player,To Par,Strokes,Points\n" +
+		"1,Connor Shaw,-4,68,500\n" +
+		"T2,\"Lee, Andy\",E,72,300\n"
+
+	rec := get("/api/results/net/"+event.EventID+"?format=csv", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, wantNet, rec.Body.String())
+	assert.Equal(t, `attachment; filename="2025-lfg-open-net.csv"`, rec.Header().Get("Content-Disposition"))
+
+	rec = get("/api/results/net/"+event.EventID, "text/csv")
+	assert.Equal(t, wantNet, rec.Body.String())
+	assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+
+	rec = get("/api/results/net/"+event.EventID, "")
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "["))
+
+	rec = get("/api/results/export?year=2025", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `attachment; filename="2025-results.csv"`, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "Event ID,Event,Date,Contest,Rank,Player,To Par,Strokes,Points,Skins\n"+
+		"2025-lfg-open,LFG Open,2025-06-14,Net,1,Connor Shaw,-4,68,500,\n"+
+		"2025-lfg-open,LFG Open,2025-06-14,Net,T2,\"Lee, Andy\",E,72,300,\n"+
+		"2025-lfg-open,LFG Open,2025-06-14,Skins,1,Connor Shaw,,,,2\n", rec.Body.String())
+
+	rec = get("/api/results/export?year=20x5", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
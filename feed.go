@@ -0,0 +1,417 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	siteURL      = "https://livefreegolf.com"
+	feedTitle    = "Live Free Golf Results"
+	feedTagHost  = "livefreegolf.com"
+	feedMaxItems = 50
+)
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Updated   string   `xml:"updated"`
+	Published string   `xml:"published"`
+	Link      atomLink `xml:"link"`
+	Category  struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+	Summary atomText `xml:"summary"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Author  struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// feedTagID builds a stable tag URI (RFC 4151) for a feed entry.
+func feedTagID(entry *FeedEntry) string {
+	return fmt.Sprintf("tag:%s,%s:%s/%s/%d", feedTagHost, entry.CreatedAt.UTC().Format("2006-01-02"), entry.Kind, entry.Key, entry.ID)
+}
+
+func buildAtomFeed(entries []FeedEntry, selfURL string) atomFeed {
+	feed := atomFeed{
+		ID:    fmt.Sprintf("tag:%s,2025:feed", feedTagHost),
+		Title: feedTitle,
+		Links: []atomLink{
+			{Href: selfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: siteURL, Rel: "alternate", Type: "text/html"},
+		},
+	}
+	feed.Author.Name = "Live Free Golf"
+
+	updated := time.Unix(0, 0)
+	for i := range entries {
+		e := &entries[i]
+		if e.CreatedAt.After(updated) {
+			updated = e.CreatedAt
+		}
+		ae := atomEntry{
+			ID:        feedTagID(e),
+			Title:     e.Title,
+			Updated:   e.CreatedAt.UTC().Format(time.RFC3339),
+			Published: e.CreatedAt.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: e.Link, Rel: "alternate", Type: "text/html"},
+			Summary:   atomText{Type: "text", Body: e.Summary},
+		}
+		ae.Category.Term = e.Kind
+		feed.Entries = append(feed.Entries, ae)
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+	return feed
+}
+
+// GET /api/feed.atom
+func (s *Server) GETAtomFeed(w http.ResponseWriter, r *http.Request) {
+	var entries []FeedEntry
+	if err := s.db.Order("created_at DESC").Limit(feedMaxItems).Find(&entries).Error; err != nil {
+		http.Error(w, "Error fetching feed", http.StatusInternalServerError)
+		return
+	}
+
+	feed := buildAtomFeed(entries, strings.TrimSuffix(s.publicURL, "/")+r.URL.Path)
+
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		http.Error(w, "Error encoding feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(out)
+}
+
+func (s *Server) addFeedEntry(entry *FeedEntry) {
+	if err := s.db.Create(entry).Error; err != nil {
+		log.Printf("error saving %s feed entry for %s: %s", entry.Kind, entry.Key, err)
+	}
+}
+
+// rowsFingerprint hashes the rows of each model matching a condition. Row
+// IDs and timestamps are left out since results and standings are deleted
+// and recreated every time they are downloaded.
+func rowsFingerprint(db *gorm.DB, query string, arg any, models ...any) (string, error) {
+	h := sha256.New()
+	for _, model := range models {
+		var rows []map[string]any
+		if err := db.Model(model).Where(query, arg).Find(&rows).Error; err != nil {
+			return "", err
+		}
+		lines := make([]string, 0, len(rows))
+		for _, row := range rows {
+			for _, col := range []string{"id", "created_at", "updated_at", "deleted_at"} {
+				delete(row, col)
+			}
+			line, err := json.Marshal(row)
+			if err != nil {
+				return "", err
+			}
+			lines = append(lines, string(line))
+		}
+		sort.Strings(lines)
+		fmt.Fprintf(h, "%T\n%s\n", model, strings.Join(lines, "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// alreadyPublished reports whether the latest feed entry for kind and key
+// was published for the same data.
+func (s *Server) alreadyPublished(kind, key, fingerprint string) bool {
+	var last FeedEntry
+	if err := s.db.Where("kind = ? AND key = ?", kind, key).Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		log.Printf("error loading %s feed entry for %s: %s", kind, key, err)
+		return false
+	}
+	return last.ID != 0 && last.Fingerprint == fingerprint
+}
+
+// publishResults is called whenever new results are saved for an event.
+func (s *Server) publishResults(eventID string) {
+	var event Event
+	if err := s.db.First(&event, "event_id = ?", eventID).Error; err != nil {
+		log.Printf("error loading event %s for publication: %s", eventID, err)
+		return
+	}
+	if event.Status == eventDraft {
+		return
+	}
+	s.markResultsProvisional(&event)
+
+	fingerprint, err := rowsFingerprint(s.db, "event_id = ?", eventID,
+		&NetResult{}, &GrossResult{}, &SkinsPlayerResult{}, &SkinsHolesResult{}, &TeamResult{}, &WGRResult{})
+	if err != nil {
+		log.Printf("error loading results for %s: %s", eventID, err)
+		return
+	}
+	if s.alreadyPublished("results", eventID, fingerprint) {
+		return
+	}
+
+	summary, err := s.resultsSummary(eventID)
+	if err != nil {
+		log.Printf("error summarizing results for %s: %s", eventID, err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/events/%s", siteURL, eventID)
+	s.addFeedEntry(&FeedEntry{
+		Kind:        "results",
+		Key:         eventID,
+		Title:       fmt.Sprintf("Results posted: %s", event.Name),
+		Summary:     summary,
+		Link:        link,
+		Fingerprint: fingerprint,
+	})
+	s.emitWebhook(webhookResultsUpdated, map[string]any{
+		"eventID": eventID,
+		"name":    event.Name,
+		"date":    event.DateString,
+		"summary": summary,
+		"link":    link,
+	})
+	s.scheduleDigest(eventID)
+}
+
+// publishStandings is called after the season standings are refreshed.
+func (s *Server) publishStandings(year string) {
+	fingerprint, err := rowsFingerprint(s.db, "year = ?", year, &SeasonRank{}, &WGRRank{})
+	if err != nil {
+		log.Printf("error loading standings for %s: %s", year, err)
+		return
+	}
+	if s.alreadyPublished("standings", year, fingerprint) {
+		return
+	}
+
+	var season []SeasonRank
+	if err := s.db.Where("year = ?", year).Find(&season).Error; err != nil {
+		log.Printf("error loading standings for %s: %s", year, err)
+		return
+	}
+	sort.Slice(season, func(i, j int) bool {
+		return parseRank(season[i].Rank) < parseRank(season[j].Rank)
+	})
+
+	var leaders []string
+	for i := 0; i < len(season) && i < 3; i++ {
+		leaders = append(leaders, fmt.Sprintf("%s. %s (%s pts)", season[i].Rank, season[i].Player, season[i].Points))
+	}
+	summary := "Season standings have been updated."
+	if len(leaders) > 0 {
+		summary = fmt.Sprintf("Season leaders: %s", strings.Join(leaders, ", "))
+	}
+
+	s.addFeedEntry(&FeedEntry{
+		Kind:        "standings",
+		Key:         year,
+		Title:       fmt.Sprintf("%s standings updated", year),
+		Summary:     summary,
+		Link:        fmt.Sprintf("%s/standings?year=%s", siteURL, year),
+		Fingerprint: fingerprint,
+	})
+
+	top := season
+	if len(top) > 10 {
+		top = top[:10]
+	}
+	s.emitWebhook(webhookStandingsRefreshed, map[string]any{
+		"year":    year,
+		"leaders": top,
+	})
+}
+
+// refreshMatchPlayBracket scrapes the bracket and publishes any matches that
+// were decided by the refresh.
+func (s *Server) refreshMatchPlayBracket(year, bracketUrl string) error {
+	var before []MatchPlayMatch
+	if err := s.db.Where("year = ?", year).Find(&before).Error; err != nil {
+		return err
+	}
+	if err := updateMatchPlayResults(s.db, year, bracketUrl); err != nil {
+		return err
+	}
+	s.publishMatchPlay(year, before)
+	return nil
+}
+
+func matchPlayKey(m *MatchPlayMatch) string {
+	return fmt.Sprintf("%s|%d", m.Round, m.MatchNum)
+}
+
+// publishMatchPlay is called after the match play bracket for a year changes.
+// Matches that have a winner now but did not in before are announced
+// individually.
+func (s *Server) publishMatchPlay(year string, before []MatchPlayMatch) {
+	var matches []MatchPlayMatch
+	if err := s.db.Where("year = ?", year).Order("id ASC").Find(&matches).Error; err != nil {
+		log.Printf("error loading match play for %s: %s", year, err)
+		return
+	}
+
+	previous := make(map[string]string, len(before))
+	for i := range before {
+		previous[matchPlayKey(&before[i])] = before[i].Winner
+	}
+	for i := range matches {
+		m := &matches[i]
+		if m.Winner != "" && previous[matchPlayKey(m)] == "" {
+			s.emitWebhook(webhookMatchDecided, m)
+		}
+	}
+
+	// The bracket entry is only published when the winners change, not on
+	// every refresh of the bracket.
+	decided := 0
+	var champion string
+	winners := make([]string, 0, len(matches))
+	for _, m := range matches {
+		winners = append(winners, matchPlayKey(&m)+"|"+m.Winner)
+		if m.Winner == "" {
+			continue
+		}
+		decided++
+		champion = m.Winner
+	}
+	sort.Strings(winners)
+	sum := sha256.Sum256([]byte(strings.Join(winners, "\n")))
+	fingerprint := hex.EncodeToString(sum[:])
+	if s.alreadyPublished("match-play", year, fingerprint) {
+		return
+	}
+	summary := fmt.Sprintf("%d of %d matches decided.", decided, len(matches))
+	if len(matches) > 0 && decided == len(matches) {
+		summary = fmt.Sprintf("%s wins the %s match play championship.", champion, year)
+	}
+
+	s.addFeedEntry(&FeedEntry{
+		Kind:        "match-play",
+		Key:         year,
+		Title:       fmt.Sprintf("%s match play bracket updated", year),
+		Summary:     summary,
+		Link:        fmt.Sprintf("%s/match-play?year=%s", siteURL, year),
+		Fingerprint: fingerprint,
+	})
+}
+
+// publishEventCreated is called after a new event is saved.
+func (s *Server) publishEventCreated(event *Event) {
+	s.emitWebhook(webhookEventCreated, event)
+	if event.RegistrationOpen {
+		s.publishRegistrationOpened(event.Name, event.ShopifyUrl, map[string]any{"eventID": event.EventID})
+	}
+}
+
+// publishRegistrationOpened is called when registration opens for an event or
+// the season's match play.
+func (s *Server) publishRegistrationOpened(name, registrationUrl string, extra map[string]any) {
+	data := map[string]any{
+		"name":            name,
+		"registrationUrl": registrationUrl,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	s.emitWebhook(webhookRegistrationOpened, data)
+}
+
+// publishRegistrationClosed is called when registration closes because an
+// event or the season's match play is full.
+func (s *Server) publishRegistrationClosed(name string, registered int, extra map[string]any) {
+	data := map[string]any{
+		"name":       name,
+		"registered": registered,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	s.emitWebhook(webhookRegistrationClosed, data)
+}
+
+// publishChampion is called when a new past champion is added.
+func (s *Server) publishChampion(champ *PastChampion) {
+	s.addFeedEntry(&FeedEntry{
+		Kind:    "champion",
+		Key:     champ.Year,
+		Title:   fmt.Sprintf("%s Champion: %s", champ.Year, champ.Player),
+		Summary: fmt.Sprintf("%s is the %s Live Free Golf champion.", champ.Player, champ.Year),
+		Link:    fmt.Sprintf("%s/champions", siteURL),
+	})
+}
+
+// resultsSummary describes the winners of each contest for an event.
+func (s *Server) resultsSummary(eventID string) (string, error) {
+	var net []NetResult
+	var gross []GrossResult
+	var skins []SkinsPlayerResult
+	var teams []TeamResult
+	for _, q := range []any{&net, &gross, &skins, &teams} {
+		if err := s.db.Where("event_id = ?", eventID).Find(q).Error; err != nil {
+			return "", err
+		}
+	}
+	sort.Slice(net, func(i, j int) bool { return parseRank(net[i].Rank) < parseRank(net[j].Rank) })
+	sort.Slice(gross, func(i, j int) bool { return parseRank(gross[i].Rank) < parseRank(gross[j].Rank) })
+	sort.Slice(teams, func(i, j int) bool { return parseRank(teams[i].Rank) < parseRank(teams[j].Rank) })
+
+	var lines []string
+	if len(net) > 0 {
+		var top []string
+		for i := 0; i < len(net) && i < 3; i++ {
+			top = append(top, fmt.Sprintf("%s. %s (%s)", net[i].Rank, net[i].Player, net[i].Total))
+		}
+		lines = append(lines, "Net: "+strings.Join(top, ", "))
+	}
+	if len(gross) > 0 {
+		lines = append(lines, fmt.Sprintf("Gross: %s (%s)", gross[0].Player, gross[0].Total))
+	}
+	var skinsWinners []string
+	for _, p := range skins {
+		if parsePoints(p.Skins) > 0 {
+			skinsWinners = append(skinsWinners, fmt.Sprintf("%s (%s)", p.Player, p.Skins))
+		}
+	}
+	if len(skinsWinners) > 0 {
+		lines = append(lines, "Skins: "+strings.Join(skinsWinners, ", "))
+	}
+	if len(teams) > 0 {
+		lines = append(lines, fmt.Sprintf("Teams: %s", teams[0].Team))
+	}
+	if len(lines) == 0 {
+		return "Results have been posted.", nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_publishResults(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = applyMigrations(db)
+	assert.NoError(t, err)
+
+	s := &Server{db: db}
+
+	event := &Event{Name: "Impact Fire Open", DateString: "2025-06-14"}
+	assert.NoError(t, db.Create(event).Error)
+	assert.NoError(t, db.Create(&[]NetResult{
+		{EventID: event.EventID, Rank: "2", Player: "Andy Lee", Total: "+4"},
+		{EventID: event.EventID, Rank: "1", Player: "Connor Shaw", Total: "+2"},
+	}).Error)
+	assert.NoError(t, db.Create(&SkinsPlayerResult{EventID: event.EventID, Rank: "1", Player: "Jim Tokanel", Skins: "2"}).Error)
+
+	s.publishResults(event.EventID)
+
+	var entries []FeedEntry
+	assert.NoError(t, db.Find(&entries).Error)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "Results posted: Impact Fire Open", entries[0].Title)
+	assert.Equal(t, "Net: 1. Connor Shaw (+2), 2. Andy Lee (+4)\nSkins: Jim Tokanel (2)", entries[0].Summary)
+	assert.Equal(t, "https://livefreegolf.com/events/2025-impact-fire-open", entries[0].Link)
+
+	// Downloading the same results again doesn't publish them twice.
+	assert.NoError(t, db.Unscoped().Where("event_id = ?", event.EventID).Delete(&NetResult{}).Error)
+	assert.NoError(t, db.Create(&[]NetResult{
+		{EventID: event.EventID, Rank: "1", Player: "Connor Shaw", Total: "+2"},
+		{EventID: event.EventID, Rank: "2", Player: "Andy Lee", Total: "+4"},
+	}).Error)
+	s.publishResults(event.EventID)
+	var count int64
+	assert.NoError(t, db.Model(&FeedEntry{}).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+
+	assert.NoError(t, db.Model(&NetResult{}).Where("player = ?", "Andy Lee").Update("total", "+3").Error)
+	s.publishResults(event.EventID)
+	assert.NoError(t, db.Model(&FeedEntry{}).Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+
+	feed := buildAtomFeed(entries, "https://example.com/api/feed.atom")
+	out, err := xml.Marshal(feed)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	assert.Contains(t, string(out), "<title>Results posted: Impact Fire Open</title>")
+}
+
+func TestServer_publishMatchPlay(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = applyMigrations(db)
+	assert.NoError(t, err)
+
+	s := &Server{db: db, publicURL: "https://lfg.example.com/"}
+
+	matches := []MatchPlayMatch{
+		{Year: "2025", Round: "Final", MatchNum: 1, Player1: "Connor Shaw", Player2: "Andy Lee"},
+	}
+	assert.NoError(t, db.Create(&matches).Error)
+
+	s.publishMatchPlay("2025", nil)
+	// Refreshing an unchanged bracket publishes nothing new.
+	s.publishMatchPlay("2025", matches)
+	var count int64
+	assert.NoError(t, db.Model(&FeedEntry{}).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+
+	before := append([]MatchPlayMatch(nil), matches...)
+	assert.NoError(t, db.Model(&matches[0]).Update("winner", "Connor Shaw").Error)
+	s.publishMatchPlay("2025", before)
+	s.publishMatchPlay("2025", nil)
+	var entries []FeedEntry
+	assert.NoError(t, db.Order("id ASC").Find(&entries).Error)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "Connor Shaw wins the 2025 match play championship.", entries[1].Summary)
+
+	// The feed links to itself through the public URL, not the request host.
+	r := httptest.NewRequest(http.MethodGet, "/api/feed.atom", nil)
+	r.Host = "attacker.example"
+	w := httptest.NewRecorder()
+	s.GETAtomFeed(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `href="https://lfg.example.com/api/feed.atom"`)
+	assert.NotContains(t, w.Body.String(), "attacker.example")
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	fieldPollInterval = time.Hour
+	// Fields are checked for events in the next two months.
+	fieldLookahead = 60 * 24 * time.Hour
+)
+
+// eventPageURL is a page of the event on its BlueGolf site. The leaderboard
+// lists the field until the event is played and waitlist.htm lists the
+// players waiting for a spot.
+func (s *Server) eventPageURL(event *Event, page string) (string, error) {
+	blueGolfID, err := blueGolfEventID(event)
+	if err != nil {
+		return "", err
+	}
+	site, err := eventBlueGolfSite(s.db, event)
+	if err != nil {
+		return "", err
+	}
+	return site.EventURL(blueGolfID, page), nil
+}
+
+// refreshField scrapes an event's field and waitlist and records who
+// registered and who withdrew since the last check. An empty field never
+// withdraws everyone, and registrants on the disabled golfer list are
+// flagged. If the waitlist can't be read the stored one is kept.
+func (s *Server) refreshField(event *Event) ([]EventRegistration, error) {
+	var sync EventFieldSync
+	s.beginSync(&sync, event.EventID)
+
+	fieldURL, err := s.eventPageURL(event, "leaderboard.htm")
+	var scraped []EventRegistration
+	if err == nil {
+		scraped, err = scrapeField(fieldURL)
+	}
+	if err != nil {
+		return nil, s.failSync(&sync, err)
+	}
+	waitlistURL, _ := s.eventPageURL(event, "waitlist.htm")
+	waiting, werr := scrapeWaitlist(waitlistURL)
+	if werr != nil {
+		log.Printf("error fetching waitlist for %s: %s", event.EventID, werr)
+	}
+	for i := range waiting {
+		waiting[i].Waitlisted = true
+	}
+	registered := len(scraped)
+	scraped = append(scraped, waiting...)
+
+	var existing []EventRegistration
+	if err := s.db.Where("event_id = ?", event.EventID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	var golfers []DisabledGolfer
+	if err := s.db.Find(&golfers).Error; err != nil {
+		return nil, err
+	}
+	disabled := make(map[string]string)
+	for _, g := range golfers {
+		disabled[strings.ToLower(g.Name)] = g.Reason
+	}
+
+	byPlayer := make(map[string]*EventRegistration)
+	for i := range existing {
+		byPlayer[strings.ToLower(existing[i].Player)] = &existing[i]
+	}
+	inField := make(map[string]bool)
+	now := time.Now()
+
+	var added []EventRegistration
+	for _, reg := range scraped {
+		key := strings.ToLower(reg.Player)
+		if inField[key] {
+			// Players in the field are sometimes still listed as waiting.
+			continue
+		}
+		inField[key] = true
+		if prev, ok := byPlayer[key]; ok {
+			prev.WithdrawnAt = nil
+			prev.Waitlisted = reg.Waitlisted
+			if reg.BlueGolfID != "" {
+				prev.BlueGolfID = reg.BlueGolfID
+			}
+			continue
+		}
+		reg.EventID = event.EventID
+		reg.RegisteredAt = now
+		added = append(added, reg)
+	}
+	if registered > 0 {
+		for i := range existing {
+			reg := &existing[i]
+			if inField[strings.ToLower(reg.Player)] || reg.WithdrawnAt != nil || (reg.Waitlisted && werr != nil) {
+				continue
+			}
+			reg.WithdrawnAt = &now
+		}
+	}
+
+	// Golfers may be disabled after they register, so every row is checked.
+	flag := func(reg *EventRegistration) {
+		reason, ok := disabled[strings.ToLower(reg.Player)]
+		if ok && !reg.Disabled && reg.WithdrawnAt == nil {
+			log.Printf("disabled golfer %s is registered for %s", reg.Player, event.EventID)
+		}
+		reg.Disabled, reg.DisabledReason = ok, reason
+	}
+	for i := range existing {
+		flag(&existing[i])
+	}
+	for i := range added {
+		flag(&added[i])
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range existing {
+			if err := tx.Save(&existing[i]).Error; err != nil {
+				return err
+			}
+		}
+		if len(added) > 0 {
+			if err := tx.Create(&added).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(&sync).Error
+	}); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// refreshUpcomingFields checks the field of every upcoming event.
+func (s *Server) refreshUpcomingFields(now time.Time) {
+	s.refreshUpcoming("field", now, now.Add(fieldLookahead), func(e *Event) error {
+		_, err := s.refreshField(e)
+		return err
+	})
+}
+
+func (s *Server) runFieldWorker() {
+	ticker := time.NewTicker(fieldPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.refreshUpcomingFields(time.Now())
+	}
+}
+
+// EventField is an event's field with its counts.
+type EventField struct {
+	EventID     string              `json:"eventID"`
+	CheckedAt   *time.Time          `json:"checkedAt"`
+	Registered  int                 `json:"registered"`
+	Waitlisted  int                 `json:"waitlisted"`
+	Withdrawn   int                 `json:"withdrawn"`
+	Disabled    int                 `json:"disabled"`
+	Field       []EventRegistration `json:"field"`
+	Waitlist    []EventRegistration `json:"waitlist"`
+	Withdrawals []EventRegistration `json:"withdrawals"`
+}
+
+func (s *Server) loadEventField(eventID string) (*EventField, error) {
+	var regs []EventRegistration
+	if err := s.db.Where("event_id = ?", eventID).Order("registered_at ASC, player ASC").Find(&regs).Error; err != nil {
+		return nil, err
+	}
+	field := &EventField{
+		EventID:     eventID,
+		Field:       []EventRegistration{},
+		Waitlist:    []EventRegistration{},
+		Withdrawals: []EventRegistration{},
+	}
+	for _, reg := range regs {
+		if reg.WithdrawnAt != nil {
+			field.Withdrawals = append(field.Withdrawals, reg)
+			continue
+		}
+		if reg.Waitlisted {
+			field.Waitlist = append(field.Waitlist, reg)
+			continue
+		}
+		field.Field = append(field.Field, reg)
+		if reg.Disabled {
+			field.Disabled++
+		}
+	}
+	field.Registered, field.Waitlisted, field.Withdrawn = len(field.Field), len(field.Waitlist), len(field.Withdrawals)
+
+	var sync EventFieldSync
+	s.db.Where("event_id = ?", eventID).Limit(1).Find(&sync)
+	if sync.ID != 0 {
+		field.CheckedAt = &sync.CheckedAt
+	}
+	return field, nil
+}
+
+// GET /api/events/{eventID}/field
+func (s *Server) GETEventField(w http.ResponseWriter, r *http.Request) {
+	var event Event
+	if err := s.visibleEvents(r).First(&event, "event_id = ?", chi.URLParam(r, "eventID")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Event not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if event.BlueGolfUrl != "" {
+		if err := s.syncOnFirstRequest(&EventFieldSync{}, event.EventID, func() error {
+			_, err := s.refreshField(&event)
+			return err
+		}); err != nil {
+			http.Error(w, fmt.Sprintf("Error fetching field: %s", err.Error()), http.StatusBadGateway)
+			return
+		}
+	}
+
+	field, err := s.loadEventField(event.EventID)
+	if err != nil {
+		http.Error(w, "Error fetching field", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(field)
+}
+
+// POST /api/events/{eventID}/field/refresh
+func (s *Server) POSTRefreshEventField(w http.ResponseWriter, r *http.Request) {
+	var event Event
+	if err := s.db.First(&event, "event_id = ?", chi.URLParam(r, "eventID")).Error; err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	if _, err := s.refreshField(&event); err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching field: %s", err.Error()), http.StatusBadGateway)
+		return
+	}
+
+	field, err := s.loadEventField(event.EventID)
+	if err != nil {
+		http.Error(w, "Error fetching field", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(field)
+}
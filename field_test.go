@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestServer_refreshField(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	field := []EventRegistration{{Player: "Connor Shaw", BlueGolfID: "1"}, {Player: "Andy Lee", BlueGolfID: "2"}}
+	defer func(orig func(string) ([]EventRegistration, error)) { scrapeField = orig }(scrapeField)
+	scrapeField = func(url string) ([]EventRegistration, error) {
+		assert.Equal(t, "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclublivefreegc25/event/lfg-open/leaderboard.htm", url)
+		out := make([]EventRegistration, len(field))
+		copy(out, field)
+		return out, nil
+	}
+	var waitlist []EventRegistration
+	var waitlistErr error
+	defer func(orig func(string) ([]EventRegistration, error)) { scrapeWaitlist = orig }(scrapeWaitlist)
+	scrapeWaitlist = func(url string) ([]EventRegistration, error) {
+		assert.Equal(t, "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclublivefreegc25/event/lfg-open/waitlist.htm", url)
+		out := make([]EventRegistration, len(waitlist))
+		copy(out, waitlist)
+		return out, waitlistErr
+	}
+
+	event := &Event{Name: "LFG Open", DateString: "2025-06-14", BlueGolfUrl: "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclublivefreegc25/event/lfg-open/index.htm"}
+	assert.NoError(t, db.Create(event).Error)
+	assert.NoError(t, db.Create(&DisabledGolfer{Name: "Mike Ross", Reason: "Conduct"}).Error)
+
+	added, err := s.refreshField(event)
+	assert.NoError(t, err)
+	assert.Len(t, added, 2)
+
+	// Andy Lee withdraws and Mike Ross registers.
+	field = []EventRegistration{{Player: "Connor Shaw"}, {Player: "Mike Ross"}}
+	added, err = s.refreshField(event)
+	assert.NoError(t, err)
+	assert.Len(t, added, 1)
+	assert.True(t, added[0].Disabled)
+
+	// An empty field withdraws nobody.
+	field = nil
+	_, err = s.refreshField(event)
+	assert.NoError(t, err)
+
+	ef, err := s.loadEventField(event.EventID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ef.Registered)
+	assert.Equal(t, 1, ef.Withdrawn)
+	assert.Equal(t, 1, ef.Disabled)
+	assert.Equal(t, "Andy Lee", ef.Withdrawals[0].Player)
+	assert.Equal(t, "1", ef.Field[0].BlueGolfID)
+	assert.Equal(t, "Conduct", ef.Field[1].DisabledReason)
+	assert.NotNil(t, ef.CheckedAt)
+
+	// Andy Lee comes back.
+	field = []EventRegistration{{Player: "andy lee"}}
+	added, err = s.refreshField(event)
+	assert.NoError(t, err)
+	assert.Empty(t, added)
+	ef, err = s.loadEventField(event.EventID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ef.Registered)
+	assert.Equal(t, "Andy Lee", ef.Field[0].Player)
+
+	// Sam Ortiz joins the waitlist.
+	waitlist = []EventRegistration{{Player: "Sam Ortiz"}}
+	added, err = s.refreshField(event)
+	assert.NoError(t, err)
+	assert.Len(t, added, 1)
+	assert.True(t, added[0].Waitlisted)
+	ef, err = s.loadEventField(event.EventID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ef.Registered)
+	assert.Equal(t, 1, ef.Waitlisted)
+	assert.Equal(t, "Sam Ortiz", ef.Waitlist[0].Player)
+
+	// A failed waitlist fetch keeps the stored waitlist.
+	waitlist, waitlistErr = nil, errors.New("unreachable")
+	_, err = s.refreshField(event)
+	assert.NoError(t, err)
+	ef, err = s.loadEventField(event.EventID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ef.Waitlisted)
+
+	// Sam Ortiz gets a spot in the field.
+	field, waitlistErr = []EventRegistration{{Player: "Andy Lee"}, {Player: "Sam Ortiz"}}, nil
+	added, err = s.refreshField(event)
+	assert.NoError(t, err)
+	assert.Empty(t, added)
+	ef, err = s.loadEventField(event.EventID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ef.Registered)
+	assert.Equal(t, 0, ef.Waitlisted)
+}
@@ -0,0 +1,112 @@
+package handicap
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Hole is a played hole with its par and stroke index.
+type Hole struct {
+	Par         int
+	StrokeIndex int // 1 is the hardest hole
+	Gross       int // Zero when the hole was not played
+}
+
+// CourseHandicap converts a Handicap Index into the number of strokes
+// received from a set of tees: index × slope / 113 + (course rating − par).
+func CourseHandicap(index float64, slope int, courseRating float64, par int) int {
+	if slope <= 0 {
+		slope = StandardSlope
+	}
+	return int(math.Round(index*float64(slope)/StandardSlope + (courseRating - float64(par))))
+}
+
+// PlayingHandicap applies a handicap allowance, such as 0.9 for 90%, to a
+// course handicap.
+func PlayingHandicap(courseHandicap int, allowance float64) int {
+	return int(math.Round(float64(courseHandicap) * allowance))
+}
+
+var allowanceRegex = regexp.MustCompile(`^\s*(\d+(?:\.\d+)?)\s*(%?)\s*$`)
+
+// ParseAllowance parses a handicap allowance written as "90%", "90" or
+// "0.9". Bare numbers of 2 or more are percentages and anything else must
+// be a fraction, so "1.5" is rejected rather than read as 1.5%. Anything
+// after the number and percent sign is rejected. An empty allowance is a
+// full 100%.
+func ParseAllowance(s string) (float64, error) {
+	if strings.TrimSpace(s) == "" {
+		return 1, nil
+	}
+	m := allowanceRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid handicap allowance %q", s)
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	if m[2] == "%" || v >= 2 {
+		v /= 100
+	}
+	if v <= 0 || v > 1 {
+		return 0, fmt.Errorf("invalid handicap allowance %q", s)
+	}
+	return v, nil
+}
+
+// Strokes returns the handicap strokes received on a hole with the given
+// stroke index. Plus handicaps give strokes back starting from the easiest
+// hole.
+func Strokes(courseHandicap, strokeIndex, holes int) int {
+	if holes <= 0 || strokeIndex <= 0 {
+		return 0
+	}
+	if courseHandicap < 0 {
+		plus := -courseHandicap
+		n := plus / holes
+		if plus%holes > holes-strokeIndex {
+			n++
+		}
+		return -n
+	}
+	n := courseHandicap / holes
+	if strokeIndex <= courseHandicap%holes {
+		n++
+	}
+	return n
+}
+
+// NetScore returns the gross score of the played holes less the strokes
+// received on them.
+func NetScore(holes []Hole, playingHandicap int) int {
+	var total int
+	for _, h := range holes {
+		if h.Gross <= 0 {
+			continue
+		}
+		total += h.Gross - Strokes(playingHandicap, h.StrokeIndex, len(holes))
+	}
+	return total
+}
+
+// AdjustedGross applies the net double bogey maximum hole score to a round.
+// Holes that were not played are scored as net par.
+func AdjustedGross(holes []Hole, courseHandicap int) int {
+	var total int
+	for _, h := range holes {
+		strokes := Strokes(courseHandicap, h.StrokeIndex, len(holes))
+		switch limit := h.Par + 2 + strokes; {
+		case h.Gross <= 0:
+			total += h.Par + strokes
+		case h.Gross > limit:
+			total += limit
+		default:
+			total += h.Gross
+		}
+	}
+	return total
+}
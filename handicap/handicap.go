@@ -0,0 +1,244 @@
+// Package handicap implements the World Handicap System calculations used by
+// the league: score differentials, the Handicap Index, caps against the low
+// index and exceptional score reductions.
+package handicap
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// MaxIndex is the highest Handicap Index that can be issued.
+	MaxIndex = 54.0
+
+	// StandardSlope is the slope rating of a course of standard difficulty.
+	StandardSlope = 113
+
+	// MaxScores is the number of most recent scores used for the index.
+	MaxScores = 20
+
+	// MinScores is the number of scores needed before an index is issued.
+	MinScores = 3
+
+	softCapThreshold = 3.0
+	hardCapThreshold = 5.0
+	lowIndexWindow   = 365 * 24 * time.Hour
+)
+
+// Cap describes which cap, if any, limited a Handicap Index.
+type Cap string
+
+const (
+	NoCap   Cap = ""
+	SoftCap Cap = "soft"
+	HardCap Cap = "hard"
+)
+
+var ErrNotEnoughScores = errors.New("at least 3 scores are needed for a handicap index")
+
+// Score is an 18 hole round posted for handicap purposes.
+type Score struct {
+	Date          time.Time
+	AdjustedGross int
+	CourseRating  float64
+	Slope         int
+	PCC           int // Playing conditions calculation, -1 to +3
+}
+
+// Differential returns the score differential for a round, rounded to the
+// nearest tenth.
+func Differential(adjustedGross int, courseRating float64, slope, pcc int) float64 {
+	if slope <= 0 {
+		slope = StandardSlope
+	}
+	d := float64(StandardSlope) / float64(slope) * (float64(adjustedGross) - courseRating - float64(pcc))
+	return round1(d)
+}
+
+// Differential returns the score differential for the round.
+func (s Score) Differential() float64 {
+	return Differential(s.AdjustedGross, s.CourseRating, s.Slope, s.PCC)
+}
+
+// scoresToUse returns how many of the lowest differentials count towards the
+// index and the adjustment applied to their average, per WHS Rule 5.2.
+func scoresToUse(n int) (int, float64) {
+	switch {
+	case n < MinScores:
+		return 0, 0
+	case n == 3:
+		return 1, -2.0
+	case n == 4:
+		return 1, -1.0
+	case n == 5:
+		return 1, 0
+	case n == 6:
+		return 2, -1.0
+	case n <= 8:
+		return 2, 0
+	case n <= 11:
+		return 3, 0
+	case n <= 14:
+		return 4, 0
+	case n <= 16:
+		return 5, 0
+	case n <= 18:
+		return 6, 0
+	case n == 19:
+		return 7, 0
+	default:
+		return 8, 0
+	}
+}
+
+// Index calculates a Handicap Index from differentials ordered most recent
+// first. Only the 20 most recent are considered. The returned slice holds the
+// positions of the differentials that counted.
+func Index(differentials []float64) (float64, []int, error) {
+	if len(differentials) > MaxScores {
+		differentials = differentials[:MaxScores]
+	}
+	count, adjustment := scoresToUse(len(differentials))
+	if count == 0 {
+		return 0, nil, ErrNotEnoughScores
+	}
+
+	order := make([]int, len(differentials))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return differentials[order[i]] < differentials[order[j]]
+	})
+	used := order[:count]
+
+	var sum float64
+	for _, i := range used {
+		sum += differentials[i]
+	}
+	index := round1(sum/float64(count) + adjustment)
+	sort.Ints(used)
+	return math.Min(index, MaxIndex), used, nil
+}
+
+// ApplyCaps limits an index against the player's low index over the previous
+// year. Any increase of more than 3.0 strokes is halved (the soft cap) and
+// the index can never be more than 5.0 strokes above the low index (the hard
+// cap).
+func ApplyCaps(index, lowIndex float64) (float64, Cap) {
+	increase := index - lowIndex
+	if increase <= softCapThreshold {
+		return index, NoCap
+	}
+	capped := lowIndex + softCapThreshold + (increase-softCapThreshold)/2
+	if capped-lowIndex > hardCapThreshold {
+		return round1(lowIndex + hardCapThreshold), HardCap
+	}
+	return round1(capped), SoftCap
+}
+
+// ExceptionalReduction returns the exceptional score reduction for a
+// differential posted against the index the player held at the time: 1 when
+// it is 7.0 to 9.9 strokes better and 2 when it is 10.0 or more.
+func ExceptionalReduction(differential, index float64) float64 {
+	better := round1(index - differential)
+	switch {
+	case better >= 10.0:
+		return 2
+	case better >= 7.0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Revision is the state of a player's handicap after a score is posted.
+type Revision struct {
+	Date         time.Time
+	Differential float64 // Differential of the score, after any reductions
+	Index        float64 // Zero until MinScores have been posted
+	Established  bool    // Whether an index has been issued
+	LowIndex     float64 // Zero until a low index is established
+	Cap          Cap
+	Reduction    float64 // Exceptional score reduction triggered by the score
+	Used         bool    // Whether the score counts towards the current index
+}
+
+// History replays a player's scores in date order and returns the handicap
+// revision after each score in the same order. Exceptional score reductions
+// are applied to the 20 most recent differentials at the time, and caps apply
+// once a low index has been established from 20 scores.
+func History(scores []Score) []Revision {
+	sorted := make([]Score, len(scores))
+	copy(sorted, scores)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	diffs := make([]float64, 0, len(sorted)) // Oldest first
+	revisions := make([]Revision, 0, len(sorted))
+	for i, score := range sorted {
+		d := score.Differential()
+		diffs = append(diffs, d)
+
+		rev := Revision{Date: score.Date}
+		if i > 0 && revisions[i-1].Established {
+			rev.Reduction = ExceptionalReduction(d, revisions[i-1].Index)
+			for j := len(diffs) - 1; j >= 0 && j >= len(diffs)-MaxScores; j-- {
+				diffs[j] = round1(diffs[j] - rev.Reduction)
+			}
+		}
+
+		recent := mostRecent(diffs)
+		index, _, err := Index(recent)
+		if err == nil {
+			rev.Established = true
+			if low, ok := lowIndex(revisions, score.Date); ok {
+				rev.LowIndex = low
+				index, rev.Cap = ApplyCaps(index, low)
+			}
+			rev.Index = index
+		}
+		revisions = append(revisions, rev)
+	}
+
+	for i := range revisions {
+		revisions[i].Differential = diffs[i]
+	}
+	if n := len(diffs); n > 0 && revisions[n-1].Established {
+		_, used, _ := Index(mostRecent(diffs))
+		for _, u := range used {
+			revisions[n-1-u].Used = true
+		}
+	}
+	return revisions
+}
+
+// mostRecent returns up to 20 differentials, most recent first.
+func mostRecent(oldestFirst []float64) []float64 {
+	var out []float64
+	for i := len(oldestFirst) - 1; i >= 0 && len(out) < MaxScores; i-- {
+		out = append(out, oldestFirst[i])
+	}
+	return out
+}
+
+// lowIndex returns the lowest index issued in the year before date from
+// revisions that were based on at least 20 scores.
+func lowIndex(revisions []Revision, date time.Time) (float64, bool) {
+	low, ok := 0.0, false
+	for i, r := range revisions {
+		if i+1 < MaxScores || !r.Established || date.Sub(r.Date) > lowIndexWindow {
+			continue
+		}
+		if !ok || r.Index < low {
+			low, ok = r.Index, true
+		}
+	}
+	return low, ok
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,154 @@
+package handicap
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDifferential(t *testing.T) {
+	assert.Equal(t, 12.5, Differential(85, 71.2, 125, 0))
+	assert.Equal(t, 11.6, Differential(85, 71.2, 125, 1))
+	assert.Equal(t, -1.8, Differential(70, 71.8, 113, 0))
+}
+
+func TestIndex(t *testing.T) {
+	_, _, err := Index([]float64{10, 12})
+	assert.ErrorIs(t, err, ErrNotEnoughScores)
+
+	// Three scores use the lowest minus 2.0.
+	idx, used, err := Index([]float64{14.2, 10.1, 12.0})
+	assert.NoError(t, err)
+	assert.Equal(t, 8.1, idx)
+	assert.Equal(t, []int{1}, used)
+
+	// Twenty scores use the best eight. Older scores are ignored.
+	diffs := []float64{
+		20, 20, 10, 20, 11, 20, 12, 20, 13, 20,
+		14, 20, 15, 20, 16, 20, 17, 20, 20, 20,
+		1, 1,
+	}
+	idx, used, err = Index(diffs)
+	assert.NoError(t, err)
+	assert.Equal(t, 13.5, idx)
+	assert.Equal(t, []int{2, 4, 6, 8, 10, 12, 14, 16}, used)
+
+	idx, _, err = Index([]float64{70, 70, 70})
+	assert.NoError(t, err)
+	assert.Equal(t, MaxIndex, idx)
+}
+
+func TestApplyCaps(t *testing.T) {
+	idx, c := ApplyCaps(12.0, 10.0)
+	assert.Equal(t, 12.0, idx)
+	assert.Equal(t, NoCap, c)
+
+	idx, c = ApplyCaps(15.0, 10.0)
+	assert.Equal(t, 14.0, idx)
+	assert.Equal(t, SoftCap, c)
+
+	idx, c = ApplyCaps(19.0, 10.0)
+	assert.Equal(t, 15.0, idx)
+	assert.Equal(t, HardCap, c)
+}
+
+func TestExceptionalReduction(t *testing.T) {
+	assert.Equal(t, 0.0, ExceptionalReduction(8.1, 15.0))
+	assert.Equal(t, 1.0, ExceptionalReduction(8.0, 15.0))
+	assert.Equal(t, 2.0, ExceptionalReduction(5.0, 15.0))
+}
+
+func TestHistory(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	var scores []Score
+	for i := 0; i < 5; i++ {
+		scores = append(scores, Score{Date: start.AddDate(0, 0, 7*i), AdjustedGross: 90, CourseRating: 72, Slope: 113})
+	}
+	// An exceptional round of 70 against an index of 18.0.
+	scores = append(scores, Score{Date: start.AddDate(0, 0, 35), AdjustedGross: 70, CourseRating: 72, Slope: 113})
+
+	revs := History(scores)
+	assert.Len(t, revs, 6)
+	assert.False(t, revs[1].Established)
+	assert.Equal(t, 16.0, revs[2].Index)
+	assert.Equal(t, 18.0, revs[4].Index)
+
+	last := revs[5]
+	assert.Equal(t, 2.0, last.Reduction)
+	assert.Equal(t, -4.0, last.Differential)
+	assert.Equal(t, 16.0, revs[0].Differential)
+	// Two scores: (-4.0 + 16.0) / 2 - 1.0
+	assert.Equal(t, 5.0, last.Index)
+	assert.True(t, last.Used)
+}
+
+func TestHistoryCaps(t *testing.T) {
+	start := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	var scores []Score
+	for i := 0; i < 20; i++ {
+		scores = append(scores, Score{Date: start.AddDate(0, 0, i), AdjustedGross: 80, CourseRating: 72, Slope: 113})
+	}
+	for i := 20; i < 40; i++ {
+		scores = append(scores, Score{Date: start.AddDate(0, 0, i), AdjustedGross: 100, CourseRating: 72, Slope: 113})
+	}
+
+	revs := History(scores)
+	assert.Equal(t, 8.0, revs[19].Index)
+	assert.Equal(t, 8.0, revs[39].LowIndex)
+	assert.Equal(t, HardCap, revs[39].Cap)
+	assert.Equal(t, 13.0, revs[39].Index)
+}
+
+func TestCourseHandicap(t *testing.T) {
+	assert.Equal(t, 15, CourseHandicap(14.3, 128, 71.2, 72))
+	assert.Equal(t, -3, CourseHandicap(-2.1, 135, 72.0, 72))
+}
+
+func TestStrokes(t *testing.T) {
+	assert.Equal(t, 1, Strokes(16, 16, 18))
+	assert.Equal(t, 0, Strokes(16, 17, 18))
+	assert.Equal(t, 2, Strokes(20, 2, 18))
+	assert.Equal(t, 1, Strokes(20, 3, 18))
+	assert.Equal(t, -1, Strokes(-2, 17, 18))
+	assert.Equal(t, 0, Strokes(-2, 16, 18))
+}
+
+func TestAdjustedGross(t *testing.T) {
+	holes := make([]Hole, 18)
+	for i := range holes {
+		holes[i] = Hole{Par: 4, StrokeIndex: i + 1, Gross: 5}
+	}
+	holes[0].Gross = 9  // Capped at 4 + 2 + 1 = 7
+	holes[17].Gross = 8 // Capped at 4 + 2 = 6
+	holes[5].Gross = 0  // Not played, net par of 5
+	assert.Equal(t, 15*5+7+6+5, AdjustedGross(holes, 10))
+}
+
+func TestParseAllowance(t *testing.T) {
+	for in, want := range map[string]float64{"": 1, "90%": 0.9, "85": 0.85, "0.95": 0.95, "1": 1, "2": 0.02, "1.5%": 0.015, " 100 % ": 1} {
+		got, err := ParseAllowance(in)
+		assert.NoError(t, err, in)
+		assert.InDelta(t, want, got, 1e-9, in)
+	}
+	_, err := ParseAllowance("full")
+	assert.Error(t, err)
+	_, err = ParseAllowance("120%")
+	assert.Error(t, err)
+	_, err = ParseAllowance("1.5")
+	assert.Error(t, err)
+	_, err = ParseAllowance("0")
+	assert.Error(t, err)
+	for _, in := range []string{"90%abc", "100% of course handicap", "85 90"} {
+		_, err = ParseAllowance(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestNetScore(t *testing.T) {
+	holes := make([]Hole, 18)
+	for i := range holes {
+		holes[i] = Hole{Par: 4, StrokeIndex: i + 1, Gross: 5}
+	}
+	assert.Equal(t, 90-PlayingHandicap(16, 0.9), NetScore(holes, PlayingHandicap(16, 0.9)))
+	assert.Equal(t, 14, PlayingHandicap(16, 0.9))
+}
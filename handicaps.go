@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cpacia/lfg-server/handicap"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recomputeHandicap replays all of a player's scores and replaces their
+// stored handicap history.
+func recomputeHandicap(tx *gorm.DB, player string) error {
+	var scores []HandicapScore
+	if err := tx.Where("player = ?", player).Order("date ASC, id ASC").Find(&scores).Error; err != nil {
+		return err
+	}
+
+	input := make([]handicap.Score, 0, len(scores))
+	for _, sc := range scores {
+		date, err := time.Parse("2006-01-02", sc.Date)
+		if err != nil {
+			return fmt.Errorf("score %d has invalid date %q", sc.ID, sc.Date)
+		}
+		input = append(input, handicap.Score{
+			Date:          date,
+			AdjustedGross: sc.AdjustedGross,
+			CourseRating:  sc.CourseRating,
+			Slope:         sc.Slope,
+			PCC:           sc.PCC,
+		})
+	}
+
+	// Scores are already in date order so revisions line up with them.
+	history := handicap.History(input)
+	var revisions []HandicapRevision
+	for i, rev := range history {
+		sc := &scores[i]
+		sc.Differential = rev.Differential
+		sc.Reduction = rev.Reduction
+		sc.Used = rev.Used
+		if err := tx.Model(sc).Select("differential", "reduction", "used").Updates(sc).Error; err != nil {
+			return err
+		}
+		if rev.Established {
+			revisions = append(revisions, HandicapRevision{
+				Player:   player,
+				ScoreID:  sc.ID,
+				Date:     sc.Date,
+				Index:    rev.Index,
+				LowIndex: rev.LowIndex,
+				Cap:      string(rev.Cap),
+			})
+		}
+	}
+
+	if err := tx.Unscoped().Where("player = ?", player).Delete(&HandicapRevision{}).Error; err != nil {
+		return err
+	}
+	if len(revisions) > 0 {
+		return tx.Create(&revisions).Error
+	}
+	return nil
+}
+
+// handicapIndexBefore returns the index a player held going into the given
+// date.
+func handicapIndexBefore(db *gorm.DB, player, date string) (float64, bool) {
+	var revs []HandicapRevision
+	err := db.Where("LOWER(player) = LOWER(?) AND date < ?", player, date).
+		Order("date DESC, id DESC").Limit(1).Find(&revs).Error
+	if err != nil || len(revs) == 0 {
+		return 0, false
+	}
+	return revs[0].Index, true
+}
+
+// importEventHandicapScores posts the scorecards from an event played from a
+// known tee to each player's handicap record, replacing any scores previously
+// imported from the event.
+func (s *Server) importEventHandicapScores(eventID string) (int, error) {
+	var event Event
+	if err := s.db.First(&event, "event_id = ?", eventID).Error; err != nil {
+		return 0, err
+	}
+	course, tee, err := s.loadEventTee(&event)
+	if err != nil {
+		return 0, err
+	}
+	cards, err := s.loadScorecards([]string{eventID})
+	if err != nil {
+		return 0, err
+	}
+
+	var scores []HandicapScore
+	for _, card := range cards {
+		if len(card.Holes) != len(tee.Holes) || len(tee.Holes) != 18 {
+			continue
+		}
+		date := time.Time(event.Date).AddDate(0, 0, card.Round-1).Format("2006-01-02")
+
+		// Players without an index are limited to par plus five on each
+		// hole, the same as net double bogey with 54 strokes.
+		courseHcp := 54
+		if idx, ok := handicapIndexBefore(s.db, card.Player, date); ok {
+			courseHcp = handicap.CourseHandicap(idx, tee.Slope, tee.Rating, tee.Par)
+		}
+
+		holes := make([]handicap.Hole, len(tee.Holes))
+		for i, th := range tee.Holes {
+			holes[i] = handicap.Hole{Par: th.Par, StrokeIndex: th.Handicap}
+		}
+		for _, hs := range card.Holes {
+			if hs.Hole >= 1 && hs.Hole <= len(holes) {
+				holes[hs.Hole-1].Gross = hs.Gross
+			}
+		}
+
+		scores = append(scores, HandicapScore{
+			Player:        card.Player,
+			EventID:       eventID,
+			Date:          date,
+			Course:        course.Name,
+			Tee:           tee.Name,
+			AdjustedGross: handicap.AdjustedGross(holes, courseHcp),
+			CourseRating:  tee.Rating,
+			Slope:         tee.Slope,
+		})
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var previous []string
+		if err := tx.Model(&HandicapScore{}).Where("event_id = ?", eventID).Distinct().Pluck("player", &previous).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("event_id = ?", eventID).Delete(&HandicapScore{}).Error; err != nil {
+			return err
+		}
+		if len(scores) > 0 {
+			if err := tx.Create(&scores).Error; err != nil {
+				return err
+			}
+		}
+
+		players := make(map[string]bool)
+		for _, p := range previous {
+			players[p] = true
+		}
+		for _, sc := range scores {
+			players[sc.Player] = true
+		}
+		for p := range players {
+			if err := recomputeHandicap(tx, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(scores), err
+}
+
+// deleteEventHandicapScores removes the scores imported from an event and
+// recalculates the affected players.
+func deleteEventHandicapScores(db *gorm.DB, eventID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var players []string
+		if err := tx.Model(&HandicapScore{}).Where("event_id = ?", eventID).Distinct().Pluck("player", &players).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("event_id = ?", eventID).Delete(&HandicapScore{}).Error; err != nil {
+			return err
+		}
+		for _, p := range players {
+			if err := recomputeHandicap(tx, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// refreshHandicaps imports an event's scores after its scorecards change.
+// Events that are not linked to a tee are skipped.
+func (s *Server) refreshHandicaps(eventID string) {
+	if _, err := s.importEventHandicapScores(eventID); err != nil && !errors.Is(err, errNoEventTee) {
+		log.Printf("error importing handicap scores for %s: %s", eventID, err)
+	}
+}
+
+func handicapPlayerParam(r *http.Request) (string, error) {
+	player, err := url.PathUnescape(chi.URLParam(r, "player"))
+	if err != nil {
+		return "", err
+	}
+	player = strings.TrimSpace(player)
+	if player == "" {
+		return "", errors.New("Player must be provided")
+	}
+	return player, nil
+}
+
+// GET /api/handicaps
+// Returns the current index of every player with an established handicap.
+func (s *Server) GETHandicaps(w http.ResponseWriter, r *http.Request) {
+	var revisions []HandicapRevision
+	if err := s.db.Order("player ASC, date ASC, id ASC").Find(&revisions).Error; err != nil {
+		http.Error(w, "Error fetching handicaps", http.StatusInternalServerError)
+		return
+	}
+
+	current := []HandicapRevision{}
+	for _, rev := range revisions {
+		if n := len(current); n > 0 && current[n-1].Player == rev.Player {
+			current[n-1] = rev
+		} else {
+			current = append(current, rev)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(current)
+}
+
+// GET /api/handicaps/{player}
+func (s *Server) GETPlayerHandicap(w http.ResponseWriter, r *http.Request) {
+	player, err := handicapPlayerParam(r)
+	if err != nil {
+		http.Error(w, "Invalid name", http.StatusBadRequest)
+		return
+	}
+
+	var scores []HandicapScore
+	if err := s.db.Where("LOWER(player) = LOWER(?)", player).Order("date DESC, id DESC").Find(&scores).Error; err != nil {
+		http.Error(w, "Error fetching scores", http.StatusInternalServerError)
+		return
+	}
+	if len(scores) == 0 {
+		http.Error(w, "Player not found", http.StatusNotFound)
+		return
+	}
+	var history []HandicapRevision
+	if err := s.db.Where("player = ?", scores[0].Player).Order("date ASC, id ASC").Find(&history).Error; err != nil {
+		http.Error(w, "Error fetching handicap history", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]any{
+		"player":  scores[0].Player,
+		"index":   nil,
+		"scores":  scores,
+		"history": history,
+	}
+	if n := len(history); n > 0 {
+		resp["index"] = history[n-1].Index
+		resp["lowIndex"] = history[n-1].LowIndex
+		resp["cap"] = history[n-1].Cap
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// POST /api/handicaps/{player}/scores
+// Body: {"date":"2025-06-14","course":"...","tee":"Blue","adjustedGross":84,"courseRating":71.2,"slope":128,"pcc":0}
+func (s *Server) POSTHandicapScore(w http.ResponseWriter, r *http.Request) {
+	player, err := handicapPlayerParam(r)
+	if err != nil {
+		http.Error(w, "Invalid name", http.StatusBadRequest)
+		return
+	}
+
+	var score HandicapScore
+	if err := json.NewDecoder(r.Body).Decode(&score); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if _, err := time.Parse("2006-01-02", score.Date); err != nil {
+		http.Error(w, "Date must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if score.AdjustedGross <= 0 || score.CourseRating <= 0 || score.Slope < 55 || score.Slope > 155 {
+		http.Error(w, "Adjusted gross, course rating and slope must be set", http.StatusBadRequest)
+		return
+	}
+	if score.PCC < -1 || score.PCC > 3 {
+		http.Error(w, "PCC must be between -1 and 3", http.StatusBadRequest)
+		return
+	}
+	score.ID = 0
+	score.Player = player
+	score.EventID = ""
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&score).Error; err != nil {
+			return err
+		}
+		return recomputeHandicap(tx, player)
+	}); err != nil {
+		http.Error(w, "Could not post score", http.StatusInternalServerError)
+		return
+	}
+
+	s.db.First(&score, score.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(score)
+}
+
+// DELETE /api/handicaps/scores/{scoreID}
+func (s *Server) DELETEHandicapScore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "scoreID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid score ID", http.StatusBadRequest)
+		return
+	}
+	var score HandicapScore
+	if err := s.db.First(&score, id).Error; err != nil {
+		http.Error(w, "Score not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&score).Error; err != nil {
+			return err
+		}
+		return recomputeHandicap(tx, score.Player)
+	}); err != nil {
+		http.Error(w, "Could not delete score", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/handicaps/import/{eventID}
+func (s *Server) POSTImportHandicapScores(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	n, err := s.importEventHandicapScores(eventID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error importing scores: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"imported": n})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestServer_importEventHandicapScores(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	course := testCourse()
+	assert.NoError(t, validateCourse(&course))
+	assert.NoError(t, db.Create(&course).Error)
+
+	// Two earlier rounds plus the event give Connor an index of 18.0 - 2.0.
+	for _, date := range []string{"2025-05-01", "2025-05-08"} {
+		assert.NoError(t, db.Create(&HandicapScore{Player: "Connor Shaw", Date: date, AdjustedGross: 89, CourseRating: 71, Slope: 113}).Error)
+	}
+
+	event := &Event{Name: "Impact Fire Open", DateString: "2025-06-14", CourseID: course.ID, TeeID: course.Tees[0].ID}
+	assert.NoError(t, db.Create(event).Error)
+
+	holes := make([]HoleScore, 18)
+	for i, h := range course.Tees[0].Holes {
+		holes[i] = HoleScore{Hole: h.Hole, Par: h.Par, Handicap: h.Handicap, Gross: h.Par + 1}
+	}
+	holes[0].Gross = 12 // Par 4, no index yet so capped at par + 5
+	assert.NoError(t, db.Create(&Scorecard{EventID: event.EventID, Player: "Connor Shaw", Round: 1, Holes: holes}).Error)
+
+	n, err := s.importEventHandicapScores(event.EventID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	var imported HandicapScore
+	assert.NoError(t, db.Where("event_id = ?", event.EventID).First(&imported).Error)
+	assert.Equal(t, 72+18+4, imported.AdjustedGross)
+	assert.Equal(t, "2025-06-14", imported.Date)
+	assert.Equal(t, 20.5, imported.Differential)
+
+	var revisions []HandicapRevision
+	assert.NoError(t, db.Where("player = ?", "Connor Shaw").Find(&revisions).Error)
+	assert.Len(t, revisions, 1)
+	assert.Equal(t, 16.0, revisions[0].Index)
+	assert.Equal(t, imported.ID, revisions[0].ScoreID)
+
+	// Importing again replaces the event's scores.
+	_, err = s.importEventHandicapScores(event.EventID)
+	assert.NoError(t, err)
+	var count int64
+	assert.NoError(t, db.Model(&HandicapScore{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+
+	assert.NoError(t, deleteEventHandicapScores(db, event.EventID))
+	assert.NoError(t, db.Model(&HandicapRevision{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
@@ -11,11 +11,13 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -188,6 +190,11 @@ func (s *Server) GETStandings(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(years) == 0 {
+		if wantsCSV(r) {
+			header, rows := standingsCSV(nil, nil)
+			writeCSV(w, "standings.csv", header, rows)
+			return
+		}
 		json.NewEncoder(w).Encode("[]")
 		return
 	}
@@ -239,6 +246,12 @@ func (s *Server) GETStandings(w http.ResponseWriter, r *http.Request) {
 		return parseRank(wgr[i].Rank) < parseRank(wgr[j].Rank)
 	})
 
+	if wantsCSV(r) {
+		header, rows := standingsCSV(season, wgr)
+		writeCSV(w, targetYear+"-standings.csv", header, rows)
+		return
+	}
+
 	// Respond
 	resp := map[string]any{
 		"calendarYear":    targetYear,
@@ -478,6 +491,7 @@ func (s *Server) POSTStandingsUrls(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Error downloading new standings: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
+	s.publishStandings(standings.CalendarYear)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -506,6 +520,8 @@ func (s *Server) PUTStandingsUrls(w http.ResponseWriter, r *http.Request) {
 	// Update fields
 	dbStandings.SeasonStandingsUrl = standings.SeasonStandingsUrl
 	dbStandings.WgrStandingsUrl = standings.WgrStandingsUrl
+	dbStandings.BlueGolfClub = standings.BlueGolfClub
+	dbStandings.BlueGolfSeason = standings.BlueGolfSeason
 
 	if err := s.db.Save(dbStandings).Error; err != nil {
 		http.Error(w, "Could not update standings", http.StatusInternalServerError)
@@ -516,6 +532,7 @@ func (s *Server) PUTStandingsUrls(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Error downloading new standings: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
+	s.publishStandings(dbStandings.CalendarYear)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(dbStandings)
@@ -572,6 +589,7 @@ func (s *Server) POSTRefreshStandings(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Error downloading new standings: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
+	s.publishStandings(latest.CalendarYear)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -591,6 +609,21 @@ func (s *Server) POSTEvent(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Event name must be set", http.StatusBadRequest)
 		return
 	}
+	if err := s.linkEventCourse(event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if event.Status == "" {
+		event.Status = legacyEventStatus(event)
+	}
+	if !slices.Contains(eventInitialStatuses, event.Status) {
+		http.Error(w, "Events must start as draft, announced, registration-open or final", http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	event.RegistrationOpen = event.Status == eventRegistrationOpen
+	event.IsComplete = event.Status == eventFinal
+	event.StatusChangedAt = &now
 
 	// Create first to get eventID
 	result := s.db.Create(&event)
@@ -598,6 +631,7 @@ func (s *Server) POSTEvent(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Error saving new event: %s", result.Error.Error()), http.StatusBadRequest)
 		return
 	}
+	s.db.Create(&EventTransition{EventID: event.EventID, To: event.Status, At: now})
 
 	// Save thumbnail
 	filename, err := s.saveThumbnail(r, event.EventID)
@@ -610,6 +644,9 @@ func (s *Server) POSTEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.db.Save(&event)
+	if event.Status != eventDraft {
+		s.publishEventCreated(event)
+	}
 
 	if event.ResultsUpdated() {
 		err := updateResults(s.db, event.EventID,
@@ -622,6 +659,8 @@ func (s *Server) POSTEvent(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, fmt.Sprintf("Error downloading results: %s", err.Error()), http.StatusBadRequest)
 			return
 		}
+		s.publishResults(event.EventID)
+		go s.refreshScorecards(event.EventID)
 
 		var latest Standings
 		err = s.db.Order("calendar_year DESC").First(&latest).Error
@@ -633,6 +672,7 @@ func (s *Server) POSTEvent(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, fmt.Sprintf("Error downloading new standings: %s", err.Error()), http.StatusBadRequest)
 				return
 			}
+			s.publishStandings(latest.CalendarYear)
 		}
 	}
 
@@ -666,6 +706,15 @@ func (s *Server) PUTEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	updated.EventID = existing.EventID
+	// The status only changes through its own endpoint, except that opening
+	// or closing registration moves it along.
+	updated.Status, updated.StatusChangedAt = existing.Status, existing.StatusChangedAt
+	updated.IsComplete = existing.IsComplete
+	updated.RegistrationOpenedAt = existing.RegistrationOpenedAt
+	if err := s.linkEventCourse(updated); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	filename, err := s.saveThumbnail(r, updated.EventID)
 	if err != nil {
@@ -688,6 +737,19 @@ func (s *Server) PUTEvent(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Update failed: %s", err.Error()), http.StatusInternalServerError)
 		return
 	}
+	if updated.RegistrationOpen != existing.RegistrationOpen {
+		if err := setEventRegistrationOpen(s.db, updated, updated.RegistrationOpen, time.Now()); err != nil {
+			log.Printf("error updating status of %s: %s", updated.EventID, err)
+		}
+	}
+	if updated.RegistrationOpen && !existing.RegistrationOpen && updated.Status != eventDraft {
+		s.publishRegistrationOpened(updated.Name, updated.ShopifyUrl, map[string]any{"eventID": updated.EventID})
+	}
+	if updated.Capacity != existing.Capacity {
+		if err := s.settleRegistration(registrationTarget{EventID: updated.EventID}); err != nil {
+			log.Printf("error settling registrations for %s: %s", updated.EventID, err)
+		}
+	}
 
 	if triggerScrape {
 		fmt.Println("triggering scrape")
@@ -710,6 +772,8 @@ func (s *Server) PUTEvent(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, fmt.Sprintf("Error downloading results: %s", err.Error()), http.StatusBadRequest)
 			return
 		}
+		s.publishResults(updated.EventID)
+		go s.refreshScorecards(updated.EventID)
 
 		if shouldUpdate {
 			var latest Standings
@@ -722,6 +786,7 @@ func (s *Server) PUTEvent(w http.ResponseWriter, r *http.Request) {
 					http.Error(w, fmt.Sprintf("Error downloading new standings: %s", err.Error()), http.StatusBadRequest)
 					return
 				}
+				s.publishStandings(latest.CalendarYear)
 			}
 		}
 	}
@@ -799,6 +864,35 @@ func (s *Server) DELETEEvent(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	scorecardIDs := s.db.Model(&Scorecard{}).Select("id").Where("event_id = ?", eventID)
+	if err := s.db.Unscoped().Where("scorecard_id IN (?)", scorecardIDs).Delete(&HoleScore{}).Error; err != nil {
+		http.Error(w, "Failed to delete related scorecards", http.StatusInternalServerError)
+		return
+	}
+	if err := s.db.Unscoped().Where("event_id = ?", eventID).Delete(&Scorecard{}).Error; err != nil {
+		http.Error(w, "Failed to delete related scorecards", http.StatusInternalServerError)
+		return
+	}
+	if err := deleteEventHandicapScores(s.db, eventID); err != nil {
+		http.Error(w, "Failed to delete related handicap scores", http.StatusInternalServerError)
+		return
+	}
+	for _, model := range []any{&TeeTime{}, &TeeTimeSync{}, &TeeTimeChange{}} {
+		if err := s.db.Unscoped().Where("event_id = ?", eventID).Delete(model).Error; err != nil {
+			http.Error(w, "Failed to delete related tee times", http.StatusInternalServerError)
+			return
+		}
+	}
+	for _, model := range []any{&EventRegistration{}, &EventFieldSync{}} {
+		if err := s.db.Unscoped().Where("event_id = ?", eventID).Delete(model).Error; err != nil {
+			http.Error(w, "Failed to delete related registrations", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := s.db.Unscoped().Where("event_id = ?", eventID).Delete(&EventTransition{}).Error; err != nil {
+		http.Error(w, "Failed to delete status history", http.StatusInternalServerError)
+		return
+	}
 
 	// Delete thumbnail if it exists
 	if event.Thumbnail != "" {
@@ -826,7 +920,7 @@ func (s *Server) GETEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var event Event
-	if err := s.db.First(&event, "event_id = ?", eventID).Error; err != nil {
+	if err := s.visibleEvents(r).First(&event, "event_id = ?", eventID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			http.Error(w, "Event not found", http.StatusNotFound)
 		} else {
@@ -847,7 +941,7 @@ func (s *Server) GETEventThumbnail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var event Event
-	if err := s.db.First(&event, "event_id = ?", eventID).Error; err != nil {
+	if err := s.visibleEvents(r).First(&event, "event_id = ?", eventID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			http.Error(w, "Event not found", http.StatusNotFound)
 		} else {
@@ -880,7 +974,12 @@ func (s *Server) GETEventThumbnail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Set your desired cache policy:
-	w.Header().Set("Cache-Control", "public, max-age=86400, stale-while-revalidate=2592000")
+	if event.Status == eventDraft {
+		// Only admins can see drafts, so shared caches mustn't keep them.
+		w.Header().Set("Cache-Control", "private, no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=86400, stale-while-revalidate=2592000")
+	}
 
 	// Let http.ServeContent detect content-type, emit Last-Modified,
 	// honor If-Modified-Since → 304, and stream bytes.
@@ -891,7 +990,7 @@ func (s *Server) GETEvents(w http.ResponseWriter, r *http.Request) {
 	requestedYear := r.URL.Query().Get("year")
 
 	var events []Event
-	if err := s.db.Order("date DESC").Find(&events).Error; err != nil {
+	if err := s.visibleEvents(r).Order("date DESC").Find(&events).Error; err != nil {
 		http.Error(w, "Error fetching events", http.StatusInternalServerError)
 		return
 	}
@@ -958,6 +1057,10 @@ func (s *Server) GETNetResults(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing event ID", http.StatusBadRequest)
 		return
 	}
+	event, ok := s.loadVisibleEvent(w, r, eventID)
+	if !ok {
+		return
+	}
 
 	var results []NetResult
 	if err := s.db.Where("event_id = ?", eventID).Order("rank ASC").Find(&results).Error; err != nil {
@@ -968,7 +1071,13 @@ func (s *Server) GETNetResults(w http.ResponseWriter, r *http.Request) {
 		return parseRank(results[i].Rank) < parseRank(results[j].Rank)
 	})
 
-	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	cacheResults(w, event)
+	w.Header().Set("Vary", "Accept")
+	if wantsCSV(r) {
+		header, rows := netResultsCSV(results)
+		writeCSV(w, eventID+"-net.csv", header, rows)
+		return
+	}
 	json.NewEncoder(w).Encode(results)
 }
 
@@ -978,6 +1087,10 @@ func (s *Server) GETGrossResults(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing event ID", http.StatusBadRequest)
 		return
 	}
+	event, ok := s.loadVisibleEvent(w, r, eventID)
+	if !ok {
+		return
+	}
 
 	var results []GrossResult
 	if err := s.db.Where("event_id = ?", eventID).Order("rank ASC").Find(&results).Error; err != nil {
@@ -988,7 +1101,13 @@ func (s *Server) GETGrossResults(w http.ResponseWriter, r *http.Request) {
 		return parseRank(results[i].Rank) < parseRank(results[j].Rank)
 	})
 
-	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	cacheResults(w, event)
+	w.Header().Set("Vary", "Accept")
+	if wantsCSV(r) {
+		header, rows := grossResultsCSV(results)
+		writeCSV(w, eventID+"-gross.csv", header, rows)
+		return
+	}
 	json.NewEncoder(w).Encode(results)
 }
 
@@ -998,6 +1117,10 @@ func (s *Server) GETSkinsResults(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing event ID", http.StatusBadRequest)
 		return
 	}
+	event, ok := s.loadVisibleEvent(w, r, eventID)
+	if !ok {
+		return
+	}
 
 	var players []SkinsPlayerResult
 	var holes []SkinsHolesResult
@@ -1017,11 +1140,24 @@ func (s *Server) GETSkinsResults(w http.ResponseWriter, r *http.Request) {
 		return parseHole(holes[i].Hole) < parseHole(holes[j].Hole)
 	})
 
+	cacheResults(w, event)
+	w.Header().Set("Vary", "Accept")
+	if wantsCSV(r) {
+		// Players and holes are separate tables; ?table=holes selects the latter.
+		if r.URL.Query().Get("table") == "holes" {
+			header, rows := skinsHolesCSV(holes)
+			writeCSV(w, eventID+"-skins-holes.csv", header, rows)
+		} else {
+			header, rows := skinsPlayersCSV(players)
+			writeCSV(w, eventID+"-skins.csv", header, rows)
+		}
+		return
+	}
+
 	resp := map[string]any{
 		"players": players,
 		"holes":   holes,
 	}
-	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
 	json.NewEncoder(w).Encode(resp)
 }
 
@@ -1031,6 +1167,10 @@ func (s *Server) GETTeamResults(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing event ID", http.StatusBadRequest)
 		return
 	}
+	event, ok := s.loadVisibleEvent(w, r, eventID)
+	if !ok {
+		return
+	}
 
 	var results []TeamResult
 	if err := s.db.Where("event_id = ?", eventID).Order("rank ASC").Find(&results).Error; err != nil {
@@ -1041,7 +1181,13 @@ func (s *Server) GETTeamResults(w http.ResponseWriter, r *http.Request) {
 		return parseRank(results[i].Rank) < parseRank(results[j].Rank)
 	})
 
-	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	cacheResults(w, event)
+	w.Header().Set("Vary", "Accept")
+	if wantsCSV(r) {
+		header, rows := teamResultsCSV(results)
+		writeCSV(w, eventID+"-teams.csv", header, rows)
+		return
+	}
 	json.NewEncoder(w).Encode(results)
 }
 
@@ -1051,6 +1197,10 @@ func (s *Server) GETWgrResults(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing event ID", http.StatusBadRequest)
 		return
 	}
+	event, ok := s.loadVisibleEvent(w, r, eventID)
+	if !ok {
+		return
+	}
 
 	var results []WGRResult
 	if err := s.db.Where("event_id = ?", eventID).Order("rank ASC").Find(&results).Error; err != nil {
@@ -1061,15 +1211,24 @@ func (s *Server) GETWgrResults(w http.ResponseWriter, r *http.Request) {
 		return parseRank(results[i].Rank) < parseRank(results[j].Rank)
 	})
 
-	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	cacheResults(w, event)
+	w.Header().Set("Vary", "Accept")
+	if wantsCSV(r) {
+		header, rows := wgrResultsCSV(results)
+		writeCSV(w, eventID+"-wgr.csv", header, rows)
+		return
+	}
 	json.NewEncoder(w).Encode(results)
 }
 
 type colonyCupPair struct {
+	ID      uint   `json:"id,omitempty"`
 	TeamOne string `json:"team1"`
 	TeamTwo string `json:"team2"`
 	Winner  string `json:"winner"`
 	Score   string `json:"score"`
+	Holes   []int  `json:"holes,omitempty"`
+	Status  string `json:"status,omitempty"`
 }
 
 func (s *Server) GETColonyCupResults(w http.ResponseWriter, r *http.Request) {
@@ -1078,6 +1237,9 @@ func (s *Server) GETColonyCupResults(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing event ID", http.StatusBadRequest)
 		return
 	}
+	if s.colonyCupEventHidden(w, r, eventID) {
+		return
+	}
 
 	var rows []ColonyCupResult
 	if err := s.db.Where("event_id = ?", eventID).Order("match_index ASC").Find(&rows).Error; err != nil {
@@ -1091,13 +1253,17 @@ func (s *Server) GETColonyCupResults(w http.ResponseWriter, r *http.Request) {
 	var overall *colonyCupPair
 	grouped := make(map[string][]colonyCupPair)
 	matchIndices := make(map[int]string)
+	live := false
 
 	for _, r := range rows {
 		item := colonyCupPair{
+			ID:      r.ID,
 			TeamOne: r.TeamOne,
 			TeamTwo: r.TeamTwo,
 			Winner:  r.Winner,
 			Score:   r.Score,
+			Holes:   r.Holes,
+			Status:  r.Status,
 		}
 
 		if strings.EqualFold(r.EventName, "Colony Cup") {
@@ -1109,6 +1275,9 @@ func (s *Server) GETColonyCupResults(w http.ResponseWriter, r *http.Request) {
 
 		grouped[r.EventName] = append(grouped[r.EventName], item)
 		matchIndices[r.MatchIndex] = r.EventName
+		if r.Winner == "" {
+			live = true
+		}
 	}
 
 	// Assemble final JSON object
@@ -1121,12 +1290,24 @@ func (s *Server) GETColonyCupResults(w http.ResponseWriter, r *http.Request) {
 		out.Set("Colony Cup", colonyCupPair{})
 	}
 
-	for i := 1; i < len(grouped)+1; i++ {
+	// Sessions are numbered from 1 but may have gaps when a session has no
+	// results yet.
+	indices := make([]int, 0, len(matchIndices))
+	for i := range matchIndices {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	for _, i := range indices {
 		name := matchIndices[i]
 		out.Set(name, grouped[name])
 	}
 
-	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if live {
+		// Matches are still being scored.
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	_ = json.NewEncoder(w).Encode(out)
 }
@@ -1193,7 +1374,7 @@ func (s *Server) POSTColonyCupResults(w http.ResponseWriter, r *http.Request) {
 				return errors.New(`non-"Colony Cup" keys must be arrays of matches`)
 			}
 			for _, it := range arr {
-				newRows = append(newRows, ColonyCupResult{
+				row := ColonyCupResult{
 					EventID:    eventID,
 					EventName:  key, // keep provided label, e.g., "Best Ball"
 					MatchIndex: i,
@@ -1201,7 +1382,14 @@ func (s *Server) POSTColonyCupResults(w http.ResponseWriter, r *http.Request) {
 					TeamTwo:    it.TeamTwo,
 					Winner:     it.Winner,
 					Score:      it.Score,
-				})
+				}
+				if len(it.Holes) > 0 {
+					row.Holes = it.Holes
+					if err := scoreColonyCupMatch(&row); err != nil {
+						return fmt.Errorf("%s: %s", key, err)
+					}
+				}
+				newRows = append(newRows, row)
 			}
 		}
 		return nil
@@ -1217,7 +1405,25 @@ func (s *Server) POSTColonyCupResults(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateColonyCupResults(s.db, eventID, newRows); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var sessions []ColonyCupSession
+	if err := s.db.Where("event_id = ?", eventID).Find(&sessions).Error; err != nil {
+		http.Error(w, "Error fetching sessions", http.StatusInternalServerError)
+		return
+	}
+	for _, session := range sessions {
+		for i := range newRows {
+			if strings.EqualFold(newRows[i].EventName, session.Name) {
+				newRows[i].EventName, newRows[i].MatchIndex = session.Name, session.Order
+			}
+		}
+	}
+
 	// Write in a transaction: delete old, insert new
+	var sb ColonyCupScoreboard
 	if err := s.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Where("event_id = ?", eventID).Delete(&ColonyCupResult{}).Error; err != nil {
 			return err
@@ -1225,11 +1431,18 @@ func (s *Server) POSTColonyCupResults(w http.ResponseWriter, r *http.Request) {
 		if err := tx.Create(&newRows).Error; err != nil {
 			return err
 		}
-		return nil
+		// Team totals come from the matches once any have a result.
+		sb, _, err = loadColonyCupScoreboard(tx, eventID)
+		if err != nil || sb.Remaining == sb.Total {
+			return err
+		}
+		sb, err = updateColonyCupOverall(tx, eventID)
+		return err
 	}); err != nil {
 		http.Error(w, "Failed to save Colony Cup results", http.StatusInternalServerError)
 		return
 	}
+	s.publishColonyCup(sb)
 
 	// Respond
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -1241,8 +1454,9 @@ func (s *Server) POSTColonyCupResults(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) GETCurrentYear(w http.ResponseWriter, r *http.Request) {
+	// This is cached publicly, so drafts never count.
 	var latest Event
-	err := s.db.Order("date DESC").Limit(1).First(&latest).Error
+	err := s.publishedEvents().Order("date DESC").Limit(1).First(&latest).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			http.Error(w, "No events found", http.StatusNotFound)
@@ -1371,7 +1585,7 @@ func (s *Server) POSTColonyCupInfo(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) GETColonyCupInfo(w http.ResponseWriter, r *http.Request) {
 	var infos []ColonyCupInfo
-	if err := s.db.Order("year DESC").Limit(2).Find(&infos).Error; err != nil {
+	if err := s.visibleDrafts(r).Order("year DESC").Limit(2).Find(&infos).Error; err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
@@ -1387,7 +1601,7 @@ func (s *Server) GETColonyCupInfo(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) GETAllColonyCupInfo(w http.ResponseWriter, r *http.Request) {
 	var infos []ColonyCupInfo
-	if err := s.db.Order("year DESC").Find(&infos).Error; err != nil {
+	if err := s.visibleDrafts(r).Order("year DESC").Find(&infos).Error; err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
@@ -1404,19 +1618,21 @@ func (s *Server) PUTColonyCupInfo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var existing ColonyCupInfo
-	if err := s.db.First(&existing).Error; err != nil {
+	if err := s.db.First(&existing, "year = ?", updated.Year).Error; err != nil {
 		http.Error(w, "Record not found", http.StatusNotFound)
 		return
 	}
-	existing.Year = updated.Year
+	existing.Team = updated.Team
 	existing.WinningTeam = updated.WinningTeam
+	existing.Draft = updated.Draft
+	existing.PublishAt = updated.PublishAt
 
-	if err := s.db.Save(&updated).Error; err != nil {
+	if err := s.db.Save(&existing).Error; err != nil {
 		http.Error(w, "Error updating record", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(updated)
+	json.NewEncoder(w).Encode(existing)
 }
 
 func (s *Server) DELETEColonyCupInfo(w http.ResponseWriter, r *http.Request) {
@@ -1473,16 +1689,24 @@ func (s *Server) POSTMatchPlayInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.validateMatchPlayCourse(input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if err := s.db.Create(&input).Error; err != nil {
 		http.Error(w, "Could not save standings", http.StatusInternalServerError)
 		return
 	}
 
 	if input.BracketUrl != "" {
-		if err := updateMatchPlayResults(s.db, input.Year, input.BracketUrl); err != nil {
+		if err := s.refreshMatchPlayBracket(input.Year, input.BracketUrl); err != nil {
 			fmt.Println("*******", err)
 		}
 	}
+	if input.RegistrationOpen {
+		s.publishRegistrationOpened(input.Year+" Match Play", input.ShopifyUrl, map[string]any{"matchPlayYear": input.Year})
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -1509,21 +1733,42 @@ func (s *Server) PUTMatchPlayInfo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if input.BracketUrl != existing.BracketUrl && input.BracketUrl != "" {
-		if err := updateMatchPlayResults(s.db, input.Year, input.BracketUrl); err != nil {
+		if err := s.refreshMatchPlayBracket(input.Year, input.BracketUrl); err != nil {
 			fmt.Println("*******", err)
 		}
 	}
+	registrationOpened := input.RegistrationOpen && !existing.RegistrationOpen
+	capacityChanged := input.Capacity != existing.Capacity
 
 	// Update fields
 	existing.Year = input.Year
 	existing.RegistrationOpen = input.RegistrationOpen
 	existing.BracketUrl = input.BracketUrl
 	existing.ShopifyUrl = input.ShopifyUrl
+	existing.CourseID = input.CourseID
+	existing.TeeID = input.TeeID
+	existing.HandicapAllowance = input.HandicapAllowance
+	existing.Capacity = input.Capacity
+	existing.RegistrationOpensAt = input.RegistrationOpensAt
+	existing.RegistrationClosesAt = input.RegistrationClosesAt
+
+	if err := s.validateMatchPlayCourse(*existing); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	if err := s.db.Save(&existing).Error; err != nil {
 		http.Error(w, "Failed to update record", http.StatusInternalServerError)
 		return
 	}
+	if registrationOpened {
+		s.publishRegistrationOpened(existing.Year+" Match Play", existing.ShopifyUrl, map[string]any{"matchPlayYear": existing.Year})
+	}
+	if capacityChanged {
+		if err := s.settleRegistration(registrationTarget{MatchPlayYear: existing.Year}); err != nil {
+			log.Printf("error settling registrations for %s match play: %s", existing.Year, err)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(existing)
@@ -1572,7 +1817,7 @@ func (s *Server) POSTRefreshMatchPlayBracket(w http.ResponseWriter, r *http.Requ
 	}
 
 	if existing.BracketUrl != "" {
-		if err := updateMatchPlayResults(s.db, existing.Year, existing.BracketUrl); err != nil {
+		if err := s.refreshMatchPlayBracket(existing.Year, existing.BracketUrl); err != nil {
 			http.Error(w, fmt.Sprintf("Error downloading new bracket: %s", err.Error()), http.StatusBadRequest)
 			return
 		}
@@ -1590,6 +1835,11 @@ func (s *Server) GETMatchPlayResults(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if len(years) == 0 {
+		if wantsCSV(r) {
+			header, rows := matchPlayCSV(nil)
+			writeCSV(w, "match-play.csv", header, rows)
+			return
+		}
 		json.NewEncoder(w).Encode(map[string]any{
 			"calendarYear":    nil,
 			"additionalYears": []string{},
@@ -1634,6 +1884,14 @@ func (s *Server) GETMatchPlayResults(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if wantsCSV(r) {
+		// Rows are stored round by round in match order.
+		sort.Slice(matches, func(i, j int) bool { return matches[i].ID < matches[j].ID })
+		header, rows := matchPlayCSV(matches)
+		writeCSV(w, targetYear+"-match-play.csv", header, rows)
+		return
+	}
+
 	resp := map[string]any{
 		"calendarYear":    targetYear,
 		"additionalYears": additionalYears,
@@ -1666,12 +1924,26 @@ func (s *Server) POSTMatchPlayPlayer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	player.Player = basicSanitize(player.Player)
+	player.AccessCode = randomHex(6)
+
+	// Access codes are looked up by name regardless of case, so names must
+	// be unique the same way, deleted players included.
+	var taken int64
+	if err := s.db.Unscoped().Model(&MatchPlayPlayer{}).Where("LOWER(player) = LOWER(?)", player.Player).Count(&taken).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if taken > 0 {
+		http.Error(w, "Player already registered", http.StatusConflict)
+		return
+	}
 
 	if err := s.db.Create(&player).Error; err != nil {
 		http.Error(w, "Database insert error", http.StatusInternalServerError)
 		return
 	}
 
+	// The access code is only given out by admins.
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(player)
@@ -1722,7 +1994,7 @@ func (s *Server) DELETEMatchPlayPlayer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Hard‐delete all rows matching the given player name
-	if err := s.db.
+	if err := s.db.Unscoped().
 		Where("player = ?", req.Player).
 		Delete(&MatchPlayPlayer{}).
 		Error; err != nil {
@@ -1805,77 +2077,6 @@ func (s *Server) GETDataDirectory(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func parseBlueGolf(raw string) (club, contest string, err error) {
-	u, err := url.Parse(raw)
-	if err != nil {
-		return "", "", err
-	}
-
-	// Split into path segments
-	segs := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
-
-	// Find the segment after "bluegolfw" and after "poy"
-	var iBlue, iPoy = -1, -1
-	for i, s := range segs {
-		if s == "bluegolfw" {
-			iBlue = i
-		} else if s == "poy" {
-			iPoy = i
-		}
-	}
-
-	if iBlue < 0 || iBlue+1 >= len(segs) || iPoy < 0 || iPoy+1 >= len(segs) {
-		return "", "", fmt.Errorf("unexpected path format: %q", u.Path)
-	}
-
-	return segs[iBlue+1], segs[iPoy+1], nil
-}
-
-func (s *Server) GetTeeTimes(w http.ResponseWriter, r *http.Request) {
-	eventID := chi.URLParam(r, "eventID")
-	if eventID == "" {
-		http.Error(w, "Missing event ID", http.StatusBadRequest)
-		return
-	}
-
-	var event Event
-	if err := s.db.First(&event, "event_id = ?", eventID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, "Event not found", http.StatusNotFound)
-		} else {
-			http.Error(w, "Database error", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	u, err := url.Parse(event.BlueGolfUrl)
-	if err != nil {
-		panic(err)
-	}
-
-	// Split the path into segments
-	parts := strings.Split(u.Path, "/")
-
-	// Find the segment after "events"
-	var seasonID string
-	for i, p := range parts {
-		if p == "events" && i+1 < len(parts) {
-			seasonID = parts[i+1]
-			break
-		}
-	}
-
-	teeTimeUrl := fmt.Sprintf("https://nhgaclub.bluegolf.com/bluegolfw/nhgaclublivefreegc25/event/%s/pairings.htm", seasonID)
-	teeTimes, err := ScrapeTeeTimes(teeTimeUrl)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(teeTimes)
-}
-
 func (s *Server) PostUpdates(w http.ResponseWriter, r *http.Request) {
 	type DataUpdate struct {
 		EventId      string        `json:"event_id"`
@@ -2003,12 +2204,20 @@ func (s *Server) PostUpdates(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	if len(input.NetResults) > 0 || len(input.GrossResults) > 0 || len(input.SkinsResults.Holes) > 0 ||
+		len(input.TeamsResults) > 0 || len(input.WgrResults) > 0 {
+		s.publishResults(input.EventId)
+	}
+	if len(input.SeasonStandings) > 0 || len(input.WgrStandings) > 0 {
+		s.publishStandings(input.Year)
+	}
 }
 
 // GET /api/champions
 func (s *Server) GETChampions(w http.ResponseWriter, r *http.Request) {
 	var champs []PastChampion
-	if err := s.db.Order("CAST(year AS INTEGER) DESC").Find(&champs).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	if err := s.visibleDrafts(r).Order("CAST(year AS INTEGER) DESC").Find(&champs).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
@@ -2062,6 +2271,9 @@ func (s *Server) POSTChampion(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
+	if !payload.Draft {
+		s.publishChampion(&payload)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(payload)
@@ -2119,8 +2331,11 @@ func (s *Server) PUTChampion(w http.ResponseWriter, r *http.Request) {
 		existing.Thumbnail = filename
 	}
 
+	published := existing.Draft && !in.Draft
 	existing.Year = in.Year
 	existing.Player = in.Player
+	existing.Draft = in.Draft
+	existing.PublishAt = in.PublishAt
 
 	if err := s.db.Save(&existing).Error; err != nil {
 		if isUniqueConstraintError(err) {
@@ -2130,6 +2345,9 @@ func (s *Server) PUTChampion(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
+	if published {
+		s.publishChampion(&existing)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(existing)
@@ -2175,7 +2393,7 @@ func (s *Server) GETChampionImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var champ PastChampion
-	if err := s.db.Select("thumbnail").Where("year = ?", year).First(&champ).Error; err != nil {
+	if err := s.visibleDrafts(r).Select("thumbnail").Where("year = ?", year).First(&champ).Error; err != nil {
 		http.Error(w, "Image not found", http.StatusNotFound)
 		return
 	}
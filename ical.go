@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	icalProdID   = "-//Live Free Golf//lfg-server//EN"
+	icalCalName  = "Live Free Golf"
+	icalUIDHost  = "livefreegolf.com"
+	icalLineSize = 75
+)
+
+// icalEscape escapes TEXT values per RFC 5545 section 3.3.11.
+func icalEscape(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return r.Replace(s)
+}
+
+// icalFold splits a content line into 75 octet chunks joined by CRLF and a
+// single space as required by RFC 5545 section 3.1. It never splits a
+// multi-byte UTF-8 sequence.
+func icalFold(line string) string {
+	if len(line) <= icalLineSize {
+		return line + "\r\n"
+	}
+	var b strings.Builder
+	limit := icalLineSize
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && (line[cut]&0xC0) == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines lose one octet to the leading space.
+		limit = icalLineSize - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// eventLocation joins the course, town and state into a single location line.
+func eventLocation(e *Event) string {
+	var parts []string
+	for _, p := range []string{e.Course, e.Town, e.State} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// teeTimeLines lists an event's tee times for a calendar description. The
+// round is only given for events played over more than one round.
+func teeTimeLines(teeTimes []TeeTime) []string {
+	rounds := false
+	for _, tt := range teeTimes {
+		if tt.Round > 1 {
+			rounds = true
+		}
+	}
+	lines := make([]string, 0, len(teeTimes))
+	for _, tt := range teeTimes {
+		line := tt.Time
+		if rounds {
+			line = fmt.Sprintf("Round %d %s", tt.Round, line)
+		}
+		if tt.Hole != "" {
+			line += fmt.Sprintf(" (hole %s)", tt.Hole)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", line, strings.Join(tt.Players, ", ")))
+	}
+	return lines
+}
+
+// buildICalendar renders the events as an RFC 5545 VCALENDAR. Events are
+// all-day entries keyed by EventID so calendar clients update rather than
+// duplicate them when details change. Stored tee times, keyed by EventID,
+// are listed in the description.
+func buildICalendar(events []Event, teeTimes map[string][]TeeTime, now time.Time) string {
+	var b strings.Builder
+	write := func(name, value string) {
+		b.WriteString(icalFold(name + ":" + value))
+	}
+
+	write("BEGIN", "VCALENDAR")
+	write("VERSION", "2.0")
+	write("PRODID", icalProdID)
+	write("CALSCALE", "GREGORIAN")
+	write("METHOD", "PUBLISH")
+	write("X-WR-CALNAME", icalCalName)
+
+	for i := range events {
+		e := &events[i]
+		start := time.Time(e.Date)
+		if start.IsZero() {
+			continue
+		}
+
+		stamp := e.UpdatedAt
+		if stamp.IsZero() {
+			stamp = now
+		}
+
+		var desc []string
+		if e.HandicapAllowance != "" {
+			desc = append(desc, fmt.Sprintf("Handicap allowance: %s", e.HandicapAllowance))
+		}
+		if e.RegistrationOpen && e.ShopifyUrl != "" {
+			desc = append(desc, fmt.Sprintf("Register: %s", e.ShopifyUrl))
+		}
+		if tts := teeTimes[e.EventID]; len(tts) > 0 {
+			desc = append(desc, "Tee times:")
+			desc = append(desc, teeTimeLines(tts)...)
+		}
+		if e.BlueGolfUrl != "" {
+			desc = append(desc, fmt.Sprintf("Tee times and pairings: %s", e.BlueGolfUrl))
+		}
+
+		write("BEGIN", "VEVENT")
+		write("UID", fmt.Sprintf("%s@%s", e.EventID, icalUIDHost))
+		write("DTSTAMP", stamp.UTC().Format("20060102T150405Z"))
+		write("LAST-MODIFIED", stamp.UTC().Format("20060102T150405Z"))
+		write("DTSTART;VALUE=DATE", start.Format("20060102"))
+		write("DTEND;VALUE=DATE", start.AddDate(0, 0, 1).Format("20060102"))
+		write("SUMMARY", icalEscape(e.Name))
+		if loc := eventLocation(e); loc != "" {
+			write("LOCATION", icalEscape(loc))
+		}
+		if len(desc) > 0 {
+			write("DESCRIPTION", icalEscape(strings.Join(desc, "\n")))
+		}
+		if e.ShopifyUrl != "" {
+			write("URL", e.ShopifyUrl)
+		}
+		write("TRANSP", "TRANSPARENT")
+		write("END", "VEVENT")
+	}
+
+	write("END", "VCALENDAR")
+	return b.String()
+}
+
+// GET /api/events.ics?year=2025
+func (s *Server) GETEventsICal(w http.ResponseWriter, r *http.Request) {
+	// Calendars are cached and shared, so drafts are never included.
+	query := s.publishedEvents().Order("date ASC")
+	if year := r.URL.Query().Get("year"); year != "" {
+		if !validateYear(year) {
+			http.Error(w, "Malformed year", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("substr(date_string, 1, 4) = ?", year)
+	}
+
+	var events []Event
+	if err := query.Find(&events).Error; err != nil {
+		http.Error(w, "Error fetching events", http.StatusInternalServerError)
+		return
+	}
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.EventID)
+	}
+	var rows []TeeTime
+	if len(ids) > 0 {
+		if err := s.db.Where("event_id IN ?", ids).Order("position ASC").Find(&rows).Error; err != nil {
+			http.Error(w, "Error fetching tee times", http.StatusInternalServerError)
+			return
+		}
+	}
+	teeTimes := make(map[string][]TeeTime)
+	for _, tt := range rows {
+		teeTimes[tt.EventID] = append(teeTimes[tt.EventID], tt)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="lfg-events.ics"`)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	_, _ = w.Write([]byte(buildICalendar(events, teeTimes, time.Now())))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_icalFold(t *testing.T) {
+	short := "SUMMARY:Impact Fire Open"
+	assert.Equal(t, short+"\r\n", icalFold(short))
+
+	long := "DESCRIPTION:" + strings.Repeat("é", 60)
+	folded := icalFold(long)
+	for _, line := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), icalLineSize)
+	}
+	unfolded := strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", "")
+	assert.Equal(t, long, unfolded)
+}
+
+func Test_buildICalendar(t *testing.T) {
+	date, _ := time.Parse("2006-01-02", "2025-06-14")
+	events := []Event{{
+		EventID:          "2025-impact-fire-open",
+		Date:             datatypes.Date(date),
+		Name:             "Impact Fire Open",
+		Course:           "Candia Woods",
+		Town:             "Candia",
+		State:            "NH",
+		ShopifyUrl:       "https://shop.example.com/impact",
+		RegistrationOpen: true,
+	}}
+
+	teeTimes := map[string][]TeeTime{"2025-impact-fire-open": {
+		{Round: 1, Time: "8:00 AM", Hole: "1", Players: []string{"Connor Shaw", "Andy Lee"}},
+		{Round: 1, Time: "8:10 AM", Hole: "10", Players: []string{"Mike Ross"}},
+	}}
+
+	cal := buildICalendar(events, teeTimes, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Contains(t, cal, "UID:2025-impact-fire-open@livefreegolf.com\r\n")
+	assert.Contains(t, cal, "DTSTART;VALUE=DATE:20250614\r\n")
+	assert.Contains(t, cal, "DTEND;VALUE=DATE:20250615\r\n")
+	assert.Contains(t, cal, "LOCATION:Candia Woods\\, Candia\\, NH\r\n")
+	assert.Contains(t, cal, "Register: https://shop.example.com/impact")
+	unfolded := strings.ReplaceAll(cal, "\r\n ", "")
+	assert.Contains(t, unfolded, `Tee times:\n8:00 AM (hole 1): Connor Shaw\, Andy Lee\n8:10 AM (hole 10): Mike Ross`)
+	assert.True(t, strings.HasPrefix(cal, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(cal, "END:VCALENDAR\r\n"))
+}
+
+func Test_teeTimeLines(t *testing.T) {
+	lines := teeTimeLines([]TeeTime{
+		{Round: 1, Time: "8:00 AM", Hole: "1", Players: []string{"Connor Shaw"}},
+		{Round: 2, Time: "9:30 AM", Players: []string{"Connor Shaw", "Andy Lee"}},
+	})
+	assert.Equal(t, []string{"Round 1 8:00 AM (hole 1): Connor Shaw", "Round 2 9:30 AM: Connor Shaw, Andy Lee"}, lines)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/gocolly/colly"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	leaderboardNet   = "net"
+	leaderboardGross = "gross"
+	leaderboardSkins = "skins"
+	leaderboardTeam  = "team"
+	leaderboardWGR   = "wgr"
+	// Leaderboards that don't feed any of the event's results, such as the
+	// season long match play contest.
+	leaderboardOther = "other"
+)
+
+var contestLeaderboardPath = regexp.MustCompile(`^(.*/event/[^/]+/)contest/(\d+)/leaderboard\.htm$`)
+
+// DiscoveredLeaderboard is a contest leaderboard found on an event's
+// BlueGolf pages.
+type DiscoveredLeaderboard struct {
+	Contest int    `json:"contest"`
+	Title   string `json:"title"`
+	Url     string `json:"url"`
+	Kind    string `json:"kind"`
+	// Guessed is set when the kind was inferred rather than named in the
+	// contest's title.
+	Guessed bool `json:"guessed"`
+}
+
+// classifyLeaderboard works out which of an event's results a leaderboard
+// holds from its title, falling back to the leaderboard's structure: skins
+// boards have a Skins column and team boards list players joined by "/".
+// Individual stroke play boards with no other hint are taken to be net,
+// which BlueGolf usually titles after the course.
+func classifyLeaderboard(title string, headers string, players []string) (kind string, guessed bool) {
+	t := strings.ToLower(title)
+	// BlueGolf titles pages "{event} - {contest} Leaderboard | {club}".
+	if i := strings.LastIndex(t, " | "); i >= 0 {
+		t = t[:i]
+	}
+	if i := strings.Index(t, " - "); i >= 0 {
+		t = t[i+3:]
+	}
+
+	teamRows := 0
+	for _, p := range players {
+		if strings.Contains(p, "/") {
+			teamRows++
+		}
+	}
+
+	switch {
+	case strings.Contains(t, "skins"):
+		return leaderboardSkins, false
+	case strings.Contains(t, "world rank") || strings.Contains(t, "wgr"):
+		return leaderboardWGR, false
+	case strings.Contains(t, "match play"):
+		return leaderboardOther, false
+	case strings.Contains(t, "team"):
+		return leaderboardTeam, false
+	case strings.Contains(t, "gross"):
+		return leaderboardGross, false
+	case strings.Contains(t, "net"):
+		return leaderboardNet, false
+	case strings.Contains(strings.ToLower(headers), "skins"):
+		return leaderboardSkins, true
+	case len(players) > 0 && teamRows*2 > len(players):
+		return leaderboardTeam, true
+	case len(players) > 0:
+		return leaderboardNet, true
+	}
+	return leaderboardOther, true
+}
+
+// discoverLeaderboards crawls an event's BlueGolf page and every contest
+// leaderboard it links to, staying within the event.
+func discoverLeaderboards(eventURL string) ([]DiscoveredLeaderboard, error) {
+	start, err := url.Parse(eventURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BlueGolf URL: %w", err)
+	}
+
+	c := colly.NewCollector(
+		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) " +
+			"AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/115.0.0.0 Safari/537.36"),
+	)
+	c.Async = true
+
+	var (
+		mu     sync.Mutex
+		prefix string
+		found  = map[int]*DiscoveredLeaderboard{}
+	)
+
+	c.OnRequest(func(r *colly.Request) {
+		r.Headers.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		r.Headers.Set("Accept-Language", "en-US,en;q=0.9")
+		r.Headers.Set("Cache-Control", "no-cache")
+		fmt.Println("Visiting", r.URL.String())
+	})
+
+	// Follow links to the event's contest leaderboards. The event is the
+	// first one linked to unless the start page is inside an event already.
+	if m := contestLeaderboardPath.FindStringSubmatch(start.Path); m != nil {
+		prefix = m[1]
+	} else if i := strings.Index(start.Path, "/event/"); i >= 0 {
+		rest := start.Path[i+len("/event/"):]
+		if j := strings.Index(rest, "/"); j >= 0 {
+			prefix = start.Path[:i+len("/event/")+j+1]
+		}
+	}
+	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		u, err := url.Parse(e.Request.AbsoluteURL(e.Attr("href")))
+		if err != nil || u.Host != start.Host {
+			return
+		}
+		m := contestLeaderboardPath.FindStringSubmatch(u.Path)
+		if m == nil {
+			return
+		}
+		contest, _ := strconv.Atoi(m[2])
+
+		mu.Lock()
+		if prefix == "" {
+			prefix = m[1]
+		}
+		var next string
+		if m[1] == prefix && found[contest] == nil {
+			next = u.Scheme + "://" + u.Host + u.Path
+			found[contest] = &DiscoveredLeaderboard{Contest: contest, Url: next}
+		}
+		mu.Unlock()
+
+		if next != "" {
+			_ = c.Visit(next)
+		}
+	})
+
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		m := contestLeaderboardPath.FindStringSubmatch(e.Request.URL.Path)
+		if m == nil {
+			return
+		}
+		contest, _ := strconv.Atoi(m[2])
+		title := strings.TrimSpace(e.ChildText("title"))
+		headers := e.ChildText("table.table-sortable thead")
+		var players []string
+		e.ForEach("tbody#lbBody > tr", func(_ int, row *colly.HTMLElement) {
+			if p := strings.TrimSpace(row.ChildText("td a span.d-none.d-md-inline")); p != "" {
+				players = append(players, p)
+			}
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+		lb := found[contest]
+		if lb == nil {
+			lb = &DiscoveredLeaderboard{Contest: contest, Url: e.Request.URL.Scheme + "://" + e.Request.URL.Host + e.Request.URL.Path}
+			found[contest] = lb
+		}
+		lb.Title = title
+		if i := strings.LastIndex(lb.Title, " | "); i >= 0 {
+			lb.Title = lb.Title[:i]
+		}
+		lb.Kind, lb.Guessed = classifyLeaderboard(title, headers, players)
+	})
+
+	if err := c.Visit(eventURL); err != nil {
+		return nil, err
+	}
+	c.Wait()
+
+	leaderboards := make([]DiscoveredLeaderboard, 0, len(found))
+	for _, lb := range found {
+		if lb.Kind == "" {
+			// The page couldn't be loaded.
+			continue
+		}
+		leaderboards = append(leaderboards, *lb)
+	}
+	if len(leaderboards) == 0 {
+		return nil, fmt.Errorf("no leaderboards found from URL: %s", eventURL)
+	}
+	sort.Slice(leaderboards, func(i, j int) bool {
+		return leaderboards[i].Contest < leaderboards[j].Contest
+	})
+	return leaderboards, nil
+}
+
+// proposeLeaderboardUrls picks one leaderboard of each kind, preferring
+// those named in their title and then the lowest contest number. The keys
+// match the event's JSON fields.
+func proposeLeaderboardUrls(leaderboards []DiscoveredLeaderboard) map[string]string {
+	fields := map[string]string{
+		leaderboardNet:   "netLeaderboardUrl",
+		leaderboardGross: "grossLeaderboardUrl",
+		leaderboardSkins: "skinsLeaderboardUrl",
+		leaderboardTeam:  "teamsLeaderboardUrl",
+		leaderboardWGR:   "wgrLeaderboardUrl",
+	}
+	proposed := make(map[string]string)
+	for _, guessed := range []bool{false, true} {
+		for _, lb := range leaderboards {
+			field, ok := fields[lb.Kind]
+			if !ok || lb.Guessed != guessed || proposed[field] != "" {
+				continue
+			}
+			proposed[field] = lb.Url
+		}
+	}
+	return proposed
+}
+
+// POST /api/events/{eventID}/discover-leaderboards
+// Nothing is saved. The proposed URLs are confirmed by updating the event.
+func (s *Server) POSTDiscoverLeaderboards(w http.ResponseWriter, r *http.Request) {
+	var event Event
+	if err := s.db.First(&event, "event_id = ?", chi.URLParam(r, "eventID")).Error; err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	if event.BlueGolfUrl == "" {
+		http.Error(w, "Event has no BlueGolf URL", http.StatusBadRequest)
+		return
+	}
+
+	// Club level event links redirect into the season, so start from the
+	// season's event page when it can be resolved.
+	eventURL := event.BlueGolfUrl
+	if blueGolfID, err := blueGolfEventID(&event); err == nil {
+		if site, err := eventBlueGolfSite(s.db, &event); err == nil {
+			eventURL = site.EventURL(blueGolfID, "index.htm")
+		}
+	}
+
+	leaderboards, err := discoverLeaderboards(eventURL)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error discovering leaderboards: %s", err.Error()), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"eventID":      event.EventID,
+		"leaderboards": leaderboards,
+		"proposed":     proposeLeaderboardUrls(leaderboards),
+	})
+}
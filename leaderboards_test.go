@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_discoverLeaderboards(t *testing.T) {
+	files := map[int]string{
+		1:  "net-results.html",
+		4:  "gross-results.html",
+		7:  "wgr-results.html",
+		8:  "skins-results.html",
+		14: "team-results.html",
+		18: "team-results2.html",
+	}
+	const event = "/bluegolfw/nhgaclublivefreegc25/event/nhgaclublivefreegc251/"
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == event+"index.htm" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<html><body>`)
+			for contest := range files {
+				fmt.Fprintf(w, `<a href="contest/%d/leaderboard.htm">Contest</a>`, contest)
+			}
+			// Other events are not followed.
+			fmt.Fprint(w, `<a href="/bluegolfw/nhgaclublivefreegc25/event/nhgaclublivefreegc252/contest/1/leaderboard.htm">Next</a>`)
+			fmt.Fprint(w, `</body></html>`)
+			return
+		}
+		for contest, filename := range files {
+			if r.URL.Path == fmt.Sprintf("%scontest/%d/leaderboard.htm", event, contest) {
+				htmlContent, err := os.ReadFile(filepath.Join("testdata", filename))
+				if err != nil {
+					http.Error(w, "could not read test file", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				_, _ = w.Write(htmlContent)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	leaderboards, err := discoverLeaderboards(server.URL + event + "index.htm")
+	assert.NoError(t, err)
+	assert.Len(t, leaderboards, 6)
+
+	kinds := map[int]string{}
+	for _, lb := range leaderboards {
+		kinds[lb.Contest] = lb.Kind
+	}
+	assert.Equal(t, map[int]string{
+		1:  leaderboardNet,
+		4:  leaderboardGross,
+		7:  leaderboardWGR,
+		8:  leaderboardSkins,
+		14: leaderboardTeam,
+		18: leaderboardTeam,
+	}, kinds)
+	assert.True(t, leaderboards[0].Guessed)
+	assert.Equal(t, "The Impact Fire Opener - Oaks Leaderboard Leaderboard", leaderboards[0].Title)
+
+	proposed := proposeLeaderboardUrls(leaderboards)
+	assert.Equal(t, server.URL+event+"contest/1/leaderboard.htm", proposed["netLeaderboardUrl"])
+	assert.Equal(t, server.URL+event+"contest/14/leaderboard.htm", proposed["teamsLeaderboardUrl"])
+	assert.Equal(t, server.URL+event+"contest/8/leaderboard.htm", proposed["skinsLeaderboardUrl"])
+	assert.Len(t, proposed, 5)
+}
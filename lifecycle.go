@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	eventDraft              = "draft"
+	eventAnnounced          = "announced"
+	eventRegistrationOpen   = "registration-open"
+	eventRegistrationClosed = "registration-closed"
+	eventInProgress         = "in-progress"
+	eventResultsProvisional = "results-provisional"
+	eventFinal              = "final"
+	eventCancelled          = "cancelled"
+	eventPostponed          = "postponed"
+
+	eventStatusPollInterval = time.Hour
+)
+
+// eventTransitions lists the statuses each status can move to. Final
+// results can be reopened if a correction is needed, postponed events are
+// rescheduled by announcing them again and cancelled events stay cancelled.
+var eventTransitions = map[string][]string{
+	eventDraft:              {eventAnnounced, eventRegistrationOpen, eventCancelled},
+	eventAnnounced:          {eventDraft, eventRegistrationOpen, eventInProgress, eventPostponed, eventCancelled},
+	eventRegistrationOpen:   {eventRegistrationClosed, eventInProgress, eventPostponed, eventCancelled},
+	eventRegistrationClosed: {eventRegistrationOpen, eventInProgress, eventPostponed, eventCancelled},
+	eventInProgress:         {eventResultsProvisional, eventFinal, eventPostponed, eventCancelled},
+	eventResultsProvisional: {eventInProgress, eventFinal},
+	eventFinal:              {eventResultsProvisional},
+	eventPostponed:          {eventAnnounced, eventRegistrationOpen, eventCancelled},
+	eventCancelled:          {},
+}
+
+// Statuses an event can be created in. Past events are entered as final.
+var eventInitialStatuses = []string{eventDraft, eventAnnounced, eventRegistrationOpen, eventFinal}
+
+var errEventTransition = errors.New("transition not allowed")
+
+// finalizeEventHook is swapped out in tests.
+var finalizeEventHook = (*Server).finalizeEvent
+
+// legacyEventStatus is the status of an event saved before statuses
+// existed, worked out from its flags.
+func legacyEventStatus(e *Event) string {
+	switch {
+	case e.IsComplete:
+		return eventFinal
+	case e.RegistrationOpen:
+		return eventRegistrationOpen
+	}
+	return eventAnnounced
+}
+
+// backfillEventStatuses stores a status on events saved before statuses
+// existed. IsComplete was never set on them, so anything already played or
+// with results posted is final.
+func backfillEventStatuses(db *gorm.DB, now time.Time) error {
+	var events []Event
+	if err := db.Where("status IS NULL OR status = ''").Find(&events).Error; err != nil {
+		return err
+	}
+	for i := range events {
+		e := &events[i]
+		status := legacyEventStatus(e)
+		if e.DateString < now.Format("2006-01-02") || e.ResultsUpdated() {
+			status = eventFinal
+		}
+		if err := db.Model(e).UpdateColumns(map[string]any{
+			"status":      status,
+			"is_complete": status == eventFinal,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validEventStatus(status string) bool {
+	_, ok := eventTransitions[status]
+	return ok
+}
+
+func eventCanTransition(from, to string) bool {
+	return slices.Contains(eventTransitions[from], to)
+}
+
+// eventBeforePlay reports whether an event hasn't started yet.
+func eventBeforePlay(status string) bool {
+	return status == eventAnnounced || status == eventRegistrationOpen || status == eventRegistrationClosed
+}
+
+// transitionEvent moves an event to a new status and records when. The
+// RegistrationOpen and IsComplete flags follow the status.
+func transitionEvent(db *gorm.DB, e *Event, to, note string, now time.Time) error {
+	from := e.Status
+	if !eventCanTransition(from, to) {
+		return fmt.Errorf("%w: %s to %s", errEventTransition, from, to)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(e).Updates(map[string]any{
+			"status":            to,
+			"status_changed_at": now,
+			"registration_open": to == eventRegistrationOpen,
+			"is_complete":       to == eventFinal,
+		}).Error; err != nil {
+			return err
+		}
+		e.Status, e.StatusChangedAt = to, &now
+		e.RegistrationOpen, e.IsComplete = to == eventRegistrationOpen, to == eventFinal
+		return tx.Create(&EventTransition{EventID: e.EventID, From: from, To: to, At: now, Note: note}).Error
+	})
+}
+
+// setEventRegistrationOpen opens or closes an event's registration, moving
+// its status along with it when the transition is allowed. Otherwise only
+// the flag changes, and drafts stay drafts until they are published.
+func setEventRegistrationOpen(db *gorm.DB, e *Event, open bool, now time.Time) error {
+	to := eventRegistrationClosed
+	if open {
+		to = eventRegistrationOpen
+	}
+	if e.Status != eventDraft && e.Status != to && eventCanTransition(e.Status, to) {
+		return transitionEvent(db, e, to, "", now)
+	}
+	e.RegistrationOpen = open
+	return db.Model(e).Update("registration_open", open).Error
+}
+
+// setEventStatus moves an event to a new status. Entering final first
+// downloads the results and recomputes the standings, and the event stays
+// where it was if that fails.
+func (s *Server) setEventStatus(e *Event, to, note string) error {
+	if !eventCanTransition(e.Status, to) {
+		return fmt.Errorf("%w: %s to %s", errEventTransition, e.Status, to)
+	}
+	if to == eventFinal {
+		if err := finalizeEventHook(s, e); err != nil {
+			return err
+		}
+		// Posting the results may have moved the event along.
+		var reloaded Event
+		if err := s.db.First(&reloaded, "event_id = ?", e.EventID).Error; err != nil {
+			return err
+		}
+		*e = reloaded
+	}
+
+	from := e.Status
+	if err := transitionEvent(s.db, e, to, note, time.Now()); err != nil {
+		return err
+	}
+	switch {
+	case from == eventDraft:
+		// Publishing a draft announces it like a new event.
+		s.publishEventCreated(e)
+	case to == eventRegistrationOpen:
+		s.publishRegistrationOpened(e.Name, e.ShopifyUrl, map[string]any{"eventID": e.EventID})
+	case from == eventRegistrationOpen && to == eventRegistrationClosed:
+		count, err := registrationCount(s.db, registrationTarget{EventID: e.EventID})
+		if err != nil {
+			log.Printf("error counting registrations for %s: %s", e.EventID, err)
+		}
+		s.publishRegistrationClosed(e.Name, count, map[string]any{"eventID": e.EventID})
+	}
+	return nil
+}
+
+// finalizeEvent downloads an event's results from every leaderboard and
+// recomputes the standings for its season.
+func (s *Server) finalizeEvent(e *Event) error {
+	if e.ResultsUpdated() {
+		if err := updateResults(s.db, e.EventID,
+			e.NetLeaderboardUrl,
+			e.GrossLeaderboardUrl,
+			e.SkinsLeaderboardUrl,
+			e.TeamsLeaderboardUrl,
+			e.WgrLeaderboardUrl); err != nil {
+			return fmt.Errorf("error downloading results: %w", err)
+		}
+		s.publishResults(e.EventID)
+		go s.refreshScorecards(e.EventID)
+	}
+
+	var standings Standings
+	if len(e.DateString) >= 4 {
+		if err := s.db.Where("calendar_year = ?", e.DateString[:4]).Limit(1).Find(&standings).Error; err != nil {
+			return err
+		}
+	}
+	if standings.ID == 0 {
+		return nil
+	}
+	if err := updateStandings(s.db, &standings); err != nil {
+		return fmt.Errorf("error downloading new standings: %w", err)
+	}
+	s.publishStandings(standings.CalendarYear)
+	return nil
+}
+
+// markResultsProvisional is called when results are posted for an event
+// that hasn't been finalized.
+func (s *Server) markResultsProvisional(e *Event) {
+	if eventBeforePlay(e.Status) {
+		if err := transitionEvent(s.db, e, eventInProgress, "", time.Now()); err != nil {
+			log.Printf("error starting %s: %s", e.EventID, err)
+			return
+		}
+	}
+	if e.Status != eventInProgress {
+		return
+	}
+	if err := transitionEvent(s.db, e, eventResultsProvisional, "", time.Now()); err != nil {
+		log.Printf("error marking results provisional for %s: %s", e.EventID, err)
+	}
+}
+
+// advanceEventStatuses starts events whose day has come. Only events from
+// the last day are looked at, so old events are never started after the
+// fact.
+func (s *Server) advanceEventStatuses(now time.Time) {
+	var events []Event
+	if err := s.db.Where("status IN ? AND date_string BETWEEN ? AND ?",
+		[]string{eventAnnounced, eventRegistrationOpen, eventRegistrationClosed},
+		now.Add(-24*time.Hour).Format("2006-01-02"), now.Format("2006-01-02")).
+		Find(&events).Error; err != nil {
+		log.Printf("error loading events for status: %s", err)
+		return
+	}
+	for i := range events {
+		if err := transitionEvent(s.db, &events[i], eventInProgress, "", now); err != nil {
+			log.Printf("error starting %s: %s", events[i].EventID, err)
+		}
+	}
+}
+
+func (s *Server) runEventStatusWorker() {
+	ticker := time.NewTicker(eventStatusPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.advanceEventStatuses(time.Now())
+	}
+}
+
+// EventStatus is an event's status, how it got there and where it can go.
+type EventStatus struct {
+	EventID     string            `json:"eventID"`
+	Status      string            `json:"status"`
+	ChangedAt   *time.Time        `json:"changedAt"`
+	Next        []string          `json:"next"`
+	Transitions []EventTransition `json:"transitions"`
+}
+
+func (s *Server) loadEventStatus(e *Event) (*EventStatus, error) {
+	status := &EventStatus{
+		EventID:   e.EventID,
+		Status:    e.Status,
+		ChangedAt: e.StatusChangedAt,
+		Next:      append([]string{}, eventTransitions[e.Status]...),
+	}
+	if err := s.db.Where("event_id = ?", e.EventID).Order("at ASC, id ASC").Find(&status.Transitions).Error; err != nil {
+		return nil, err
+	}
+	if status.Transitions == nil {
+		status.Transitions = []EventTransition{}
+	}
+	return status, nil
+}
+
+// GET /api/events/{eventID}/status
+func (s *Server) GETEventStatus(w http.ResponseWriter, r *http.Request) {
+	var event Event
+	if err := s.visibleEvents(r).First(&event, "event_id = ?", chi.URLParam(r, "eventID")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Event not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+	status, err := s.loadEventStatus(&event)
+	if err != nil {
+		http.Error(w, "Error fetching status", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// POST /api/events/{eventID}/status
+func (s *Server) POSTEventStatus(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if !validEventStatus(input.Status) {
+		http.Error(w, "Unknown status", http.StatusBadRequest)
+		return
+	}
+
+	var event Event
+	if err := s.db.First(&event, "event_id = ?", chi.URLParam(r, "eventID")).Error; err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	if err := s.setEventStatus(&event, input.Status, input.Note); err != nil {
+		switch {
+		case errors.Is(err, errEventTransition):
+			http.Error(w, fmt.Sprintf("Cannot move event from %s to %s", event.Status, input.Status), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		}
+		return
+	}
+
+	status, err := s.loadEventStatus(&event)
+	if err != nil {
+		http.Error(w, "Error fetching status", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestServer_setEventStatus(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	var finalized []string
+	finalizeErr := errors.New("leaderboard unavailable")
+	defer func(orig func(*Server, *Event) error) { finalizeEventHook = orig }(finalizeEventHook)
+	finalizeEventHook = func(s *Server, e *Event) error {
+		finalized = append(finalized, e.EventID)
+		return finalizeErr
+	}
+
+	event := &Event{Name: "LFG Open", DateString: time.Now().Format("2006-01-02")}
+	assert.NoError(t, db.Create(event).Error)
+	assert.Equal(t, eventAnnounced, event.Status)
+
+	assert.NoError(t, s.setEventStatus(event, eventRegistrationOpen, ""))
+	assert.True(t, event.RegistrationOpen)
+	assert.ErrorIs(t, s.setEventStatus(event, eventFinal, ""), errEventTransition)
+
+	// Registration closes with the flag.
+	st, err := loadRegistrationState(db, registrationTarget{EventID: event.EventID})
+	assert.NoError(t, err)
+	assert.NoError(t, st.setOpen(db, false))
+
+	s.advanceEventStatuses(time.Now())
+	assert.NoError(t, db.First(event, "event_id = ?", event.EventID).Error)
+	assert.Equal(t, eventInProgress, event.Status)
+	assert.False(t, event.RegistrationOpen)
+
+	// The event stays in progress when the results can't be downloaded.
+	assert.ErrorIs(t, s.setEventStatus(event, eventFinal, ""), finalizeErr)
+	assert.Equal(t, eventInProgress, event.Status)
+
+	finalizeErr = nil
+	assert.NoError(t, s.setEventStatus(event, eventFinal, "Scores attested"))
+	assert.Equal(t, []string{event.EventID, event.EventID}, finalized)
+
+	var final Event
+	assert.NoError(t, db.First(&final, "event_id = ?", event.EventID).Error)
+	assert.Equal(t, eventFinal, final.Status)
+	assert.True(t, final.IsComplete)
+	assert.NotNil(t, final.StatusChangedAt)
+
+	status, err := s.loadEventStatus(&final)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{eventResultsProvisional}, status.Next)
+	var moves []string
+	for _, tr := range status.Transitions {
+		moves = append(moves, tr.From+">"+tr.To)
+	}
+	assert.Equal(t, []string{
+		"announced>registration-open",
+		"registration-open>registration-closed",
+		"registration-closed>in-progress",
+		"in-progress>final",
+	}, moves)
+	assert.Equal(t, "Scores attested", status.Transitions[3].Note)
+}
+
+func TestServer_markResultsProvisional(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	event := &Event{Name: "LFG Classic", DateString: "2025-07-12", RegistrationOpen: true}
+	assert.NoError(t, db.Create(event).Error)
+	assert.Equal(t, eventRegistrationOpen, event.Status)
+
+	s.markResultsProvisional(event)
+	assert.Equal(t, eventResultsProvisional, event.Status)
+	assert.False(t, event.RegistrationOpen)
+
+	// Final results aren't reopened by a later update.
+	assert.NoError(t, transitionEvent(db, event, eventFinal, "", time.Now()))
+	s.markResultsProvisional(event)
+	assert.Equal(t, eventFinal, event.Status)
+
+	// Events saved before statuses existed get one from their flags.
+	assert.NoError(t, db.Model(event).Update("status", "").Error)
+	var legacy Event
+	assert.NoError(t, db.First(&legacy, "event_id = ?", event.EventID).Error)
+	assert.Equal(t, eventFinal, legacy.Status)
+}
+
+func Test_backfillEventStatuses(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	now := time.Date(2025, 6, 14, 9, 0, 0, 0, time.UTC)
+	events := []*Event{
+		{Name: "Memorial Day Shootout", DateString: "2025-05-24"},
+		{Name: "LFG Open", DateString: "2025-06-14", NetLeaderboardUrl: "https://example.com/leaderboard.htm"},
+		{Name: "LFG Classic", DateString: "2025-06-14"},
+		{Name: "LFG Invitational", DateString: "2025-07-12", RegistrationOpen: true},
+	}
+	for _, e := range events {
+		assert.NoError(t, db.Create(e).Error)
+		assert.NoError(t, db.Model(e).UpdateColumn("status", "").Error)
+	}
+	assert.NoError(t, backfillEventStatuses(db, now))
+
+	// Old events stay where they were when the worker first runs.
+	s.advanceEventStatuses(now)
+	var statuses []string
+	for _, e := range events {
+		var loaded Event
+		assert.NoError(t, db.First(&loaded, "event_id = ?", e.EventID).Error)
+		statuses = append(statuses, loaded.Status)
+	}
+	assert.Equal(t, []string{eventFinal, eventFinal, eventInProgress, eventRegistrationOpen}, statuses)
+
+	var moves int64
+	assert.NoError(t, db.Model(&EventTransition{}).Count(&moves).Error)
+	assert.Equal(t, int64(1), moves)
+}
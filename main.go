@@ -20,6 +20,7 @@ import (
 	"os"
 	"os/user"
 	"path"
+	"time"
 )
 
 const (
@@ -31,8 +32,15 @@ const (
 )
 
 type Options struct {
-	Dev     bool   `long:"dev" description:"Use run a development server on localhost"`
-	DataDir string `short:"d" long:"datadir" description:"Data directory to use for the db and images"`
+	Dev           bool   `long:"dev" description:"Use run a development server on localhost"`
+	DataDir       string `short:"d" long:"datadir" description:"Data directory to use for the db and images"`
+	PublicURL     string `long:"publicurl" description:"Public URL of this server used in email links" default:"https://lfg-server-production.up.railway.app"`
+	SMTPHost      string `long:"smtphost" description:"SMTP server used to send email. Email is disabled if not set"`
+	SMTPPort      int    `long:"smtpport" description:"SMTP server port" default:"587"`
+	SMTPUser      string `long:"smtpuser" description:"SMTP username"`
+	SMTPPassword  string `long:"smtppassword" description:"SMTP password"`
+	SMTPFrom      string `long:"smtpfrom" description:"From address for outgoing email" default:"Live Free Golf <noreply@livefreegolf.com>"`
+	ShopifySecret string `long:"shopifysecret" description:"Shopify webhook signing secret. Order webhooks are rejected if not set"`
 }
 
 type contextKey string
@@ -44,6 +52,12 @@ type Server struct {
 	dataDir          string
 	loginRateLimiter *limiter.Limiter
 	devMode          bool
+	webhookClient    *http.Client
+	webhookWake      chan struct{}
+	mailer           *mailer
+	publicURL        string
+	liveColonyCup    *colonyCupHub
+	shopifySecret    string
 }
 
 var (
@@ -101,7 +115,23 @@ func main() {
 		dataDir:          dataDir,
 		loginRateLimiter: lim,
 		devMode:          opts.Dev,
+		webhookClient:    &http.Client{Timeout: webhookTimeout},
+		webhookWake:      make(chan struct{}, 1),
+		publicURL:        opts.PublicURL,
+		liveColonyCup:    newColonyCupHub(),
+		shopifySecret:    opts.ShopifySecret,
 	}
+	if opts.SMTPHost != "" {
+		s.mailer = newMailer(opts.SMTPHost, opts.SMTPPort, opts.SMTPUser, opts.SMTPPassword, opts.SMTPFrom)
+	}
+	go s.runWebhookWorker()
+	go s.runDigestWorker()
+	go s.runMatchPlayWorker()
+	go s.runTeeTimeWorker()
+	go s.runFieldWorker()
+	go s.runRegistrationWorker()
+	go s.runEventStatusWorker()
+	go s.runPublicationWorker()
 
 	r.Post("/api/login", s.POSTLoginHandler)
 	r.Post("/api/logout", s.POSTLogoutHandler)
@@ -118,19 +148,58 @@ func main() {
 	r.Post("/api/refresh-standings", authMiddleware(s.POSTRefreshStandings))
 
 	r.Get("/api/events", s.GETEvents)
+	r.Get("/api/events.ics", s.GETEventsICal)
 	r.Get("/api/events/{eventID}", s.GETEvent)
 	r.Get("/api/events/{eventID}/thumbnail", s.GETEventThumbnail)
+	r.Get("/api/events/{eventID}/handicaps", s.GETEventHandicaps)
+	r.Get("/api/events/{eventID}/bluegolf", s.GETEventBlueGolf)
+	r.Get("/api/events/{eventID}/field", s.GETEventField)
+	r.Post("/api/events/{eventID}/field/refresh", authMiddleware(s.POSTRefreshEventField))
+	r.Get("/api/events/{eventID}/status", s.GETEventStatus)
+	r.Post("/api/events/{eventID}/status", authMiddleware(s.POSTEventStatus))
+	r.Get("/api/registrations", authMiddleware(s.GETRegistrations))
+	r.Get("/api/registrations/waitlist", authMiddleware(s.GETWaitlist))
+	r.Post("/api/registrations/{id}/promote", authMiddleware(s.POSTPromoteRegistration))
+	r.Post("/api/shopify/orders", s.POSTShopifyOrder)
+	r.Get("/api/shopify/products", authMiddleware(s.GETShopifyProducts))
+	r.Put("/api/shopify/products", authMiddleware(s.PUTShopifyProducts))
+	r.Post("/api/events/{eventID}/discover-leaderboards", authMiddleware(s.POSTDiscoverLeaderboards))
 	r.Post("/api/events", authMiddleware(s.POSTEvent))
 	r.Put("/api/events/{eventID}", authMiddleware(s.PUTEvent))
 	r.Delete("/api/events/{eventID}", authMiddleware(s.DELETEEvent))
 
+	r.Get("/api/courses", s.GETCourses)
+	r.Get("/api/courses/{courseID}", s.GETCourse)
+	r.Get("/api/courses/{courseID}/holes", s.GETCourseHoleStats)
+	r.Post("/api/courses", authMiddleware(s.POSTCourse))
+	r.Put("/api/courses/{courseID}", authMiddleware(s.PUTCourse))
+	r.Delete("/api/courses/{courseID}", authMiddleware(s.DELETECourse))
+
+	r.Get("/api/handicaps", s.GETHandicaps)
+	r.Get("/api/handicaps/{player}", s.GETPlayerHandicap)
+	r.Post("/api/handicaps/{player}/scores", authMiddleware(s.POSTHandicapScore))
+	r.Delete("/api/handicaps/scores/{scoreID}", authMiddleware(s.DELETEHandicapScore))
+	r.Post("/api/handicaps/import/{eventID}", authMiddleware(s.POSTImportHandicapScores))
+
 	r.Get("/api/results/net/{eventID}", s.GETNetResults)
+	r.Get("/api/results/net/{eventID}/computed", s.GETComputedNetResults)
 	r.Get("/api/results/gross/{eventID}", s.GETGrossResults)
 	r.Get("/api/results/skins/{eventID}", s.GETSkinsResults)
 	r.Get("/api/results/teams/{eventID}", s.GETTeamResults)
 	r.Get("/api/results/wgr/{eventID}", s.GETWgrResults)
+	r.Get("/api/results/export", s.GETSeasonResultsExport)
+	r.Get("/api/results/holes/{eventID}", s.GETEventHoleStats)
+	r.Get("/api/results/scorecards/{eventID}", s.GETEventScorecards)
+	r.Get("/api/results/scorecards/{eventID}/{player}", s.GETPlayerScorecard)
+	r.Post("/api/results/scorecards/{eventID}/refresh", authMiddleware(s.POSTRefreshScorecards))
 	r.Get("/api/results/colony-cup/{eventID}", s.GETColonyCupResults)
-	r.Post("/api/results/colony-cup", s.POSTColonyCupResults)
+	r.Post("/api/results/colony-cup", authMiddleware(s.POSTColonyCupResults))
+	r.Get("/api/results/colony-cup/{eventID}/live", s.GETColonyCupScoreboard)
+	r.Get("/api/results/colony-cup/{eventID}/stream", s.GETColonyCupStream)
+	r.Put("/api/results/colony-cup/{eventID}/matches/{matchID}", authMiddleware(s.PUTColonyCupMatch))
+	r.Get("/api/results/colony-cup/{eventID}/sessions", s.GETColonyCupSessions)
+	r.Put("/api/results/colony-cup/{eventID}/sessions", authMiddleware(s.PUTColonyCupSessions))
+	r.Post("/api/results/colony-cup/{eventID}/sessions/{session}/pairings", authMiddleware(s.POSTColonyCupPairings))
 
 	r.Get("/api/disabled-golfers", s.GETDisabledGolfer)
 	r.Post("/api/disabled-golfers/{name}", authMiddleware(s.POSTDisabledGolfer))
@@ -139,6 +208,9 @@ func main() {
 
 	r.Get("/api/colony-cup", s.GETColonyCupInfo)
 	r.Get("/api/colony-cup/all", s.GETAllColonyCupInfo)
+	r.Get("/api/colony-cup/teams", s.GETColonyCupTeams)
+	r.Put("/api/colony-cup/teams", authMiddleware(s.PUTColonyCupTeams))
+	r.Get("/api/colony-cup/records", s.GETColonyCupRecords)
 	r.Post("/api/colony-cup", authMiddleware(s.POSTColonyCupInfo))
 	r.Put("/api/colony-cup", authMiddleware(s.PUTColonyCupInfo))
 	r.Delete("/api/colony-cup", authMiddleware(s.DELETEColonyCupInfo))
@@ -149,22 +221,50 @@ func main() {
 	r.Delete("/api/match-play", authMiddleware(s.DELETEMatchPlayInfo))
 	r.Post("/api/refresh-match-play-bracket", authMiddleware(s.POSTRefreshMatchPlayBracket))
 	r.Get("/api/match-play/results", s.GETMatchPlayResults)
+	r.Get("/api/match-play/bracket", s.GETMatchPlayBracket)
+	r.Get("/api/match-play/bracket.svg", s.GETMatchPlayBracketSVG)
+	r.Post("/api/match-play/bracket", authMiddleware(s.POSTGenerateMatchPlayBracket))
+	r.Put("/api/match-play/matches/{matchID}", authMiddleware(s.PUTMatchPlayMatch))
+	r.Get("/api/match-play/matches/{matchID}/strokes", s.GETMatchStrokes)
+	r.Post("/api/match-play/matches/{matchID}/report", s.POSTReportMatchResult)
+	r.Post("/api/match-play/matches/{matchID}/confirm", s.POSTConfirmMatchResult)
+	r.Post("/api/match-play/matches/{matchID}/dispute", s.POSTDisputeMatchResult)
+	r.Post("/api/match-play/matches/{matchID}/approve", authMiddleware(s.POSTApproveMatchResult))
+	r.Post("/api/match-play/matches/{matchID}/forfeit", authMiddleware(s.POSTForfeitMatch))
+	r.Put("/api/match-play/deadlines", authMiddleware(s.PUTMatchPlayDeadline))
 	r.Post("/api/match-play/player", s.POSTMatchPlayPlayer)
-	r.Put("/api/match-play/player", s.PUTMatchPlayPlayer)
-	r.Delete("/api/match-play/player", s.DELETEMatchPlayPlayer)
+	r.Put("/api/match-play/player", authMiddleware(s.PUTMatchPlayPlayer))
+	r.Delete("/api/match-play/player", authMiddleware(s.DELETEMatchPlayPlayer))
 	r.Get("/api/match-play/players", s.GETMatchPlayPlayers)
+	r.Get("/api/match-play/players/codes", authMiddleware(s.GETMatchPlayAccessCodes))
+
+	r.Get("/api/feed.atom", s.GETAtomFeed)
+	r.Get("/api/webhooks", authMiddleware(s.GETWebhooks))
+	r.Post("/api/webhooks", authMiddleware(s.POSTWebhook))
+	r.Put("/api/webhooks/{id}", authMiddleware(s.PUTWebhook))
+	r.Delete("/api/webhooks/{id}", authMiddleware(s.DELETEWebhook))
+	r.Get("/api/webhooks/{id}/deliveries", authMiddleware(s.GETWebhookDeliveries))
+
+	r.Get("/api/subscriptions", authMiddleware(s.GETEmailSubscriptions))
+	r.Post("/api/subscriptions", s.POSTEmailSubscription)
+	r.Get("/api/subscriptions/confirm", s.GETConfirmEmailSubscription)
+	r.Get("/api/subscriptions/unsubscribe", s.UnsubscribeEmailSubscription)
+	r.Post("/api/subscriptions/unsubscribe", s.UnsubscribeEmailSubscription)
+	r.Post("/api/digests/{eventID}", authMiddleware(s.POSTSendDigest))
 
 	r.Get("/api/current-year", s.GETCurrentYear)
 	r.Get("/api/tee-times/{eventID}", s.GetTeeTimes)
+	r.Get("/api/tee-times/{eventID}/changes", s.GETTeeTimeChanges)
+	r.Post("/api/tee-times/{eventID}/refresh", authMiddleware(s.POSTRefreshTeeTimes))
 	r.Post("/api/updates", s.PostUpdates)
 
 	r.Route("/api/champions", func(r chi.Router) {
 		r.Get("/", s.GETChampions)
-		r.Post("/", s.POSTChampion)
+		r.Post("/", authMiddleware(s.POSTChampion))
 		r.Route("/{year}", func(r chi.Router) {
 			r.Get("/image", s.GETChampionImage)
-			r.Put("/", s.PUTChampion)
-			r.Delete("/", s.DELETEChampion)
+			r.Put("/", authMiddleware(s.PUTChampion))
+			r.Delete("/", authMiddleware(s.DELETEChampion))
 		})
 	})
 
@@ -233,7 +333,7 @@ func initDatabase(dataDirOpt string) (*gorm.DB, string, error) {
 }
 
 func applyMigrations(db *gorm.DB) error {
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		&DBCredentials{},
 		&Event{},
 		&Standings{},
@@ -243,6 +343,9 @@ func applyMigrations(db *gorm.DB) error {
 		&MatchPlayMatch{},
 		&MatchPlayPlayer{},
 		&ColonyCupInfo{},
+		&ColonyCupTeam{},
+		&ColonyCupPlayer{},
+		&ColonyCupSession{},
 		&DisabledGolfer{},
 		&NetResult{},
 		&GrossResult{},
@@ -251,35 +354,39 @@ func applyMigrations(db *gorm.DB) error {
 		&TeamResult{},
 		&WGRResult{},
 		&ColonyCupResult{},
-		&PastChampion{})
+		&Scorecard{},
+		&HoleScore{},
+		&PastChampion{},
+		&Course{},
+		&CourseTee{},
+		&CourseHole{},
+		&HandicapScore{},
+		&HandicapRevision{},
+		&TeeTime{},
+		&TeeTimeSync{},
+		&TeeTimeChange{},
+		&EventRegistration{},
+		&EventFieldSync{},
+		&Registration{},
+		&ShopifyProduct{},
+		&EventTransition{},
+		&FeedEntry{},
+		&Webhook{},
+		&WebhookDelivery{},
+		&WebhookAttempt{},
+		&EmailSubscription{},
+		&EmailDigest{}); err != nil {
+		return err
+	}
+	return backfillEventStatuses(db, time.Now())
 }
 
 // Validate the JWT token. It can either been in a cookie or a header.
 func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var tokenStr string
-
-		// First try Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if len(authHeader) >= 7 && authHeader[:7] == "Bearer " {
-			tokenStr = authHeader[7:]
-		} else {
-			// Fallback to auth_token cookie
-			cookie, err := r.Cookie("auth_token")
-			if err != nil {
-				http.Error(w, "Missing auth token", http.StatusUnauthorized)
-				return
-			}
-			tokenStr = cookie.Value
-		}
-
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		claims, err := requestClaims(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
@@ -288,3 +395,38 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
+
+// requestClaims validates the JWT sent with a request.
+func requestClaims(r *http.Request) (*Claims, error) {
+	var tokenStr string
+
+	// First try Authorization header
+	authHeader := r.Header.Get("Authorization")
+	if len(authHeader) >= 7 && authHeader[:7] == "Bearer " {
+		tokenStr = authHeader[7:]
+	} else {
+		// Fallback to auth_token cookie
+		cookie, err := r.Cookie("auth_token")
+		if err != nil {
+			return nil, errors.New("Missing auth token")
+		}
+		tokenStr = cookie.Value
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, errors.New("Invalid token")
+	}
+	return claims, nil
+}
+
+// authenticated reports whether a request to a public route comes from a
+// logged in admin.
+func authenticated(r *http.Request) bool {
+	_, err := requestClaims(r)
+	return err == nil
+}
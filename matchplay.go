@@ -0,0 +1,419 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	seedByHandicap = "handicap"
+	seedByRank     = "rank"
+
+	byeScore = "Bye"
+)
+
+// parseHandicapIndex parses a handicap as entered by players. Plus handicaps
+// such as "+1.2" are returned as negative numbers.
+func parseHandicapIndex(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	plus := strings.HasPrefix(s, "+")
+	v, err := strconv.ParseFloat(strings.TrimPrefix(s, "+"), 64)
+	if err != nil {
+		return 0, false
+	}
+	if plus {
+		v = -v
+	}
+	return v, true
+}
+
+// seedMatchPlayPlayers orders players from the top seed down. Handicap
+// seeding puts the lowest index first. Rank seeding uses the season ranks
+// given and falls back to handicap for unranked players. Players with no
+// usable handicap are seeded last in name order.
+func seedMatchPlayPlayers(players []MatchPlayPlayer, seedBy string, ranks map[string]int) []string {
+	type seed struct {
+		name   string
+		rank   int
+		hcp    float64
+		hasHcp bool
+	}
+	seeds := make([]seed, 0, len(players))
+	for _, p := range players {
+		sd := seed{name: p.Player, rank: math.MaxInt}
+		sd.hcp, sd.hasHcp = parseHandicapIndex(p.Handicap)
+		if seedBy == seedByRank {
+			if r, ok := ranks[strings.ToLower(p.Player)]; ok {
+				sd.rank = r
+			}
+		}
+		seeds = append(seeds, sd)
+	}
+	sort.SliceStable(seeds, func(i, j int) bool {
+		a, b := seeds[i], seeds[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.hasHcp != b.hasHcp {
+			return a.hasHcp
+		}
+		if a.hcp != b.hcp {
+			return a.hcp < b.hcp
+		}
+		return a.name < b.name
+	})
+	names := make([]string, len(seeds))
+	for i, sd := range seeds {
+		names[i] = sd.name
+	}
+	return names
+}
+
+// bracketOrder returns the seeds in first round order for a bracket of the
+// given power of two size so the top seeds can only meet in later rounds,
+// e.g. 1, 8, 4, 5, 2, 7, 3, 6 for eight players.
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for n := 2; n <= size; n *= 2 {
+		next := make([]int, 0, n)
+		for _, s := range order {
+			next = append(next, s, n+1-s)
+		}
+		order = next
+	}
+	return order
+}
+
+// matchPlayRoundName names a round by how many rounds remain.
+func matchPlayRoundName(roundNum, rounds int) string {
+	switch rounds - roundNum {
+	case 0:
+		return "Finals"
+	case 1:
+		return "Semifinals"
+	case 2:
+		return "Quarterfinals"
+	default:
+		return fmt.Sprintf("Round of %d", 1<<(rounds-roundNum+1))
+	}
+}
+
+// generateBracket builds every match of a single elimination bracket for
+// the seeded players. Fields that are not a power of two give byes to the
+// top seeds, which are decided immediately and advanced.
+func generateBracket(year string, seeds []string) []MatchPlayMatch {
+	if len(seeds) < 2 {
+		return nil
+	}
+	size := 1
+	rounds := 0
+	for size < len(seeds) {
+		size *= 2
+		rounds++
+	}
+
+	var matches []MatchPlayMatch
+	order := bracketOrder(size)
+	for i := 0; i < len(order); i += 2 {
+		m := MatchPlayMatch{
+			Year:     year,
+			RoundNum: 1,
+			Round:    matchPlayRoundName(1, rounds),
+			MatchNum: i / 2,
+			Seed1:    order[i],
+			Seed2:    order[i+1],
+		}
+		if m.Seed1 <= len(seeds) {
+			m.Player1 = seeds[m.Seed1-1]
+		}
+		if m.Seed2 <= len(seeds) {
+			m.Player2 = seeds[m.Seed2-1]
+		}
+		if m.Player2 == "" {
+			m.Winner, m.Score = m.Player1, byeScore
+		}
+		matches = append(matches, m)
+	}
+	for r := 2; r <= rounds; r++ {
+		for n := 0; n < size>>r; n++ {
+			matches = append(matches, MatchPlayMatch{
+				Year:     year,
+				RoundNum: r,
+				Round:    matchPlayRoundName(r, rounds),
+				MatchNum: n,
+			})
+		}
+	}
+	advanceBracket(matches)
+	return matches
+}
+
+// advanceBracket moves the winner of each decided match into its slot in the
+// next round. If a result is changed any later results that depended on it
+// are cleared. It returns the indexes of the matches it changed.
+func advanceBracket(matches []MatchPlayMatch) []int {
+	byPos := make(map[[2]int]int, len(matches))
+	maxRound := 0
+	for i, m := range matches {
+		byPos[[2]int{m.RoundNum, m.MatchNum}] = i
+		if m.RoundNum > maxRound {
+			maxRound = m.RoundNum
+		}
+	}
+
+	changed := make(map[int]bool)
+	for r := 1; r < maxRound; r++ {
+		for i := range matches {
+			m := &matches[i]
+			if m.RoundNum != r {
+				continue
+			}
+			j, ok := byPos[[2]int{r + 1, m.MatchNum / 2}]
+			if !ok {
+				continue
+			}
+			next := &matches[j]
+			player, seed := &next.Player1, &next.Seed1
+			if m.MatchNum%2 == 1 {
+				player, seed = &next.Player2, &next.Seed2
+			}
+
+			winnerSeed := 0
+			if m.Winner != "" {
+				winnerSeed = m.Seed1
+				if m.Winner == m.Player2 {
+					winnerSeed = m.Seed2
+				}
+			}
+			if *player == m.Winner && *seed == winnerSeed {
+				continue
+			}
+			// A result against a different opponent no longer stands.
+			*player, *seed = m.Winner, winnerSeed
+			next.Winner, next.Score = "", ""
+			changed[j] = true
+		}
+	}
+
+	idx := make([]int, 0, len(changed))
+	for i := range changed {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+	return idx
+}
+
+// seasonRankOrder returns the season rank of each player, keyed by lower case
+// name, for the most recent standings year no later than year.
+func seasonRankOrder(db *gorm.DB, year string) (map[string]int, error) {
+	var years []string
+	if err := db.Model(&SeasonRank{}).Where("year <= ?", year).Distinct().Order("year DESC").Pluck("year", &years).Error; err != nil {
+		return nil, err
+	}
+	ranks := make(map[string]int)
+	if len(years) == 0 {
+		return ranks, nil
+	}
+	var rows []SeasonRank
+	if err := db.Where("year = ?", years[0]).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		ranks[strings.ToLower(r.Player)] = parseRank(r.Rank)
+	}
+	return ranks, nil
+}
+
+// POST /api/match-play/bracket
+// Body: {"year":"2025","seedBy":"handicap"|"rank","force":false}
+// Generates the bracket from the registered match play players. Set force to
+// replace a bracket that already has results.
+func (s *Server) POSTGenerateMatchPlayBracket(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Year   string `json:"year"`
+		SeedBy string `json:"seedBy"`
+		Force  bool   `json:"force"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if !validateYear(req.Year) {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return
+	}
+	if req.SeedBy == "" {
+		req.SeedBy = seedByHandicap
+	}
+	if req.SeedBy != seedByHandicap && req.SeedBy != seedByRank {
+		http.Error(w, "seedBy must be handicap or rank", http.StatusBadRequest)
+		return
+	}
+
+	var info MatchPlayInfo
+	if err := s.db.Where("year = ?", req.Year).First(&info).Error; err != nil {
+		http.Error(w, "Match Play not saved yet", http.StatusBadRequest)
+		return
+	}
+	if info.BracketUrl != "" {
+		http.Error(w, "Bracket is scraped from BlueGolf for this year", http.StatusConflict)
+		return
+	}
+
+	var decided int64
+	if err := s.db.Model(&MatchPlayMatch{}).Where("year = ? AND winner <> '' AND score <> ?", req.Year, byeScore).Count(&decided).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if decided > 0 && !req.Force {
+		http.Error(w, "Bracket already has results", http.StatusConflict)
+		return
+	}
+
+	var players []MatchPlayPlayer
+	if err := s.db.Order("player ASC").Find(&players).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if len(players) < 2 {
+		http.Error(w, "At least two players are needed for a bracket", http.StatusBadRequest)
+		return
+	}
+
+	var ranks map[string]int
+	if req.SeedBy == seedByRank {
+		var err error
+		if ranks, err = seasonRankOrder(s.db, req.Year); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	matches := generateBracket(req.Year, seedMatchPlayPlayers(players, req.SeedBy, ranks))
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("year = ?", req.Year).Delete(&MatchPlayMatch{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&matches).Error
+	}); err != nil {
+		http.Error(w, "Could not save bracket", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(matches)
+}
+
+// setMatchResult records the result of a generated bracket match, advances
+// the winner and publishes the change. Later matches that are cleared by the
+// change lose their reported results too.
+func (s *Server) setMatchResult(match *MatchPlayMatch, winner, score, status string) error {
+	if match.RoundNum == 0 {
+		return errors.New("match is not part of a generated bracket")
+	}
+	if winner != "" && winner != match.Player1 && winner != match.Player2 {
+		return errors.New("winner must be one of the players in the match")
+	}
+	if winner != "" && (match.Player1 == "" || match.Player2 == "") {
+		return errors.New("both players must be known before a result is entered")
+	}
+
+	var before []MatchPlayMatch
+	if err := s.db.Where("year = ?", match.Year).Find(&before).Error; err != nil {
+		return err
+	}
+	matches := make([]MatchPlayMatch, len(before))
+	copy(matches, before)
+
+	for i := range matches {
+		if matches[i].ID == match.ID {
+			matches[i].Winner, matches[i].Score = winner, score
+		}
+	}
+	changed := advanceBracket(matches)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&MatchPlayMatch{}).Where("id = ?", match.ID).
+			Updates(map[string]any{"winner": winner, "score": score, "status": status}).Error; err != nil {
+			return err
+		}
+		for _, i := range changed {
+			m := matches[i]
+			if err := tx.Model(&MatchPlayMatch{}).Where("id = ?", m.ID).Updates(map[string]any{
+				"player1":         m.Player1,
+				"player2":         m.Player2,
+				"seed1":           m.Seed1,
+				"seed2":           m.Seed2,
+				"winner":          m.Winner,
+				"score":           m.Score,
+				"status":          "",
+				"reported_by":     "",
+				"reported_winner": "",
+				"reported_score":  "",
+				"reported_at":     nil,
+				"dispute_reason":  "",
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.publishMatchPlay(match.Year, before)
+	return nil
+}
+
+// PUT /api/match-play/matches/{matchID}
+// Body: {"winner":"...","score":"3&2"}. An empty winner clears the result.
+func (s *Server) PUTMatchPlayMatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "matchID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid match ID", http.StatusBadRequest)
+		return
+	}
+	var match MatchPlayMatch
+	if err := s.db.First(&match, id).Error; err != nil {
+		http.Error(w, "Match not found", http.StatusNotFound)
+		return
+	}
+
+	var input struct {
+		Winner string `json:"winner"`
+		Score  string `json:"score"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	status := matchStatusConfirmed
+	if input.Winner == "" {
+		input.Score, status = "", ""
+	} else if input.Score != "" {
+		score, err := normalizeMatchScore(input.Score)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		input.Score = score
+	}
+
+	if err := s.setMatchResult(&match, input.Winner, input.Score, status); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.db.First(&match, id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(match)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_bracketOrder(t *testing.T) {
+	assert.Equal(t, []int{1, 2}, bracketOrder(2))
+	assert.Equal(t, []int{1, 8, 4, 5, 2, 7, 3, 6}, bracketOrder(8))
+	assert.Len(t, bracketOrder(32), 32)
+}
+
+func Test_seedMatchPlayPlayers(t *testing.T) {
+	players := []MatchPlayPlayer{
+		{Player: "Andy Lee", Handicap: "12.4"},
+		{Player: "Connor Shaw", Handicap: "+1.2"},
+		{Player: "Jim Tokanel", Handicap: "n/a"},
+		{Player: "Mike Ross", Handicap: "3"},
+	}
+	assert.Equal(t, []string{"Connor Shaw", "Mike Ross", "Andy Lee", "Jim Tokanel"},
+		seedMatchPlayPlayers(players, seedByHandicap, nil))
+
+	ranks := map[string]int{"jim tokanel": 1, "andy lee": 2}
+	assert.Equal(t, []string{"Jim Tokanel", "Andy Lee", "Connor Shaw", "Mike Ross"},
+		seedMatchPlayPlayers(players, seedByRank, ranks))
+}
+
+func Test_generateBracket(t *testing.T) {
+	seeds := []string{"S1", "S2", "S3", "S4", "S5", "S6"}
+	matches := generateBracket("2025", seeds)
+	assert.Len(t, matches, 7)
+
+	assert.Equal(t, "Quarterfinals", matches[0].Round)
+	assert.Equal(t, "Semifinals", matches[4].Round)
+	assert.Equal(t, "Finals", matches[6].Round)
+
+	// The top two seeds get byes and are already in the semifinals.
+	assert.Equal(t, "S1", matches[0].Winner)
+	assert.Equal(t, byeScore, matches[0].Score)
+	assert.Equal(t, "S2", matches[2].Winner)
+	assert.Equal(t, []string{"S4", "S5"}, []string{matches[1].Player1, matches[1].Player2})
+	assert.Equal(t, "S1", matches[4].Player1)
+	assert.Equal(t, "", matches[4].Player2)
+	assert.Equal(t, "S2", matches[5].Player1)
+
+	// Results advance and changing one clears results that depended on it.
+	matches[1].Winner, matches[1].Score = "S5", "2&1"
+	assert.Equal(t, []int{4}, advanceBracket(matches))
+	assert.Equal(t, "S5", matches[4].Player2)
+	assert.Equal(t, 5, matches[4].Seed2)
+
+	matches[4].Winner = "S5"
+	advanceBracket(matches)
+	assert.Equal(t, "S5", matches[6].Player1)
+
+	matches[1].Winner = "S4"
+	assert.Equal(t, []int{4, 6}, advanceBracket(matches))
+	assert.Equal(t, "S4", matches[4].Player2)
+	assert.Equal(t, "", matches[4].Winner)
+	assert.Equal(t, "", matches[6].Player1)
+}
+
+func Test_normalizeMatchScore(t *testing.T) {
+	for in, want := range map[string]string{
+		"3&2":       "3&2",
+		" 4 and 3 ": "4&3",
+		"10&8":      "10&8",
+		"1 UP":      "1 up",
+		"2up":       "2 up",
+		"1 up (19)": "1 up (19)",
+		"20 holes":  "1 up (20)",
+		"19th":      "1 up (19)",
+	} {
+		got, err := normalizeMatchScore(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, in := range []string{"", "3&4", "5&2", "3 up", "1&0", "18 holes", "won"} {
+		_, err := normalizeMatchScore(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestServer_reportMatchResult(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	matches := generateBracket("2025", []string{"S1", "S2", "S3", "S4"})
+	assert.NoError(t, db.Create(&matches).Error)
+	semi := matches[0] // S1 v S4
+
+	err = s.reportMatchResult(&semi, "S2", "S1", "3&2", time.Now())
+	assert.EqualError(t, err, "only players in the match can report its result")
+	assert.Error(t, s.reportMatchResult(&semi, "S4", "S1", "3&4", time.Now()))
+
+	assert.NoError(t, s.reportMatchResult(&semi, "s4", "s1", "3 & 2", time.Now()))
+	assert.Equal(t, matchStatusReported, semi.Status)
+	assert.Equal(t, "S1", semi.ReportedWinner)
+	assert.Equal(t, "3&2", semi.ReportedScore)
+
+	assert.EqualError(t, s.confirmMatchResult(&semi, "S4"), "the result must be confirmed by the opponent")
+	assert.NoError(t, s.disputeMatchResult(&semi, "S1", "I lost 2&1"))
+	assert.EqualError(t, s.confirmMatchResult(&semi, "S1"), "disputed results must be confirmed by an admin")
+	assert.NoError(t, s.confirmMatchResult(&semi, ""))
+
+	var final MatchPlayMatch
+	assert.NoError(t, db.Where("year = ? AND round_num = 2", "2025").First(&final).Error)
+	assert.Equal(t, "S1", final.Player1)
+	assert.NoError(t, db.First(&semi, semi.ID).Error)
+	assert.Equal(t, matchStatusConfirmed, semi.Status)
+	assert.Equal(t, "S1", semi.Winner)
+
+	// Deadlines confirm unanswered reports and flag unplayed matches.
+	past := time.Now().Add(-time.Hour)
+	assert.NoError(t, db.Model(&MatchPlayMatch{}).Where("year = ?", "2025").Update("deadline", past).Error)
+	other := matches[1]
+	assert.NoError(t, s.reportMatchResult(&other, "S2", "S3", "1 up", time.Now()))
+	s.processMatchPlayDeadlines(time.Now())
+
+	assert.NoError(t, db.First(&other, other.ID).Error)
+	assert.Equal(t, "S3", other.Winner)
+	assert.NoError(t, db.First(&final, final.ID).Error)
+	assert.Equal(t, "S3", final.Player2)
+	assert.Equal(t, "", final.Status)
+
+	// The final only had both players after the first pass.
+	s.processMatchPlayDeadlines(time.Now())
+	assert.NoError(t, db.First(&final, final.ID).Error)
+	assert.Equal(t, matchStatusOverdue, final.Status)
+}
+
+func Test_matchStrokes(t *testing.T) {
+	course := testCourse()
+	assert.NoError(t, validateCourse(&course))
+	tee := &course.Tees[0]
+
+	match := &MatchPlayMatch{Player1: "S1", Player2: "S2"}
+	indexes := map[string]float64{"S1": 4.0, "S2": 12.4}
+
+	ms := matchStrokes(match, indexes, &course, tee, 1)
+	assert.Equal(t, 3, ms.Players[0].CourseHandicap)
+	assert.Equal(t, 13, ms.Players[1].CourseHandicap)
+	assert.Equal(t, 10, ms.Strokes)
+	assert.Equal(t, "S2", ms.Receiver)
+	for _, h := range ms.Holes {
+		if h.Handicap <= 10 {
+			assert.Equal(t, 1, h.Strokes, h.Hole)
+		} else {
+			assert.Equal(t, 0, h.Strokes, h.Hole)
+		}
+	}
+
+	ms = matchStrokes(match, indexes, &course, tee, 0.9)
+	assert.Equal(t, 9, ms.Strokes)
+
+	indexes["S1"] = 12.4
+	ms = matchStrokes(match, indexes, &course, tee, 1)
+	assert.Equal(t, 0, ms.Strokes)
+	assert.Equal(t, "", ms.Receiver)
+}
+
+func TestServer_GETMatchStrokes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	course := testCourse()
+	assert.NoError(t, validateCourse(&course))
+	assert.NoError(t, db.Create(&course).Error)
+	assert.NoError(t, db.Create(&MatchPlayInfo{Year: "2025", CourseID: course.ID, TeeID: course.Tees[0].ID}).Error)
+	assert.NoError(t, db.Create(&[]MatchPlayPlayer{
+		{Player: "Connor Shaw", Handicap: "4.0"},
+		{Player: "Andy Lee", Handicap: "12.4"},
+		{Player: "Mike Ross", Handicap: "20.1"},
+	}).Error)
+	match := &MatchPlayMatch{Year: "2025", Round: "1", Player1: "connor shaw", Player2: "Andy Lee"}
+	assert.NoError(t, db.Create(match).Error)
+
+	r := chi.NewRouter()
+	r.Get("/api/match-play/matches/{matchID}/strokes", s.GETMatchStrokes)
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/match-play/matches/%d/strokes%s", match.ID, query), nil))
+		return rec
+	}
+
+	rec := get("")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var ms MatchStrokes
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&ms))
+	assert.Equal(t, 10, ms.Strokes)
+	assert.Equal(t, "Andy Lee", ms.Receiver)
+
+	assert.Equal(t, http.StatusBadRequest, get("?allowance=1.5").Code)
+
+	assert.NoError(t, db.Model(match).Update("player2", "Sam Park").Error)
+	assert.Equal(t, http.StatusConflict, get("").Code)
+}
+
+func Test_buildBracket(t *testing.T) {
+	matches := generateBracket("2025", []string{"S1", "S2", "S3"})
+	for i := range matches {
+		matches[i].ID = uint(i + 1)
+	}
+	matches[1].Winner, matches[1].Score = "S3", "1 up"
+	advanceBracket(matches)
+	matches[2].Winner, matches[2].Score = "S1", "4&3"
+
+	b := buildBracket("2025", matches)
+	assert.Len(t, b.Rounds, 2)
+	assert.Equal(t, "Semifinals", b.Rounds[0].Name)
+	assert.Equal(t, "S1", b.Champion)
+
+	final := b.Rounds[1].Matches[0]
+	assert.Equal(t, uint(1), *final.Slots[0].FeederMatchID)
+	assert.Equal(t, uint(2), *final.Slots[1].FeederMatchID)
+	assert.Equal(t, "S3", final.Slots[1].Player)
+	assert.Equal(t, uint(3), *b.Rounds[0].Matches[1].NextMatchID)
+
+	// Scraped brackets are ordered by their round labels as stored.
+	scraped := []MatchPlayMatch{
+		{Model: gorm.Model{ID: 1}, Round: "Round 1", MatchNum: 0, Player1: "A", Player2: "B", Winner: "A"},
+		{Model: gorm.Model{ID: 2}, Round: "Round 1", MatchNum: 1, Player1: "C", Player2: "D", Winner: "D"},
+		{Model: gorm.Model{ID: 3}, Round: "Final", MatchNum: 0, Player1: "A", Player2: "D"},
+	}
+	b = buildBracket("2024", scraped)
+	assert.Equal(t, []string{"Round 1", "Final"}, []string{b.Rounds[0].Name, b.Rounds[1].Name})
+	assert.Equal(t, "", b.Champion)
+	assert.Equal(t, uint(2), *b.Rounds[1].Matches[0].Slots[1].FeederMatchID)
+
+	svg := string(renderBracketSVG(b))
+	assert.Contains(t, svg, "<svg")
+	assert.Contains(t, svg, "2024 Match Play")
+	assert.Contains(t, svg, ">Final<")
+}
+
+func TestServer_POSTMatchPlayPlayer(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	post := func(name string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.POSTMatchPlayPlayer(rec, httptest.NewRequest(http.MethodPost, "/api/match-play/player",
+			strings.NewReader(fmt.Sprintf(`{"player":%q,"handicap":"12.4"}`, name))))
+		return rec
+	}
+
+	rec := post("John Smith")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "accessCode")
+	var p MatchPlayPlayer
+	assert.NoError(t, db.First(&p, "player = ?", "John Smith").Error)
+	assert.NotEmpty(t, p.AccessCode)
+
+	// Nobody can register a second John Smith to get a code for their matches.
+	assert.Equal(t, http.StatusConflict, post("john smith").Code)
+}
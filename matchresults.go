@@ -0,0 +1,408 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	matchStatusReported  = "reported"
+	matchStatusConfirmed = "confirmed"
+	matchStatusDisputed  = "disputed"
+	matchStatusForfeit   = "forfeit"
+	matchStatusOverdue   = "overdue"
+
+	forfeitScore = "Forfeit"
+
+	matchPlayPollInterval = 15 * time.Minute
+)
+
+var (
+	matchScoreClosedOut = regexp.MustCompile(`^(\d{1,2})\s*(?:&|and)\s*(\d)$`)
+	matchScoreUp        = regexp.MustCompile(`^([12])\s*up$`)
+	matchScoreExtra     = regexp.MustCompile(`^(?:1\s*up\s*)?\(?(\d{2})(?:\s*holes|th)?\)?$`)
+)
+
+// normalizeMatchScore validates a match play result over 18 holes and
+// returns it in a standard form: "3&2", "1 up", "2 up" or "1 up (19)" for
+// matches decided in extra holes.
+func normalizeMatchScore(score string) (string, error) {
+	s := strings.ToLower(strings.TrimSpace(score))
+	if m := matchScoreClosedOut.FindStringSubmatch(s); m != nil {
+		up, _ := strconv.Atoi(m[1])
+		toPlay, _ := strconv.Atoi(m[2])
+		// A match ends as soon as the lead is greater than the holes left, so
+		// the winning margin is one or two more than the holes to play.
+		if toPlay >= 1 && toPlay <= 8 && (up == toPlay+1 || up == toPlay+2) {
+			return fmt.Sprintf("%d&%d", up, toPlay), nil
+		}
+	}
+	if m := matchScoreUp.FindStringSubmatch(s); m != nil {
+		return m[1] + " up", nil
+	}
+	if m := matchScoreExtra.FindStringSubmatch(s); m != nil {
+		if hole, _ := strconv.Atoi(m[1]); hole >= 19 && hole <= 36 {
+			return fmt.Sprintf("1 up (%d)", hole), nil
+		}
+	}
+	return "", fmt.Errorf("invalid match score %q, expected a result like 3&2, 1 up or 1 up (19)", score)
+}
+
+// opponent returns the other player in the match or "" if player is not in it.
+func (m *MatchPlayMatch) opponent(player string) string {
+	switch {
+	case strings.EqualFold(player, m.Player1):
+		return m.Player2
+	case strings.EqualFold(player, m.Player2):
+		return m.Player1
+	default:
+		return ""
+	}
+}
+
+// matchPlayerName returns the player's name as it appears in the match.
+func (m *MatchPlayMatch) matchPlayerName(player string) string {
+	if strings.EqualFold(player, m.Player1) {
+		return m.Player1
+	}
+	return m.Player2
+}
+
+// reportMatchResult records a result submitted by one of the players. It
+// stands once the opponent or an admin confirms it.
+func (s *Server) reportMatchResult(match *MatchPlayMatch, player, winner, score string, now time.Time) error {
+	if match.RoundNum == 0 {
+		return errors.New("match is not part of a generated bracket")
+	}
+	if match.Player1 == "" || match.Player2 == "" {
+		return errors.New("both players must be known before a result is reported")
+	}
+	if match.opponent(player) == "" {
+		return errors.New("only players in the match can report its result")
+	}
+	if match.Winner != "" {
+		return errors.New("match has already been decided")
+	}
+	if match.opponent(winner) == "" {
+		return errors.New("winner must be one of the players in the match")
+	}
+	score, err := normalizeMatchScore(score)
+	if err != nil {
+		return err
+	}
+
+	match.Status = matchStatusReported
+	match.ReportedBy = match.matchPlayerName(player)
+	match.ReportedWinner = match.matchPlayerName(winner)
+	match.ReportedScore = score
+	match.ReportedAt = &now
+	match.DisputeReason = ""
+	return s.db.Model(match).Select("status", "reported_by", "reported_winner", "reported_score", "reported_at", "dispute_reason").
+		Updates(match).Error
+}
+
+// confirmMatchResult makes the reported result official and advances the
+// winner. An empty player means an admin is confirming.
+func (s *Server) confirmMatchResult(match *MatchPlayMatch, player string) error {
+	if match.ReportedWinner == "" || (match.Status != matchStatusReported && match.Status != matchStatusDisputed && match.Status != matchStatusOverdue) {
+		return errors.New("no result has been reported for this match")
+	}
+	if player != "" {
+		if match.opponent(player) == "" {
+			return errors.New("only players in the match can confirm its result")
+		}
+		if strings.EqualFold(player, match.ReportedBy) {
+			return errors.New("the result must be confirmed by the opponent")
+		}
+		if match.Status == matchStatusDisputed {
+			return errors.New("disputed results must be confirmed by an admin")
+		}
+	}
+	return s.setMatchResult(match, match.ReportedWinner, match.ReportedScore, matchStatusConfirmed)
+}
+
+// disputeMatchResult flags a reported result for an admin to resolve.
+func (s *Server) disputeMatchResult(match *MatchPlayMatch, player, reason string) error {
+	if match.Status != matchStatusReported {
+		return errors.New("only reported results can be disputed")
+	}
+	if match.opponent(player) == "" || strings.EqualFold(player, match.ReportedBy) {
+		return errors.New("only the opponent can dispute a result")
+	}
+	match.Status = matchStatusDisputed
+	match.DisputeReason = strings.TrimSpace(reason)
+	return s.db.Model(match).Select("status", "dispute_reason").Updates(match).Error
+}
+
+// processMatchPlayDeadlines handles generated bracket matches whose round
+// deadline has passed. Reported results the opponent never answered are
+// confirmed and unreported matches are flagged as overdue for an admin to
+// decide, usually by forfeit.
+func (s *Server) processMatchPlayDeadlines(now time.Time) {
+	var due []MatchPlayMatch
+	if err := s.db.Where("round_num > 0 AND winner = '' AND deadline IS NOT NULL AND deadline <= ?", now).
+		Where("status IN ?", []string{"", matchStatusReported}).Find(&due).Error; err != nil {
+		log.Printf("error loading overdue matches: %s", err)
+		return
+	}
+	for i := range due {
+		m := &due[i]
+		if m.Player1 == "" || m.Player2 == "" {
+			continue
+		}
+		var err error
+		if m.Status == matchStatusReported {
+			err = s.confirmMatchResult(m, "")
+		} else {
+			err = s.db.Model(m).Update("status", matchStatusOverdue).Error
+		}
+		if err != nil {
+			log.Printf("error processing deadline for match %d: %s", m.ID, err)
+		}
+	}
+}
+
+func (s *Server) runMatchPlayWorker() {
+	ticker := time.NewTicker(matchPlayPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.processMatchPlayDeadlines(time.Now())
+	}
+}
+
+// checkPlayerAccessCode verifies the access code issued to a match play
+// player. Failed attempts count against the login rate limit.
+func (s *Server) checkPlayerAccessCode(r *http.Request, player, code string) error {
+	key := loginRateLimitKey(r, "match-play:"+strings.ToLower(player))
+	lctx, err := s.loginRateLimiter.Peek(r.Context(), key)
+	if err != nil {
+		return err
+	}
+	if lctx.Reached {
+		return errors.New("Too many failed attempts")
+	}
+
+	var p MatchPlayPlayer
+	if err := s.db.Where("LOWER(player) = LOWER(?)", player).First(&p).Error; err != nil ||
+		p.AccessCode == "" || subtle.ConstantTimeCompare([]byte(p.AccessCode), []byte(code)) != 1 {
+		s.loginRateLimiter.Increment(r.Context(), key, 1)
+		return errors.New("Invalid player or access code")
+	}
+	return nil
+}
+
+// loadMatchRequest decodes the request body and loads the match, writing the
+// error response on failure. When player is set the request must carry that
+// player's access code.
+func (s *Server) loadMatchRequest(w http.ResponseWriter, r *http.Request, body any, player, code *string) (*MatchPlayMatch, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "matchID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid match ID", http.StatusBadRequest)
+		return nil, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return nil, false
+	}
+	var match MatchPlayMatch
+	if err := s.db.First(&match, id).Error; err != nil {
+		http.Error(w, "Match not found", http.StatusNotFound)
+		return nil, false
+	}
+	if player != nil {
+		if err := s.checkPlayerAccessCode(r, *player, *code); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return nil, false
+		}
+	}
+	return &match, true
+}
+
+func (s *Server) writeMatch(w http.ResponseWriter, id uint) {
+	var match MatchPlayMatch
+	s.db.First(&match, id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(match)
+}
+
+// POST /api/match-play/matches/{matchID}/report
+// Body: {"player":"...","code":"...","winner":"...","score":"3&2"}
+func (s *Server) POSTReportMatchResult(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Player string `json:"player"`
+		Code   string `json:"code"`
+		Winner string `json:"winner"`
+		Score  string `json:"score"`
+	}
+	match, ok := s.loadMatchRequest(w, r, &req, &req.Player, &req.Code)
+	if !ok {
+		return
+	}
+	if err := s.reportMatchResult(match, req.Player, req.Winner, req.Score, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.writeMatch(w, match.ID)
+}
+
+// POST /api/match-play/matches/{matchID}/confirm
+// Body: {"player":"...","code":"..."}
+func (s *Server) POSTConfirmMatchResult(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Player string `json:"player"`
+		Code   string `json:"code"`
+	}
+	match, ok := s.loadMatchRequest(w, r, &req, &req.Player, &req.Code)
+	if !ok {
+		return
+	}
+	if err := s.confirmMatchResult(match, req.Player); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.writeMatch(w, match.ID)
+}
+
+// POST /api/match-play/matches/{matchID}/dispute
+// Body: {"player":"...","code":"...","reason":"..."}
+func (s *Server) POSTDisputeMatchResult(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Player string `json:"player"`
+		Code   string `json:"code"`
+		Reason string `json:"reason"`
+	}
+	match, ok := s.loadMatchRequest(w, r, &req, &req.Player, &req.Code)
+	if !ok {
+		return
+	}
+	if err := s.disputeMatchResult(match, req.Player, req.Reason); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.writeMatch(w, match.ID)
+}
+
+// POST /api/match-play/matches/{matchID}/approve
+// Confirms the reported result as an admin, including disputed results.
+func (s *Server) POSTApproveMatchResult(w http.ResponseWriter, r *http.Request) {
+	var req struct{}
+	match, ok := s.loadMatchRequest(w, r, &req, nil, nil)
+	if !ok {
+		return
+	}
+	if err := s.confirmMatchResult(match, ""); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.writeMatch(w, match.ID)
+}
+
+// POST /api/match-play/matches/{matchID}/forfeit
+// Body: {"player":"..."} is the player forfeiting the match.
+func (s *Server) POSTForfeitMatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Player string `json:"player"`
+	}
+	match, ok := s.loadMatchRequest(w, r, &req, nil, nil)
+	if !ok {
+		return
+	}
+	winner := match.opponent(req.Player)
+	if winner == "" {
+		http.Error(w, "Player is not in the match", http.StatusBadRequest)
+		return
+	}
+	if err := s.setMatchResult(match, winner, forfeitScore, matchStatusForfeit); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.writeMatch(w, match.ID)
+}
+
+// PUT /api/match-play/deadlines
+// Body: {"year":"2025","roundNum":1,"deadline":"2025-06-30"}. The deadline
+// may be a date, meaning the end of that day, or an RFC 3339 timestamp. An
+// empty deadline removes it.
+func (s *Server) PUTMatchPlayDeadline(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Year     string `json:"year"`
+		RoundNum int    `json:"roundNum"`
+		Deadline string `json:"deadline"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if !validateYear(req.Year) || req.RoundNum < 1 {
+		http.Error(w, "Year and round must be set", http.StatusBadRequest)
+		return
+	}
+
+	var deadline *time.Time
+	if req.Deadline != "" {
+		t, err := time.Parse(time.RFC3339, req.Deadline)
+		if err != nil {
+			day, derr := time.ParseInLocation("2006-01-02", req.Deadline, time.Local)
+			if derr != nil {
+				http.Error(w, "Deadline must be a date or RFC 3339 timestamp", http.StatusBadRequest)
+				return
+			}
+			t = day.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		deadline = &t
+	}
+
+	res := s.db.Model(&MatchPlayMatch{}).Where("year = ? AND round_num = ?", req.Year, req.RoundNum).Update("deadline", deadline)
+	if res.Error != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if res.RowsAffected == 0 {
+		http.Error(w, "Round not found", http.StatusNotFound)
+		return
+	}
+	if deadline != nil && deadline.After(time.Now()) {
+		// Matches flagged under an earlier deadline are back in play.
+		s.db.Model(&MatchPlayMatch{}).Where("year = ? AND round_num = ? AND status = ?", req.Year, req.RoundNum, matchStatusOverdue).
+			Update("status", "")
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// GET /api/match-play/players/codes
+// Lists each player's access code so they can be sent out, issuing codes to
+// players who do not have one yet.
+func (s *Server) GETMatchPlayAccessCodes(w http.ResponseWriter, r *http.Request) {
+	var players []MatchPlayPlayer
+	if err := s.db.Order("player ASC").Find(&players).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	type accessCode struct {
+		Player string `json:"player"`
+		Code   string `json:"code"`
+	}
+	codes := []accessCode{}
+	for i := range players {
+		p := &players[i]
+		if p.AccessCode == "" {
+			p.AccessCode = randomHex(6)
+			if err := s.db.Model(p).Update("access_code", p.AccessCode).Error; err != nil {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+		}
+		codes = append(codes, accessCode{Player: p.Player, Code: p.AccessCode})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(codes)
+}
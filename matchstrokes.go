@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cpacia/lfg-server/handicap"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type MatchStrokesPlayer struct {
+	Player         string  `json:"player"`
+	Index          float64 `json:"index"`
+	CourseHandicap int     `json:"courseHandicap"`
+}
+
+// MatchStrokes are the handicap terms for a match: how many strokes the
+// higher handicap receives and the holes they fall on.
+type MatchStrokes struct {
+	MatchID   uint                 `json:"matchID"`
+	Course    string               `json:"course"`
+	Tee       string               `json:"tee"`
+	Rating    float64              `json:"rating"`
+	Slope     int                  `json:"slope"`
+	Par       int                  `json:"par"`
+	Allowance float64              `json:"allowance"`
+	Players   []MatchStrokesPlayer `json:"players"`
+	Strokes   int                  `json:"strokes"`
+	Receiver  string               `json:"receiver,omitempty"`
+	Holes     []HoleStroke         `json:"holes"`
+}
+
+// validateMatchPlayCourse checks the default course, tee and allowance set
+// for a match play season.
+func (s *Server) validateMatchPlayCourse(info MatchPlayInfo) error {
+	if _, err := handicap.ParseAllowance(info.HandicapAllowance); err != nil {
+		return err
+	}
+	if info.CourseID == 0 && info.TeeID == 0 {
+		return nil
+	}
+	if info.CourseID == 0 || info.TeeID == 0 {
+		return errors.New("courseID and teeID must be set together")
+	}
+	if _, _, err := s.loadTee(info.CourseID, info.TeeID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("course not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// matchStrokes works out the strokes given in a match. The difference between
+// the players' course handicaps is reduced by the allowance and the player
+// with the higher handicap receives that many strokes, allocated by the
+// tee's hole handicap ranking.
+func matchStrokes(match *MatchPlayMatch, indexes map[string]float64, course *Course, tee *CourseTee, allowance float64) *MatchStrokes {
+	ms := &MatchStrokes{
+		MatchID:   match.ID,
+		Course:    course.Name,
+		Tee:       tee.Name,
+		Rating:    tee.Rating,
+		Slope:     tee.Slope,
+		Par:       tee.Par,
+		Allowance: allowance,
+	}
+	for _, p := range []string{match.Player1, match.Player2} {
+		idx := indexes[p]
+		ms.Players = append(ms.Players, MatchStrokesPlayer{
+			Player:         p,
+			Index:          idx,
+			CourseHandicap: handicap.CourseHandicap(idx, tee.Slope, tee.Rating, tee.Par),
+		})
+	}
+
+	diff := ms.Players[0].CourseHandicap - ms.Players[1].CourseHandicap
+	ms.Strokes = handicap.PlayingHandicap(int(math.Abs(float64(diff))), allowance)
+	if ms.Strokes > 0 {
+		ms.Receiver = ms.Players[0].Player
+		if diff < 0 {
+			ms.Receiver = ms.Players[1].Player
+		}
+	}
+	ms.Holes = holeStrokes(tee, ms.Strokes)
+	return ms
+}
+
+// GET /api/match-play/matches/{matchID}/strokes
+// Optional ?courseID=&teeID=&allowance= override the season's defaults.
+func (s *Server) GETMatchStrokes(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "matchID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid match ID", http.StatusBadRequest)
+		return
+	}
+	var match MatchPlayMatch
+	if err := s.db.First(&match, id).Error; err != nil {
+		http.Error(w, "Match not found", http.StatusNotFound)
+		return
+	}
+	if match.Player1 == "" || match.Player2 == "" {
+		http.Error(w, "Match does not have both players yet", http.StatusConflict)
+		return
+	}
+
+	var info MatchPlayInfo
+	s.db.Where("year = ?", match.Year).Limit(1).Find(&info)
+
+	q := r.URL.Query()
+	courseID, teeID := info.CourseID, info.TeeID
+	if q.Get("courseID") != "" || q.Get("teeID") != "" {
+		c, err1 := strconv.ParseUint(q.Get("courseID"), 10, 64)
+		t, err2 := strconv.ParseUint(q.Get("teeID"), 10, 64)
+		if err1 != nil || err2 != nil {
+			http.Error(w, "courseID and teeID must both be valid IDs", http.StatusBadRequest)
+			return
+		}
+		courseID, teeID = uint(c), uint(t)
+	}
+	if courseID == 0 || teeID == 0 {
+		http.Error(w, "No course and tee selected for this match", http.StatusConflict)
+		return
+	}
+
+	allowanceStr := info.HandicapAllowance
+	if q.Get("allowance") != "" {
+		allowanceStr = q.Get("allowance")
+	}
+	allowance, err := handicap.ParseAllowance(allowanceStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	course, tee, err := s.loadTee(courseID, teeID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Course not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	var players []MatchPlayPlayer
+	if err := s.db.Where("LOWER(player) IN ?", []string{strings.ToLower(match.Player1), strings.ToLower(match.Player2)}).
+		Find(&players).Error; err != nil {
+		http.Error(w, "Error fetching players", http.StatusInternalServerError)
+		return
+	}
+	indexes := make(map[string]float64)
+	for _, name := range []string{match.Player1, match.Player2} {
+		found := false
+		for _, p := range players {
+			if !strings.EqualFold(p.Player, name) {
+				continue
+			}
+			if idx, ok := parseHandicapIndex(p.Handicap); ok {
+				indexes[name] = idx
+				found = true
+			}
+			break
+		}
+		if !found {
+			http.Error(w, fmt.Sprintf("No handicap on file for %s", name), http.StatusConflict)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(matchStrokes(&match, indexes, course, tee, allowance))
+}
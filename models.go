@@ -50,6 +50,35 @@ type Event struct {
 	SkinsLeaderboardUrl string `json:"skinsLeaderboardUrl"`
 	TeamsLeaderboardUrl string `json:"teamsLeaderboardUrl"`
 	WgrLeaderboardUrl   string `json:"wgrLeaderboardUrl"`
+	CourseID            uint   `json:"courseID"`
+	TeeID               uint   `json:"teeID"`
+	// BlueGolfClub and BlueGolfSeason override the season's BlueGolf site
+	// for this event.
+	BlueGolfClub   string `json:"blueGolfClub"`
+	BlueGolfSeason string `json:"blueGolfSeason"`
+	// Capacity is the number of paid registrations at which registration
+	// closes and later orders join the waitlist. Zero means unlimited.
+	Capacity int `json:"capacity"`
+	// Registration opens and closes on its own at these times.
+	// RegistrationOpenedAt records when the opening time was applied, so
+	// registration closed by hand afterwards stays closed.
+	RegistrationOpensAt  *time.Time `json:"registrationOpensAt"`
+	RegistrationClosesAt *time.Time `json:"registrationClosesAt"`
+	RegistrationOpenedAt *time.Time `json:"registrationOpenedAt"`
+	// Status is where the event is in its lifecycle. It only changes
+	// through the transitions in lifecycle.go.
+	Status          string     `json:"status"`
+	StatusChangedAt *time.Time `json:"statusChangedAt"`
+	// PublishAt announces a draft event at the given time.
+	PublishAt *time.Time `json:"publishAt"`
+}
+
+// AfterFind fills in the status of events saved before statuses existed.
+func (e *Event) AfterFind(tx *gorm.DB) (err error) {
+	if e.Status == "" {
+		e.Status = legacyEventStatus(e)
+	}
+	return
 }
 
 func (e *Event) BeforeSave(tx *gorm.DB) (err error) {
@@ -74,6 +103,9 @@ func (e *Event) BeforeCreate(tx *gorm.DB) (err error) {
 
 	// Set the ID
 	e.EventID = fmt.Sprintf("%d-%s", year, safeSlug)
+	if e.Status == "" {
+		e.Status = legacyEventStatus(e)
+	}
 	return
 }
 
@@ -154,6 +186,93 @@ type ColonyCupResult struct {
 	TeamTwo    string `json:"team2"`
 	Winner     string `json:"winner"`
 	Score      string `json:"score"`
+
+	// Live scoring. Holes holds the result of each hole played in order:
+	// 1 or 2 for the side that won it, 0 when it was halved.
+	Holes  datatypes.JSONSlice[int] `json:"holes"`
+	Status string                   `json:"status"` // e.g. "2 UP thru 12"
+}
+
+type Scorecard struct {
+	gorm.Model
+	EventID      string      `json:"eventID" gorm:"index"`
+	Player       string      `json:"player" gorm:"index"`
+	Round        int         `json:"round"`
+	ScorecardUrl string      `json:"scorecardUrl"`
+	Par          int         `json:"par"`
+	Gross        int         `json:"gross"`
+	Net          int         `json:"net"`
+	Eagles       int         `json:"eagles"` // Eagle or better
+	Birdies      int         `json:"birdies"`
+	Holes        []HoleScore `json:"holes" gorm:"foreignKey:ScorecardID;constraint:OnDelete:CASCADE"`
+}
+
+type HoleScore struct {
+	gorm.Model
+	ScorecardID uint `json:"-" gorm:"index"`
+	Hole        int  `json:"hole"`
+	Par         int  `json:"par"`
+	Handicap    int  `json:"handicap"` // Hole stroke index, 1 is the hardest hole
+	Gross       int  `json:"gross"`
+	Net         int  `json:"net"`
+	Strokes     int  `json:"strokes"` // Handicap strokes received on the hole
+}
+
+type Course struct {
+	gorm.Model
+	Name  string      `json:"name" gorm:"uniqueIndex"`
+	Town  string      `json:"town"`
+	State string      `json:"state"`
+	Tees  []CourseTee `json:"tees" gorm:"foreignKey:CourseID"`
+}
+
+type CourseTee struct {
+	gorm.Model
+	CourseID uint         `json:"courseID" gorm:"index"`
+	Name     string       `json:"name"` // e.g. "Blue", "White"
+	Rating   float64      `json:"rating"`
+	Slope    int          `json:"slope"`
+	Par      int          `json:"par"`
+	Yardage  int          `json:"yardage"`
+	Holes    []CourseHole `json:"holes" gorm:"foreignKey:TeeID"`
+}
+
+type CourseHole struct {
+	gorm.Model
+	TeeID    uint `json:"-" gorm:"index"`
+	Hole     int  `json:"hole"`
+	Par      int  `json:"par"`
+	Handicap int  `json:"handicap"` // Stroke index, 1 is the hardest hole
+	Yardage  int  `json:"yardage"`
+}
+
+// HandicapScore is a round posted to a player's handicap record, either
+// imported from an event scorecard or entered by an admin.
+type HandicapScore struct {
+	gorm.Model
+	Player        string  `json:"player" gorm:"index"`
+	EventID       string  `json:"eventID" gorm:"index"`
+	Date          string  `json:"date"` // 2006-01-02
+	Course        string  `json:"course"`
+	Tee           string  `json:"tee"`
+	AdjustedGross int     `json:"adjustedGross"`
+	CourseRating  float64 `json:"courseRating"`
+	Slope         int     `json:"slope"`
+	PCC           int     `json:"pcc"`
+	Differential  float64 `json:"differential"`
+	Reduction     float64 `json:"reduction"`
+	Used          bool    `json:"used"`
+}
+
+// HandicapRevision is a player's Handicap Index after a score was posted.
+type HandicapRevision struct {
+	gorm.Model
+	Player   string  `json:"player" gorm:"index"`
+	ScoreID  uint    `json:"scoreID"`
+	Date     string  `json:"date"`
+	Index    float64 `json:"index"`
+	LowIndex float64 `json:"lowIndex"`
+	Cap      string  `json:"cap"`
 }
 
 type Standings struct {
@@ -161,6 +280,24 @@ type Standings struct {
 	CalendarYear       string `json:"calendarYear" gorm:"uniqueIndex"`
 	SeasonStandingsUrl string `json:"seasonStandingsUrl"`
 	WgrStandingsUrl    string `json:"wgrStandingsUrl"`
+	// BlueGolfClub and BlueGolfSeason locate the season's pages on BlueGolf.
+	// They are filled from SeasonStandingsUrl when left blank.
+	BlueGolfClub   string `json:"blueGolfClub"`
+	BlueGolfSeason string `json:"blueGolfSeason"`
+}
+
+func (s *Standings) BeforeSave(tx *gorm.DB) (err error) {
+	if s.BlueGolfSeason == "" || s.BlueGolfClub == "" {
+		if site, err := parseBlueGolfSite(s.SeasonStandingsUrl); err == nil {
+			if s.BlueGolfClub == "" {
+				s.BlueGolfClub = site.Club
+			}
+			if s.BlueGolfSeason == "" {
+				s.BlueGolfSeason = site.Season
+			}
+		}
+	}
+	return nil
 }
 
 type DisabledGolfer struct {
@@ -176,6 +313,18 @@ type MatchPlayInfo struct {
 	RegistrationOpen bool   `json:"registrationOpen"`
 	BracketUrl       string `json:"bracketUrl"`
 	ShopifyUrl       string `json:"shopifyUrl"`
+
+	// Default course, tee and allowance used to work out handicap strokes
+	// for each match.
+	CourseID          uint   `json:"courseID"`
+	TeeID             uint   `json:"teeID"`
+	HandicapAllowance string `json:"handicapAllowance"`
+
+	// Capacity and the registration times work as they do for events.
+	Capacity             int        `json:"capacity"`
+	RegistrationOpensAt  *time.Time `json:"registrationOpensAt"`
+	RegistrationClosesAt *time.Time `json:"registrationClosesAt"`
+	RegistrationOpenedAt *time.Time `json:"registrationOpenedAt"`
 }
 
 type MatchPlayMatch struct {
@@ -187,12 +336,25 @@ type MatchPlayMatch struct {
 	Winner   string `json:"winner"` // Optional: empty if not yet played
 	Score    string `json:"score"`
 	MatchNum int    `json:"matchNum"` // Optional: for ordering within round
+	RoundNum int    `json:"roundNum"` // Set for brackets generated by the server, 1 is the first round
+	Seed1    int    `json:"seed1"`
+	Seed2    int    `json:"seed2"`
+
+	// Player reported results for generated brackets.
+	Status         string     `json:"status"` // "", reported, confirmed, disputed, forfeit or overdue
+	ReportedBy     string     `json:"reportedBy"`
+	ReportedWinner string     `json:"reportedWinner"`
+	ReportedScore  string     `json:"reportedScore"`
+	ReportedAt     *time.Time `json:"reportedAt"`
+	DisputeReason  string     `json:"disputeReason"`
+	Deadline       *time.Time `json:"deadline"`
 }
 
 type MatchPlayPlayer struct {
 	gorm.Model
-	Player   string `json:"player" gorm:"uniqueIndex"`
-	Handicap string `json:"handicap"`
+	Player     string `json:"player" gorm:"uniqueIndex"`
+	Handicap   string `json:"handicap"`
+	AccessCode string `json:"-"` // Lets the player report and confirm their own results
 }
 
 type ColonyCupInfo struct {
@@ -200,6 +362,39 @@ type ColonyCupInfo struct {
 	Year        string         `json:"year" gorm:"uniqueIndex"`
 	Team        datatypes.JSON `gorm:"type:json" json:"team"`
 	WinningTeam bool           `json:"winningTeam"`
+	// Drafts are hidden from the public until published by hand or at
+	// PublishAt.
+	Draft     bool       `json:"draft"`
+	PublishAt *time.Time `json:"publishAt"`
+}
+
+// ColonyCupTeam is one side's roster for a year's Colony Cup. Side 1 plays as
+// team1 and side 2 as team2 in the results.
+type ColonyCupTeam struct {
+	gorm.Model
+	Year    string            `json:"year" gorm:"uniqueIndex:idx_colony_cup_team_side"`
+	Side    int               `json:"side" gorm:"uniqueIndex:idx_colony_cup_team_side"`
+	Name    string            `json:"name"`
+	Captain string            `json:"captain"`
+	Players []ColonyCupPlayer `json:"players" gorm:"foreignKey:TeamID;constraint:OnDelete:CASCADE"`
+}
+
+type ColonyCupPlayer struct {
+	gorm.Model
+	TeamID   uint   `json:"-" gorm:"index"`
+	Player   string `json:"player"`
+	Handicap string `json:"handicap"`
+}
+
+// ColonyCupSession is a round of matches at a Colony Cup event, such as the
+// morning best ball. Results are grouped under the session's name.
+type ColonyCupSession struct {
+	gorm.Model
+	EventID string  `json:"eventID" gorm:"index"`
+	Name    string  `json:"name"`
+	Format  string  `json:"format"` // best-ball, alternate-shot, scramble, shamble or singles
+	Points  float64 `json:"points"` // Points for each match
+	Order   int     `json:"order"`
 }
 
 type PastChampion struct {
@@ -207,6 +402,10 @@ type PastChampion struct {
 	Year      string `json:"year" gorm:"uniqueIndex"`
 	Player    string `json:"player"`
 	Thumbnail string `json:"thumbnail"`
+	// A draft champion is announced once Draft is cleared or PublishAt
+	// passes.
+	Draft     bool       `json:"draft"`
+	PublishAt *time.Time `json:"publishAt"`
 }
 
 type SeasonRank struct {
@@ -229,6 +428,70 @@ type WGRRank struct {
 	User   string `json:"user"`
 }
 
+type FeedEntry struct {
+	gorm.Model
+	Kind    string `json:"kind" gorm:"index"` // "results", "standings", "match-play" or "champion"
+	Key     string `json:"key"`               // eventID or year the entry refers to
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Link    string `json:"link"`
+	// Fingerprint identifies the data the entry was published for, so
+	// downloading the same results again doesn't publish them twice.
+	Fingerprint string `json:"-"`
+}
+
+type Webhook struct {
+	gorm.Model
+	Url         string                      `json:"url"`
+	Description string                      `json:"description"`
+	Secret      string                      `json:"secret"`
+	Events      datatypes.JSONSlice[string] `json:"events"` // Subscribed event types, e.g. "results.updated"
+	Active      bool                        `json:"active"`
+}
+
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID     uint       `json:"webhookID" gorm:"index"`
+	DeliveryID    string     `json:"deliveryID" gorm:"uniqueIndex"`
+	EventType     string     `json:"eventType"`
+	Payload       string     `json:"payload"`
+	Attempts      int        `json:"attempts"`
+	Status        string     `json:"status" gorm:"index"` // "pending", "delivered" or "failed"
+	NextAttemptAt time.Time  `json:"nextAttemptAt" gorm:"index"`
+	DeliveredAt   *time.Time `json:"deliveredAt"`
+}
+
+type WebhookAttempt struct {
+	gorm.Model
+	DeliveryID string `json:"deliveryID" gorm:"index"`
+	Attempt    int    `json:"attempt"`
+	StatusCode int    `json:"statusCode"`
+	Error      string `json:"error"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+type EmailSubscription struct {
+	gorm.Model
+	Email            string     `json:"email" gorm:"uniqueIndex"`
+	Confirmed        bool       `json:"confirmed"`
+	ConfirmedAt      *time.Time `json:"confirmedAt"`
+	ConfirmToken     string     `json:"-" gorm:"index"`
+	ConfirmSentAt    *time.Time `json:"-"`
+	UnsubscribeToken string     `json:"-" gorm:"index"`
+}
+
+type EmailDigest struct {
+	gorm.Model
+	EventID   string    `json:"eventID" gorm:"index"`
+	Status    string    `json:"status" gorm:"index"` // "pending", "sent" or "failed"
+	SendAfter time.Time `json:"sendAfter"`
+	// Season ranks (player -> rank) at the time results were posted, used
+	// to report standings movement once the standings have been refreshed.
+	PreviousRanks datatypes.JSONType[map[string]string] `json:"previousRanks"`
+	Recipients    int                                   `json:"recipients"`
+	Error         string                                `json:"error"`
+}
+
 type Tournament struct {
 	gorm.Model
 	Year       string `json:"year"`
@@ -243,8 +506,102 @@ type Tournament struct {
 }
 
 type TeeTime struct {
-	Round   int      `json:"round"`
-	Time    string   `json:"time"`
-	Hole    string   `json:"hole"`
-	Players []string `json:"players"`
+	ID       uint                        `json:"-" gorm:"primaryKey"`
+	EventID  string                      `json:"-" gorm:"index"`
+	Position int                         `json:"-"` // Order on the pairings sheet
+	Round    int                         `json:"round"`
+	Time     string                      `json:"time"`
+	Hole     string                      `json:"hole"`
+	Players  datatypes.JSONSlice[string] `json:"players"`
+}
+
+// BlueGolfSync records when one of an event's BlueGolf pages was last
+// scraped and whether that failed.
+type BlueGolfSync struct {
+	EventID   string    `json:"eventID" gorm:"uniqueIndex"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Error     string    `json:"error"`
+}
+
+// TeeTimeSync records when an event's tee times were last checked against
+// BlueGolf.
+type TeeTimeSync struct {
+	gorm.Model
+	BlueGolfSync
+	ETag      string     `json:"etag"`
+	ChangedAt *time.Time `json:"changedAt"`
+}
+
+// TeeTimeChange is a player's tee time being added, removed or moved.
+type TeeTimeChange struct {
+	gorm.Model
+	EventID string `json:"eventID" gorm:"index"`
+	Kind    string `json:"kind"` // added, removed or moved
+	Round   int    `json:"round"`
+	Player  string `json:"player"`
+	OldTime string `json:"oldTime"`
+	OldHole string `json:"oldHole"`
+	NewTime string `json:"newTime"`
+	NewHole string `json:"newHole"`
+}
+
+// EventRegistration is a player in an event's field. Players who drop out
+// of the field keep their row with WithdrawnAt set.
+type EventRegistration struct {
+	gorm.Model
+	EventID      string     `json:"eventID" gorm:"uniqueIndex:idx_event_registration"`
+	Player       string     `json:"player" gorm:"uniqueIndex:idx_event_registration"`
+	BlueGolfID   string     `json:"blueGolfID"`
+	RegisteredAt time.Time  `json:"registeredAt"`
+	WithdrawnAt  *time.Time `json:"withdrawnAt,omitempty"`
+	// Waitlisted is set while the player is on the event's waitlist rather
+	// than in the field.
+	Waitlisted bool `json:"waitlisted"`
+	// Disabled is set while the player is on the disabled golfer list.
+	Disabled       bool   `json:"disabled"`
+	DisabledReason string `json:"disabledReason,omitempty"`
+}
+
+// EventFieldSync records when an event's field was last checked.
+type EventFieldSync struct {
+	gorm.Model
+	BlueGolfSync
+}
+
+// Registration is an entry bought through Shopify for an event or the
+// season's match play. There is one per order line item.
+type Registration struct {
+	gorm.Model
+	EventID        string `json:"eventID,omitempty" gorm:"index"`
+	MatchPlayYear  string `json:"matchPlayYear,omitempty" gorm:"index"`
+	Player         string `json:"player"`
+	Email          string `json:"email"`
+	Quantity       int    `json:"quantity"`
+	ShopifyOrderID int64  `json:"shopifyOrderID" gorm:"uniqueIndex:idx_registration_line"`
+	ShopifyLineID  int64  `json:"shopifyLineID" gorm:"uniqueIndex:idx_registration_line"`
+	PaymentStatus  string `json:"paymentStatus"` // Shopify's financial status
+	// ShopifyUpdatedAt is the updated_at of the order payload last applied.
+	ShopifyUpdatedAt *time.Time `json:"-"`
+	CancelledAt      *time.Time `json:"cancelledAt,omitempty"`
+	Status           string     `json:"status"` // registered or waitlisted
+	PromotedAt       *time.Time `json:"promotedAt,omitempty"`
+}
+
+// ShopifyProduct maps a Shopify product to the event or match play season
+// it registers players for.
+type ShopifyProduct struct {
+	gorm.Model
+	ProductID     int64  `json:"productID" gorm:"uniqueIndex"`
+	EventID       string `json:"eventID,omitempty"`
+	MatchPlayYear string `json:"matchPlayYear,omitempty"`
+}
+
+// EventTransition records an event moving from one status to another.
+type EventTransition struct {
+	gorm.Model
+	EventID string    `json:"eventID" gorm:"index"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	At      time.Time `json:"at"`
+	Note    string    `json:"note,omitempty"`
 }
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cpacia/lfg-server/handicap"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type HoleStroke struct {
+	Hole     int `json:"hole"`
+	Par      int `json:"par"`
+	Handicap int `json:"handicap"`
+	Strokes  int `json:"strokes"`
+}
+
+// PlayerHandicap is a player's handicap for an event from its tee, after the
+// event's handicap allowance.
+type PlayerHandicap struct {
+	Player          string       `json:"player"`
+	Index           *float64     `json:"index"`
+	CourseHandicap  int          `json:"courseHandicap"`
+	PlayingHandicap int          `json:"playingHandicap"`
+	Strokes         []HoleStroke `json:"strokes,omitempty"`
+}
+
+type ComputedNetResult struct {
+	Rank            string  `json:"rank"`
+	Player          string  `json:"player"`
+	Index           float64 `json:"index"`
+	CourseHandicap  int     `json:"courseHandicap"`
+	PlayingHandicap int     `json:"playingHandicap"`
+	Rounds          int     `json:"rounds"`
+	Gross           int     `json:"gross"`
+	Net             int     `json:"net"`
+	ToPar           int     `json:"toPar"`
+	ScrapedNet      string  `json:"scrapedNet,omitempty"`
+	Matches         *bool   `json:"matches,omitempty"`
+	Difference      *int    `json:"difference,omitempty"`
+}
+
+// holeStrokes allocates a playing handicap over a tee's holes.
+func holeStrokes(tee *CourseTee, playingHandicap int) []HoleStroke {
+	strokes := make([]HoleStroke, 0, len(tee.Holes))
+	for _, h := range tee.Holes {
+		strokes = append(strokes, HoleStroke{
+			Hole:     h.Hole,
+			Par:      h.Par,
+			Handicap: h.Handicap,
+			Strokes:  handicap.Strokes(playingHandicap, h.Handicap, len(tee.Holes)),
+		})
+	}
+	return strokes
+}
+
+// playerHandicap calculates a player's course and playing handicap for an
+// event using the index they held going into it. Index is nil when the
+// player has no established handicap.
+func (s *Server) playerHandicap(event *Event, tee *CourseTee, allowance float64, player string) PlayerHandicap {
+	ph := PlayerHandicap{Player: player}
+	idx, ok := handicapIndexBefore(s.db, player, event.DateString)
+	if !ok {
+		return ph
+	}
+	ph.Index = &idx
+	ph.CourseHandicap = handicap.CourseHandicap(idx, tee.Slope, tee.Rating, tee.Par)
+	ph.PlayingHandicap = handicap.PlayingHandicap(ph.CourseHandicap, allowance)
+	return ph
+}
+
+// eventPlayers returns everyone with a scorecard or net result for an event.
+func (s *Server) eventPlayers(eventID string) ([]string, error) {
+	var fromCards, fromNet []string
+	if err := s.db.Model(&Scorecard{}).Where("event_id = ?", eventID).Distinct().Pluck("player", &fromCards).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&NetResult{}).Where("event_id = ?", eventID).Distinct().Pluck("player", &fromNet).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var players []string
+	for _, p := range append(fromCards, fromNet...) {
+		if p == "" || seen[strings.ToLower(p)] {
+			continue
+		}
+		seen[strings.ToLower(p)] = true
+		players = append(players, p)
+	}
+	sort.Strings(players)
+	return players, nil
+}
+
+// loadEventForHandicaps loads an event the requester can see with its tee
+// and parsed allowance, writing the error response when either is
+// unavailable.
+func (s *Server) loadEventForHandicaps(w http.ResponseWriter, r *http.Request) (*Event, *CourseTee, float64, bool) {
+	var event Event
+	if err := s.visibleEvents(r).First(&event, "event_id = ?", chi.URLParam(r, "eventID")).Error; err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return nil, nil, 0, false
+	}
+	_, tee, err := s.loadEventTee(&event)
+	if err != nil {
+		if errors.Is(err, errNoEventTee) {
+			http.Error(w, "Event is not linked to a course and tee", http.StatusConflict)
+		} else {
+			http.Error(w, "Error loading course", http.StatusInternalServerError)
+		}
+		return nil, nil, 0, false
+	}
+	allowance, err := handicap.ParseAllowance(event.HandicapAllowance)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return nil, nil, 0, false
+	}
+	return &event, tee, allowance, true
+}
+
+// computeNetResults scores every complete scorecard for an event from the
+// players' handicap indexes and compares the totals with the scraped net
+// leaderboard. Players without an index are returned separately.
+func (s *Server) computeNetResults(event *Event, tee *CourseTee, allowance float64) ([]ComputedNetResult, []string, error) {
+	cards, err := s.loadScorecards([]string{event.EventID})
+	if err != nil {
+		return nil, nil, err
+	}
+	var netResults []NetResult
+	if err := s.db.Where("event_id = ?", event.EventID).Find(&netResults).Error; err != nil {
+		return nil, nil, err
+	}
+	scraped := make(map[string]string)
+	for _, nr := range netResults {
+		scraped[strings.ToLower(nr.Player)] = nr.Strokes
+	}
+
+	byPlayer := make(map[string]*ComputedNetResult)
+	var order []string
+	missing := []string{}
+	incomplete := make(map[string]bool)
+	for _, card := range cards {
+		key := strings.ToLower(card.Player)
+		if incomplete[key] {
+			continue
+		}
+		res, ok := byPlayer[key]
+		if !ok {
+			ph := s.playerHandicap(event, tee, allowance, card.Player)
+			if ph.Index == nil {
+				missing = append(missing, card.Player)
+				incomplete[key] = true
+				continue
+			}
+			res = &ComputedNetResult{
+				Player:          card.Player,
+				Index:           *ph.Index,
+				CourseHandicap:  ph.CourseHandicap,
+				PlayingHandicap: ph.PlayingHandicap,
+			}
+			byPlayer[key] = res
+			order = append(order, key)
+		}
+
+		holes := make([]handicap.Hole, len(tee.Holes))
+		for i, th := range tee.Holes {
+			holes[i] = handicap.Hole{Par: th.Par, StrokeIndex: th.Handicap}
+		}
+		played := 0
+		for _, hs := range card.Holes {
+			if hs.Hole >= 1 && hs.Hole <= len(holes) && hs.Gross > 0 {
+				holes[hs.Hole-1].Gross = hs.Gross
+				played++
+			}
+		}
+		if played != len(holes) {
+			// Only complete rounds can be compared with the leaderboard.
+			delete(byPlayer, key)
+			incomplete[key] = true
+			continue
+		}
+		res.Rounds++
+		res.Gross += card.Gross
+		res.Net += handicap.NetScore(holes, res.PlayingHandicap)
+		res.ToPar = res.Net - tee.Par*res.Rounds
+	}
+
+	results := []ComputedNetResult{}
+	for _, key := range order {
+		res, ok := byPlayer[key]
+		if !ok {
+			continue
+		}
+		if total, ok := scraped[key]; ok && total != "" {
+			res.ScrapedNet = total
+			if n, err := strconv.Atoi(strings.TrimSpace(total)); err == nil {
+				diff := res.Net - n
+				matches := diff == 0
+				res.Difference = &diff
+				res.Matches = &matches
+			}
+		}
+		results = append(results, *res)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Net != results[j].Net {
+			return results[i].Net < results[j].Net
+		}
+		return results[i].Player < results[j].Player
+	})
+	for i := range results {
+		pos := i
+		for pos > 0 && results[pos-1].Net == results[i].Net {
+			pos--
+		}
+		tied := (i+1 < len(results) && results[i+1].Net == results[i].Net) || pos != i
+		results[i].Rank = strconv.Itoa(pos + 1)
+		if tied {
+			results[i].Rank = "T" + results[i].Rank
+		}
+	}
+	sort.Strings(missing)
+	return results, missing, nil
+}
+
+// GET /api/events/{eventID}/handicaps
+// Optional ?player= limits the response to one player.
+func (s *Server) GETEventHandicaps(w http.ResponseWriter, r *http.Request) {
+	event, tee, allowance, ok := s.loadEventForHandicaps(w, r)
+	if !ok {
+		return
+	}
+
+	players := []string{r.URL.Query().Get("player")}
+	if players[0] == "" {
+		var err error
+		if players, err = s.eventPlayers(event.EventID); err != nil {
+			http.Error(w, "Error fetching players", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	handicaps := []PlayerHandicap{}
+	for _, p := range players {
+		ph := s.playerHandicap(event, tee, allowance, p)
+		if ph.Index != nil {
+			ph.Strokes = holeStrokes(tee, ph.PlayingHandicap)
+		}
+		handicaps = append(handicaps, ph)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"eventID":   event.EventID,
+		"tee":       tee.Name,
+		"rating":    tee.Rating,
+		"slope":     tee.Slope,
+		"par":       tee.Par,
+		"allowance": allowance,
+		"players":   handicaps,
+	})
+}
+
+// GET /api/results/net/{eventID}/computed
+func (s *Server) GETComputedNetResults(w http.ResponseWriter, r *http.Request) {
+	event, tee, allowance, ok := s.loadEventForHandicaps(w, r)
+	if !ok {
+		return
+	}
+
+	results, missing, err := s.computeNetResults(event, tee, allowance)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error computing net results: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	mismatches := 0
+	for _, res := range results {
+		if res.Matches != nil && !*res.Matches {
+			mismatches++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"eventID":    event.EventID,
+		"allowance":  allowance,
+		"results":    results,
+		"noHandicap": missing,
+		"mismatches": mismatches,
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestServer_computeNetResults(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	course := testCourse()
+	assert.NoError(t, validateCourse(&course))
+	assert.NoError(t, db.Create(&course).Error)
+	tee := course.Tees[0]
+
+	event := &Event{Name: "Impact Fire Open", DateString: "2025-06-14", HandicapAllowance: "90%", CourseID: course.ID, TeeID: tee.ID}
+	assert.NoError(t, db.Create(event).Error)
+
+	// Course handicaps: 14.3 -> 15, 5.0 -> 4. Playing handicaps at 90%: 14 and 4.
+	assert.NoError(t, db.Create(&[]HandicapRevision{
+		{Player: "Connor Shaw", Date: "2025-06-01", Index: 14.3},
+		{Player: "Andy Lee", Date: "2025-06-01", Index: 5.0},
+		{Player: "Andy Lee", Date: "2025-06-20", Index: 1.0},
+	}).Error)
+
+	card := func(player string, over int) Scorecard {
+		holes := make([]HoleScore, 18)
+		gross := 0
+		for i, h := range tee.Holes {
+			holes[i] = HoleScore{Hole: h.Hole, Par: h.Par, Gross: h.Par}
+			if i < over {
+				holes[i].Gross++
+			}
+			gross += holes[i].Gross
+		}
+		return Scorecard{EventID: event.EventID, Player: player, Round: 1, Gross: gross, Holes: holes}
+	}
+	for _, sc := range []Scorecard{card("Connor Shaw", 18), card("Andy Lee", 9), card("Jim Tokanel", 3)} {
+		assert.NoError(t, db.Create(&sc).Error)
+	}
+	assert.NoError(t, db.Create(&[]NetResult{
+		{EventID: event.EventID, Player: "Connor Shaw", Strokes: "76"},
+		{EventID: event.EventID, Player: "Andy Lee", Strokes: "76"},
+	}).Error)
+
+	results, missing, err := s.computeNetResults(event, &tee, 0.9)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Jim Tokanel"}, missing)
+	assert.Len(t, results, 2)
+
+	assert.Equal(t, "Connor Shaw", results[0].Player)
+	assert.Equal(t, "1", results[0].Rank)
+	assert.Equal(t, 15, results[0].CourseHandicap)
+	assert.Equal(t, 14, results[0].PlayingHandicap)
+	assert.Equal(t, 90, results[0].Gross)
+	assert.Equal(t, 76, results[0].Net)
+	assert.Equal(t, 4, results[0].ToPar)
+	assert.True(t, *results[0].Matches)
+
+	// Andy's later index is ignored.
+	assert.Equal(t, "Andy Lee", results[1].Player)
+	assert.Equal(t, 4, results[1].CourseHandicap)
+	assert.Equal(t, 4, results[1].PlayingHandicap)
+	assert.Equal(t, 77, results[1].Net)
+	assert.False(t, *results[1].Matches)
+	assert.Equal(t, 1, *results[1].Difference)
+}
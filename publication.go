@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"time"
+)
+
+const publicationPollInterval = time.Minute
+
+// publishedEvents scopes a query to events the public can see: anything
+// that isn't a draft. Rows saved before statuses existed have none.
+func (s *Server) publishedEvents() *gorm.DB {
+	return s.db.Where("COALESCE(status, '') <> ?", eventDraft)
+}
+
+// visibleEvents scopes a query to the events the requester can see. Admins
+// see drafts too.
+func (s *Server) visibleEvents(r *http.Request) *gorm.DB {
+	if authenticated(r) {
+		return s.db
+	}
+	return s.publishedEvents()
+}
+
+// visibleDrafts does the same for champions and Colony Cup years, which
+// mark drafts with a flag.
+func (s *Server) visibleDrafts(r *http.Request) *gorm.DB {
+	if authenticated(r) {
+		return s.db
+	}
+	return s.db.Where("draft IS NULL OR draft = ?", false)
+}
+
+// loadVisibleEvent loads an event the requester can see, writing a 404
+// when there is none.
+func (s *Server) loadVisibleEvent(w http.ResponseWriter, r *http.Request, eventID string) (*Event, bool) {
+	var event Event
+	if err := s.visibleEvents(r).First(&event, "event_id = ?", eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Event not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return &event, true
+}
+
+// cacheResults lets shared caches keep an event's results. Only admins see
+// a draft's, so those are never stored.
+func cacheResults(w http.ResponseWriter, event *Event) {
+	if event.Status == eventDraft {
+		w.Header().Set("Cache-Control", "private, no-store")
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+}
+
+// colonyCupDrafts is what the requester can't see of the Colony Cup: years
+// saved as drafts and Colony Cup rounds played at draft events. Event IDs
+// start with their year.
+type colonyCupDrafts struct {
+	years  map[string]bool
+	events map[string]bool
+}
+
+func (d *colonyCupDrafts) hidesYear(year string) bool {
+	return d.years[year]
+}
+
+func (d *colonyCupDrafts) hidesEvent(eventID string) bool {
+	return d.events[eventID] || (len(eventID) >= 4 && d.years[eventID[:4]])
+}
+
+// loadColonyCupDrafts loads the Colony Cup drafts hidden from the requester.
+// Admins see everything.
+func (s *Server) loadColonyCupDrafts(r *http.Request) (*colonyCupDrafts, error) {
+	d := &colonyCupDrafts{years: make(map[string]bool), events: make(map[string]bool)}
+	if authenticated(r) {
+		return d, nil
+	}
+	var years, events []string
+	if err := s.db.Model(&ColonyCupInfo{}).Where("draft = ?", true).Pluck("year", &years).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&Event{}).Where("status = ?", eventDraft).Pluck("event_id", &events).Error; err != nil {
+		return nil, err
+	}
+	for _, y := range years {
+		d.years[y] = true
+	}
+	for _, id := range events {
+		d.events[id] = true
+	}
+	return d, nil
+}
+
+// colonyCupEventHidden writes a 404 when the requester can't see a Colony
+// Cup event's results.
+func (s *Server) colonyCupEventHidden(w http.ResponseWriter, r *http.Request, eventID string) bool {
+	drafts, err := s.loadColonyCupDrafts(r)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return true
+	}
+	if drafts.hidesEvent(eventID) {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return true
+	}
+	return false
+}
+
+// publishScheduled publishes drafts whose publish time has passed. Events
+// are announced, which posts them to the feed and webhooks like a new
+// event.
+func (s *Server) publishScheduled(now time.Time) {
+	var events []Event
+	if err := s.db.Where("status = ? AND publish_at IS NOT NULL", eventDraft).Find(&events).Error; err != nil {
+		log.Printf("error loading scheduled events: %s", err)
+		return
+	}
+	for i := range events {
+		if now.Before(*events[i].PublishAt) {
+			continue
+		}
+		if err := s.setEventStatus(&events[i], eventAnnounced, "Published as scheduled"); err != nil {
+			log.Printf("error publishing %s: %s", events[i].EventID, err)
+		}
+	}
+
+	var champs []PastChampion
+	if err := s.db.Where("draft = ? AND publish_at IS NOT NULL", true).Find(&champs).Error; err != nil {
+		log.Printf("error loading scheduled champions: %s", err)
+		return
+	}
+	for i := range champs {
+		if now.Before(*champs[i].PublishAt) {
+			continue
+		}
+		if err := s.db.Model(&champs[i]).Update("draft", false).Error; err != nil {
+			log.Printf("error publishing %s champion: %s", champs[i].Year, err)
+			continue
+		}
+		s.publishChampion(&champs[i])
+	}
+
+	var infos []ColonyCupInfo
+	if err := s.db.Where("draft = ? AND publish_at IS NOT NULL", true).Find(&infos).Error; err != nil {
+		log.Printf("error loading scheduled Colony Cup years: %s", err)
+		return
+	}
+	for i := range infos {
+		if now.Before(*infos[i].PublishAt) {
+			continue
+		}
+		if err := s.db.Model(&infos[i]).Update("draft", false).Error; err != nil {
+			log.Printf("error publishing %s Colony Cup: %s", infos[i].Year, err)
+		}
+	}
+}
+
+func (s *Server) runPublicationWorker() {
+	ticker := time.NewTicker(publicationPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.publishScheduled(time.Now())
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testAdminToken signs an admin token with a test key for the rest of the
+// test.
+func testAdminToken(t *testing.T) string {
+	orig := jwtKey
+	t.Cleanup(func() { jwtKey = orig })
+	jwtKey = []byte("test key")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		Username: "admin",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString(jwtKey)
+	assert.NoError(t, err)
+	return token
+}
+
+func TestServer_drafts(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+	token := testAdminToken(t)
+
+	publishAt := time.Now().Add(time.Hour)
+	assert.NoError(t, db.Create(&Event{Name: "LFG Open", DateString: "2025-06-14"}).Error)
+	draft := &Event{Name: "LFG Open", DateString: "2026-06-13", Status: eventDraft, PublishAt: &publishAt,
+		RegistrationOpensAt: &publishAt}
+	assert.NoError(t, db.Create(draft).Error)
+	assert.NoError(t, db.Create(&PastChampion{Year: "2025", Player: "Connor Shaw"}).Error)
+	assert.NoError(t, db.Create(&PastChampion{Year: "2026", Player: "Andy Lee", Draft: true, PublishAt: &publishAt}).Error)
+
+	eventNames := func(auth bool) []string {
+		req := httptest.NewRequest(http.MethodGet, "/api/events?year=2026", nil)
+		if auth {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		s.GETEvents(rec, req)
+		if rec.Code != http.StatusOK {
+			return nil
+		}
+		var resp struct {
+			Events []Event `json:"events"`
+		}
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		var ids []string
+		for _, e := range resp.Events {
+			ids = append(ids, e.EventID)
+		}
+		return ids
+	}
+	champions := func(auth bool) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/champions", nil)
+		if auth {
+			req.AddCookie(&http.Cookie{Name: "auth_token", Value: token})
+		}
+		rec := httptest.NewRecorder()
+		s.GETChampions(rec, req)
+		var champs []PastChampion
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&champs))
+		return len(champs)
+	}
+
+	// The draft season is hidden from the public, not from admins.
+	assert.Nil(t, eventNames(false))
+	assert.Equal(t, []string{"2026-lfg-open"}, eventNames(true))
+	assert.Equal(t, 1, champions(false))
+	assert.Equal(t, 2, champions(true))
+
+	r := chi.NewRouter()
+	r.Get("/api/events/{eventID}/handicaps", s.GETEventHandicaps)
+	r.Get("/api/events/{eventID}/bluegolf", s.GETEventBlueGolf)
+	r.Get("/api/events/{eventID}/field", s.GETEventField)
+	r.Get("/api/results/net/{eventID}/computed", s.GETComputedNetResults)
+	r.Get("/api/results/holes/{eventID}", s.GETEventHoleStats)
+	r.Get("/api/results/export", s.GETSeasonResultsExport)
+	r.Get("/api/tee-times/{eventID}", s.GetTeeTimes)
+	r.Get("/api/tee-times/{eventID}/changes", s.GETTeeTimeChanges)
+	r.Get("/api/results/net/{eventID}", s.GETNetResults)
+	r.Get("/api/results/gross/{eventID}", s.GETGrossResults)
+	r.Get("/api/results/skins/{eventID}", s.GETSkinsResults)
+	r.Get("/api/results/teams/{eventID}", s.GETTeamResults)
+	r.Get("/api/results/wgr/{eventID}", s.GETWgrResults)
+	r.Get("/api/results/scorecards/{eventID}", s.GETEventScorecards)
+	r.Get("/api/results/scorecards/{eventID}/{player}", s.GETPlayerScorecard)
+	r.Get("/api/courses/{courseID}/holes", s.GETCourseHoleStats)
+	for _, path := range []string{
+		"/api/results/net/%s",
+		"/api/results/gross/%s",
+		"/api/results/skins/%s",
+		"/api/results/teams/%s",
+		"/api/results/wgr/%s",
+		"/api/results/scorecards/%s",
+		"/api/results/scorecards/%s/Andy%%20Lee",
+		"/api/events/%s/handicaps",
+		"/api/events/%s/bluegolf",
+		"/api/events/%s/field",
+		"/api/results/net/%s/computed",
+		"/api/results/holes/%s",
+		"/api/tee-times/%s",
+		"/api/tee-times/%s/changes",
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf(path, draft.EventID), nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code, path)
+	}
+	assert.NoError(t, db.Create(&NetResult{EventID: draft.EventID, Rank: "1", Player: "Andy Lee"}).Error)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/results/export?year=2026", nil))
+	assert.NotContains(t, rec.Body.String(), draft.EventID)
+
+	// Admins can read a draft's results, but caches don't keep them.
+	req := httptest.NewRequest(http.MethodGet, "/api/results/net/"+draft.EventID, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "private, no-store", rec.Header().Get("Cache-Control"))
+
+	// Course stats leave the draft out.
+	course := testCourse()
+	assert.NoError(t, db.Create(&course).Error)
+	assert.NoError(t, db.Model(&Event{}).Where("1 = 1").UpdateColumn("course_id", course.ID).Error)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/courses/%d/holes", course.ID), nil))
+	var stats HoleDifficulty
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	assert.Equal(t, []string{"2025-lfg-open"}, stats.Events)
+
+	// A draft's registration window waits until it is published.
+	s.applyRegistrationSchedule(publishAt.Add(time.Minute))
+	var loaded Event
+	assert.NoError(t, db.First(&loaded, "event_id = ?", draft.EventID).Error)
+	assert.False(t, loaded.RegistrationOpen)
+
+	// Nothing about the draft reaches the feed before it is published.
+	s.publishResults(draft.EventID)
+	s.publishTeeTimes(draft, true, nil)
+	var count int64
+	assert.NoError(t, db.Model(&FeedEntry{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	s.publishScheduled(time.Now())
+	assert.Nil(t, eventNames(false))
+
+	s.publishScheduled(publishAt.Add(time.Minute))
+	assert.Equal(t, []string{"2026-lfg-open"}, eventNames(false))
+	assert.Equal(t, 2, champions(false))
+
+	var published Event
+	assert.NoError(t, db.First(&published, "event_id = ?", draft.EventID).Error)
+	assert.Equal(t, eventAnnounced, published.Status)
+
+	var entries []FeedEntry
+	assert.NoError(t, db.Find(&entries).Error)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "champion", entries[0].Kind)
+}
+
+func TestServer_colonyCupDrafts(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db, liveColonyCup: newColonyCupHub()}
+	token := testAdminToken(t)
+
+	// 2025 is published, 2026 is a draft year and the invitational is a
+	// draft event in a published year.
+	assert.NoError(t, db.Create(&ColonyCupInfo{Year: "2025"}).Error)
+	assert.NoError(t, db.Create(&ColonyCupInfo{Year: "2026", Draft: true}).Error)
+	events := []*Event{
+		{Name: "Colony Cup", DateString: "2025-09-13"},
+		{Name: "Colony Cup", DateString: "2026-09-12"},
+		{Name: "Colony Cup Invitational", DateString: "2025-10-04", Status: eventDraft},
+	}
+	for _, e := range events {
+		assert.NoError(t, db.Create(e).Error)
+		assert.NoError(t, db.Create(&ColonyCupResult{EventID: e.EventID, EventName: "Singles", TeamOne: "Connor Shaw", TeamTwo: "Andy Lee", Winner: "Connor Shaw", Score: "2&1"}).Error)
+		assert.NoError(t, db.Create(&ColonyCupSession{EventID: e.EventID, Name: "Singles", Format: formatSingles, Points: 1}).Error)
+	}
+	for _, year := range []string{"2025", "2026"} {
+		assert.NoError(t, db.Create(&ColonyCupTeam{Year: year, Side: 1, Name: "Shaw", Players: []ColonyCupPlayer{{Player: "Connor Shaw"}}}).Error)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/api/results/colony-cup/{eventID}", s.GETColonyCupResults)
+	r.Get("/api/results/colony-cup/{eventID}/live", s.GETColonyCupScoreboard)
+	r.Get("/api/results/colony-cup/{eventID}/stream", s.GETColonyCupStream)
+	r.Get("/api/results/colony-cup/{eventID}/sessions", s.GETColonyCupSessions)
+	r.Get("/api/colony-cup/teams", s.GETColonyCupTeams)
+	r.Get("/api/colony-cup/records", s.GETColonyCupRecords)
+	get := func(path string, auth bool) *httptest.ResponseRecorder {
+		// The stream stays open until the request is cancelled.
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+		if auth {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, e := range events {
+		for _, path := range []string{
+			"/api/results/colony-cup/%s",
+			"/api/results/colony-cup/%s/live",
+			"/api/results/colony-cup/%s/stream",
+			"/api/results/colony-cup/%s/sessions",
+		} {
+			path = fmt.Sprintf(path, e.EventID)
+			want := http.StatusNotFound
+			if e == events[0] {
+				want = http.StatusOK
+			}
+			assert.Equal(t, want, get(path, false).Code, path)
+			assert.Equal(t, http.StatusOK, get(path, true).Code, path)
+		}
+	}
+
+	assert.Equal(t, http.StatusOK, get("/api/colony-cup/teams?year=2025", false).Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/colony-cup/teams?year=2026", false).Code)
+	assert.Equal(t, http.StatusOK, get("/api/colony-cup/teams?year=2026", true).Code)
+
+	appearances := func(auth bool) int {
+		rec := get("/api/colony-cup/records?player=Connor%20Shaw", auth)
+		var record ColonyCupRecord
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&record))
+		return record.Appearances
+	}
+	assert.Equal(t, 1, appearances(false))
+	assert.Equal(t, 3, appearances(true))
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	registrationRegistered = "registered"
+	registrationWaitlisted = "waitlisted"
+
+	registrationPollInterval = time.Minute
+)
+
+// registrationTarget is what a registration is for: an event or a season's
+// match play.
+type registrationTarget struct {
+	EventID       string
+	MatchPlayYear string
+}
+
+func (t registrationTarget) scope(db *gorm.DB) *gorm.DB {
+	if t.EventID != "" {
+		return db.Where("event_id = ?", t.EventID)
+	}
+	return db.Where("match_play_year = ?", t.MatchPlayYear)
+}
+
+func (t registrationTarget) extra() map[string]any {
+	if t.EventID != "" {
+		return map[string]any{"eventID": t.EventID}
+	}
+	return map[string]any{"matchPlayYear": t.MatchPlayYear}
+}
+
+// registrationState is the registration settings of an event or a season's
+// match play.
+type registrationState struct {
+	Target     registrationTarget
+	Name       string
+	ShopifyUrl string
+	Open       bool
+	Capacity   int
+	OpensAt    *time.Time
+	ClosesAt   *time.Time
+	OpenedAt   *time.Time
+	model      any
+}
+
+func eventRegistrationState(e *Event) *registrationState {
+	return &registrationState{registrationTarget{EventID: e.EventID}, e.Name, e.ShopifyUrl,
+		e.RegistrationOpen, e.Capacity, e.RegistrationOpensAt, e.RegistrationClosesAt, e.RegistrationOpenedAt, e}
+}
+
+func matchPlayRegistrationState(m *MatchPlayInfo) *registrationState {
+	return &registrationState{registrationTarget{MatchPlayYear: m.Year}, m.Year + " Match Play", m.ShopifyUrl,
+		m.RegistrationOpen, m.Capacity, m.RegistrationOpensAt, m.RegistrationClosesAt, m.RegistrationOpenedAt, m}
+}
+
+func loadRegistrationState(db *gorm.DB, t registrationTarget) (*registrationState, error) {
+	if t.EventID != "" {
+		var event Event
+		if err := db.First(&event, "event_id = ?", t.EventID).Error; err != nil {
+			return nil, err
+		}
+		return eventRegistrationState(&event), nil
+	}
+	var info MatchPlayInfo
+	if err := db.First(&info, "year = ?", t.MatchPlayYear).Error; err != nil {
+		return nil, err
+	}
+	return matchPlayRegistrationState(&info), nil
+}
+
+func (st *registrationState) setOpen(db *gorm.DB, open bool) error {
+	st.Open = open
+	if e, ok := st.model.(*Event); ok {
+		return setEventRegistrationOpen(db, e, open, time.Now())
+	}
+	return db.Model(st.model).Update("registration_open", open).Error
+}
+
+// registrationActive reports whether a registration still stands: it
+// hasn't been cancelled, refunded or voided.
+func registrationActive(r *Registration) bool {
+	return r.CancelledAt == nil && r.PaymentStatus != "refunded" && r.PaymentStatus != "voided"
+}
+
+// registrationCounted reports whether a registration holds a spot.
+func registrationCounted(r *Registration) bool {
+	return registrationActive(r) && r.Status != registrationWaitlisted
+}
+
+// countRegistrations adds up the spots held by registrations.
+func countRegistrations(regs []Registration) int {
+	count := 0
+	for i := range regs {
+		if registrationCounted(&regs[i]) {
+			count += regs[i].Quantity
+		}
+	}
+	return count
+}
+
+// registrationCount is the number of spots taken for an event or match
+// play season.
+func registrationCount(db *gorm.DB, t registrationTarget) (int, error) {
+	var regs []Registration
+	if err := t.scope(db).Find(&regs).Error; err != nil {
+		return 0, err
+	}
+	return countRegistrations(regs), nil
+}
+
+// registrationFull reports whether there is no room for another quantity
+// spots. Nobody takes a spot while others are waiting for one, even if
+// their order would fit.
+func registrationFull(db *gorm.DB, t registrationTarget, quantity int) (bool, error) {
+	st, err := loadRegistrationState(db, t)
+	if err != nil || st.Capacity <= 0 {
+		return false, err
+	}
+	count, err := registrationCount(db, t)
+	if err != nil {
+		return false, err
+	}
+	if count+quantity > st.Capacity {
+		return true, nil
+	}
+	queue, err := waitlist(db, t)
+	if err != nil {
+		return false, err
+	}
+	return len(queue) > 0, nil
+}
+
+// waitlist is the active waitlisted registrations in the order they joined.
+func waitlist(db *gorm.DB, t registrationTarget) ([]Registration, error) {
+	var regs []Registration
+	if err := t.scope(db).Where("status = ?", registrationWaitlisted).Order("created_at ASC, id ASC").Find(&regs).Error; err != nil {
+		return nil, err
+	}
+	active := regs[:0]
+	for _, reg := range regs {
+		if registrationActive(&reg) {
+			active = append(active, reg)
+		}
+	}
+	return active, nil
+}
+
+// promoteWaitlist moves players off the waitlist in the order they joined
+// for as long as their spots fit. Nobody skips ahead of a larger order.
+func promoteWaitlist(db *gorm.DB, t registrationTarget, now time.Time) ([]Registration, error) {
+	st, err := loadRegistrationState(db, t)
+	if err != nil {
+		return nil, err
+	}
+	count, err := registrationCount(db, t)
+	if err != nil {
+		return nil, err
+	}
+	queue, err := waitlist(db, t)
+	if err != nil {
+		return nil, err
+	}
+
+	var promoted []Registration
+	for _, reg := range queue {
+		if st.Capacity > 0 && count+reg.Quantity > st.Capacity {
+			break
+		}
+		reg.Status = registrationRegistered
+		reg.PromotedAt = &now
+		if err := db.Save(&reg).Error; err != nil {
+			return promoted, err
+		}
+		count += reg.Quantity
+		promoted = append(promoted, reg)
+	}
+	return promoted, nil
+}
+
+// settleRegistrations is called after registrations change. For each event
+// or match play season touched it promotes waitlisted players into any
+// spots that opened and closes registration once full.
+func (s *Server) settleRegistrations(regs []Registration) {
+	seen := make(map[registrationTarget]bool)
+	for _, reg := range regs {
+		t := registrationTarget{EventID: reg.EventID, MatchPlayYear: reg.MatchPlayYear}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		if err := s.settleRegistration(t); err != nil {
+			log.Printf("error settling registrations for %v: %s", t, err)
+		}
+	}
+}
+
+func (s *Server) settleRegistration(t registrationTarget) error {
+	promoted, err := promoteWaitlist(s.db, t, time.Now())
+	if err != nil {
+		return err
+	}
+	st, err := loadRegistrationState(s.db, t)
+	if err != nil {
+		return err
+	}
+	for i := range promoted {
+		s.notifyPromoted(st.Name, &promoted[i])
+	}
+
+	if !st.Open || st.Capacity <= 0 {
+		return nil
+	}
+	count, err := registrationCount(s.db, t)
+	if err != nil {
+		return err
+	}
+	if count >= st.Capacity {
+		if err := st.setOpen(s.db, false); err != nil {
+			return err
+		}
+		s.publishRegistrationClosed(st.Name, count, t.extra())
+	}
+	return nil
+}
+
+// notifyPromoted emails a player who came off the waitlist.
+func (s *Server) notifyPromoted(name string, reg *Registration) {
+	log.Printf("%s promoted from the %s waitlist", reg.Player, name)
+	if s.mailer == nil || reg.Email == "" {
+		return
+	}
+	subject := fmt.Sprintf("You're in: %s", name)
+	text := fmt.Sprintf("Hi %s,\n\nA spot opened up and you've been moved off the waitlist for %s. See you there!\n", reg.Player, name)
+	htmlBody := fmt.Sprintf("<p>Hi %s,</p><p>A spot opened up and you've been moved off the waitlist for %s. See you there!</p>",
+		html.EscapeString(reg.Player), html.EscapeString(name))
+	go func() {
+		if err := s.mailer.send(reg.Email, subject, text, htmlBody, nil); err != nil {
+			log.Printf("error emailing %s: %s", reg.Email, err)
+		}
+	}()
+}
+
+// applyRegistrationSchedule opens and closes registration whose scheduled
+// time has passed.
+func (s *Server) applyRegistrationSchedule(now time.Time) {
+	const scheduled = "registration_opens_at IS NOT NULL OR registration_closes_at IS NOT NULL"
+	var states []*registrationState
+	// Draft events follow their schedule once they are published.
+	var events []Event
+	if err := s.publishedEvents().Where(scheduled).Find(&events).Error; err != nil {
+		log.Printf("error loading events for registration: %s", err)
+		return
+	}
+	for i := range events {
+		states = append(states, eventRegistrationState(&events[i]))
+	}
+	var infos []MatchPlayInfo
+	if err := s.db.Where(scheduled).Find(&infos).Error; err != nil {
+		log.Printf("error loading match play for registration: %s", err)
+		return
+	}
+	for i := range infos {
+		states = append(states, matchPlayRegistrationState(&infos[i]))
+	}
+
+	for _, st := range states {
+		if err := s.applySchedule(st, now); err != nil {
+			log.Printf("error applying registration schedule for %s: %s", st.Name, err)
+		}
+	}
+}
+
+// applySchedule applies an opening time once, the first time it has passed.
+// Moving the opening time later schedules it again.
+func (s *Server) applySchedule(st *registrationState, now time.Time) error {
+	closed := st.ClosesAt != nil && !now.Before(*st.ClosesAt)
+	due := st.OpensAt != nil && !now.Before(*st.OpensAt)
+	if due && (st.OpenedAt == nil || st.OpenedAt.Before(*st.OpensAt)) {
+		if err := s.db.Model(st.model).UpdateColumn("registration_opened_at", now).Error; err != nil {
+			return err
+		}
+		if !st.Open && !closed {
+			if err := st.setOpen(s.db, true); err != nil {
+				return err
+			}
+			s.publishRegistrationOpened(st.Name, st.ShopifyUrl, st.Target.extra())
+		}
+	}
+	if st.Open && closed {
+		if err := st.setOpen(s.db, false); err != nil {
+			return err
+		}
+		count, err := registrationCount(s.db, st.Target)
+		if err != nil {
+			return err
+		}
+		s.publishRegistrationClosed(st.Name, count, st.Target.extra())
+	}
+	return nil
+}
+
+func (s *Server) runRegistrationWorker() {
+	ticker := time.NewTicker(registrationPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.applyRegistrationSchedule(time.Now())
+	}
+}
+
+func registrationTargetParam(r *http.Request) (registrationTarget, error) {
+	t := registrationTarget{EventID: r.URL.Query().Get("eventID"), MatchPlayYear: r.URL.Query().Get("year")}
+	if (t.EventID == "") == (t.MatchPlayYear == "") {
+		return t, fmt.Errorf("eventID or year is required")
+	}
+	return t, nil
+}
+
+// GET /api/registrations?eventID=...|year=...
+func (s *Server) GETRegistrations(w http.ResponseWriter, r *http.Request) {
+	t, err := registrationTargetParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var regs []Registration
+	if err := t.scope(s.db).Order("created_at ASC").Find(&regs).Error; err != nil {
+		http.Error(w, "Error fetching registrations", http.StatusInternalServerError)
+		return
+	}
+	queue, err := waitlist(s.db, t)
+	if err != nil {
+		http.Error(w, "Error fetching registrations", http.StatusInternalServerError)
+		return
+	}
+	if regs == nil {
+		regs = []Registration{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"registered":    countRegistrations(regs),
+		"waitlisted":    len(queue),
+		"registrations": regs,
+	})
+}
+
+// WaitlistEntry is a waitlisted registration and its place in the queue.
+type WaitlistEntry struct {
+	Position int `json:"position"`
+	Registration
+}
+
+// GET /api/registrations/waitlist?eventID=...|year=...
+func (s *Server) GETWaitlist(w http.ResponseWriter, r *http.Request) {
+	t, err := registrationTargetParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	queue, err := waitlist(s.db, t)
+	if err != nil {
+		http.Error(w, "Error fetching waitlist", http.StatusInternalServerError)
+		return
+	}
+	entries := make([]WaitlistEntry, 0, len(queue))
+	for i, reg := range queue {
+		entries = append(entries, WaitlistEntry{Position: i + 1, Registration: reg})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// POST /api/registrations/{id}/promote
+// Moves a player off the waitlist whether or not there is room.
+func (s *Server) POSTPromoteRegistration(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid registration ID", http.StatusBadRequest)
+		return
+	}
+	var reg Registration
+	if err := s.db.First(&reg, id).Error; err != nil {
+		http.Error(w, "Registration not found", http.StatusNotFound)
+		return
+	}
+	if reg.Status != registrationWaitlisted || !registrationActive(&reg) {
+		http.Error(w, "Registration is not on the waitlist", http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	reg.Status = registrationRegistered
+	reg.PromotedAt = &now
+	if err := s.db.Save(&reg).Error; err != nil {
+		http.Error(w, "Could not promote registration", http.StatusInternalServerError)
+		return
+	}
+	t := registrationTarget{EventID: reg.EventID, MatchPlayYear: reg.MatchPlayYear}
+	if st, err := loadRegistrationState(s.db, t); err == nil {
+		s.notifyPromoted(st.Name, &reg)
+	}
+	s.settleRegistrations([]Registration{reg})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reg)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// refreshScorecards scrapes the scorecards for an event after its results
+// change. Failures are logged rather than returned as scorecards are
+// supplementary to the leaderboards, and since every player's card is
+// fetched it is run in the background of admin requests.
+func (s *Server) refreshScorecards(eventID string) {
+	if err := updateScorecards(s.db, eventID); err != nil {
+		log.Printf("error updating scorecards for %s: %s", eventID, err)
+		return
+	}
+	s.refreshHandicaps(eventID)
+}
+
+// GET /api/results/scorecards/{eventID}
+func (s *Server) GETEventScorecards(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	if eventID == "" {
+		http.Error(w, "Missing event ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.loadVisibleEvent(w, r, eventID); !ok {
+		return
+	}
+
+	var scorecards []Scorecard
+	if err := s.db.Preload("Holes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("hole ASC")
+	}).Where("event_id = ?", eventID).Order("player ASC, round ASC").Find(&scorecards).Error; err != nil {
+		http.Error(w, "Error fetching scorecards", http.StatusInternalServerError)
+		return
+	}
+	if scorecards == nil {
+		scorecards = []Scorecard{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scorecards)
+}
+
+// GET /api/results/scorecards/{eventID}/{player}
+func (s *Server) GETPlayerScorecard(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	if eventID == "" {
+		http.Error(w, "Missing event ID", http.StatusBadRequest)
+		return
+	}
+	player, err := url.PathUnescape(chi.URLParam(r, "player"))
+	if err != nil {
+		http.Error(w, "Invalid name", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(player) == "" {
+		http.Error(w, "Player must be provided", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := s.loadVisibleEvent(w, r, eventID); !ok {
+		return
+	}
+
+	var scorecards []Scorecard
+	if err := s.db.Preload("Holes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("hole ASC")
+	}).Where("event_id = ? AND LOWER(player) = LOWER(?)", eventID, player).Order("round ASC").Find(&scorecards).Error; err != nil {
+		http.Error(w, "Error fetching scorecard", http.StatusInternalServerError)
+		return
+	}
+	if len(scorecards) == 0 {
+		http.Error(w, "Scorecard not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"eventID": eventID,
+		"player":  scorecards[0].Player,
+		"rounds":  scorecards,
+	})
+}
+
+// POST /api/results/scorecards/{eventID}/refresh
+func (s *Server) POSTRefreshScorecards(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	if eventID == "" {
+		http.Error(w, "Missing event ID", http.StatusBadRequest)
+		return
+	}
+	if err := updateScorecards(s.db, eventID); err != nil {
+		http.Error(w, fmt.Sprintf("Error downloading scorecards: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	s.refreshHandicaps(eventID)
+	w.WriteHeader(http.StatusOK)
+}
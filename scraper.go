@@ -234,8 +234,7 @@ func updateResultsGeneric[T any](db *gorm.DB, url string, eventID string, newRow
 			return
 		}
 
-		base := strings.TrimSuffix(url, "leaderboard.htm")
-		scorecardURL := fmt.Sprintf("%scontestant/%s/scorecard.htm", base, playerID)
+		scorecardURL := scorecardURL(url, playerID)
 
 		parts := strings.SplitN(points, ".", 2)
 		integerPoints := parts[0]
@@ -305,8 +304,7 @@ func updateSkinsResults(db *gorm.DB, url string, eventID string) error {
 			return
 		}
 
-		base := strings.TrimSuffix(url, "leaderboard.htm")
-		scorecardURL := fmt.Sprintf("%scontestant/%s/scorecard.htm", base, playerID)
+		scorecardURL := scorecardURL(url, playerID)
 
 		playerRows = append(playerRows, &SkinsPlayerResult{
 			EventID:      eventID,
@@ -963,3 +961,344 @@ func sortTeeTimes(teeTimes []TeeTime) {
 		return ti.Before(tj)
 	})
 }
+
+// scorecardValue parses a scorecard cell. A trailing dot, which some
+// scorecards use to mark holes where a handicap stroke is received ("5."),
+// is ignored so those holes still count as played.
+func scorecardValue(s string) (int, bool) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), ".")
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// parseScorecardTable reads one BlueGolf scorecard table. Columns are mapped
+// to holes through the "Hole" header row so the Out, In and Total columns are
+// ignored. Rows are identified by their label: Par, Handicap, Net Score and
+// the player's gross score row (tr.scores).
+func parseScorecardTable(table *goquery.Selection) []HoleScore {
+	holeCols := make(map[int]int)
+	var parRow, hcpRow, grossRow, netRow *goquery.Selection
+
+	table.Find("tr").Each(func(_ int, tr *goquery.Selection) {
+		cells := tr.ChildrenFiltered("td, th")
+		if cells.Length() < 2 {
+			return
+		}
+		label := strings.ToLower(strings.TrimSpace(cells.Eq(0).Text()))
+		switch {
+		case label == "hole" || label == "holes":
+			cells.Each(func(i int, c *goquery.Selection) {
+				if n, ok := scorecardValue(c.Text()); ok && n >= 1 && n <= 18 {
+					holeCols[i] = n
+				}
+			})
+		case label == "par":
+			parRow = cells
+		case strings.Contains(label, "hcp") || strings.Contains(label, "handicap") || strings.Contains(label, "hdcp"):
+			hcpRow = cells
+		case tr.HasClass("bg-enhanced-net") || strings.HasPrefix(label, "net"):
+			if netRow == nil {
+				netRow = cells
+			}
+		case tr.HasClass("scores") || label == "score" || strings.HasPrefix(label, "gross"):
+			if grossRow == nil {
+				grossRow = cells
+			}
+		}
+	})
+
+	if len(holeCols) == 0 || grossRow == nil {
+		return nil
+	}
+
+	cols := make([]int, 0, len(holeCols))
+	for i := range holeCols {
+		cols = append(cols, i)
+	}
+	sort.Ints(cols)
+
+	value := func(row *goquery.Selection, i int) int {
+		if row == nil || i >= row.Length() {
+			return 0
+		}
+		n, _ := scorecardValue(row.Eq(i).Text())
+		return n
+	}
+
+	holes := make([]HoleScore, 0, len(cols))
+	for _, i := range cols {
+		gross := value(grossRow, i)
+		if gross == 0 {
+			// Hole not played yet
+			continue
+		}
+		h := HoleScore{
+			Hole:     holeCols[i],
+			Par:      value(parRow, i),
+			Handicap: value(hcpRow, i),
+			Gross:    gross,
+			Net:      value(netRow, i),
+		}
+		if h.Net > 0 {
+			h.Strokes = h.Gross - h.Net
+		}
+		holes = append(holes, h)
+	}
+	return holes
+}
+
+// ScrapeField downloads an event leaderboard and returns the players in its
+// field. Before the event starts BlueGolf lists every registrant there.
+func ScrapeField(url string) ([]EventRegistration, error) {
+	c := colly.NewCollector(
+		// Optional: make it look like Chrome
+		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) " +
+			"AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/115.0.0.0 Safari/537.36"),
+	)
+
+	c.OnRequest(func(r *colly.Request) {
+		r.Headers.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		r.Headers.Set("Accept-Language", "en-US,en;q=0.9")
+		r.Headers.Set("Cache-Control", "no-cache")
+		fmt.Println("Visiting", r.URL.String())
+	})
+
+	var field []EventRegistration
+	seen := make(map[string]bool)
+	c.OnHTML("table.table-sortable tbody#lbBody > tr", func(e *colly.HTMLElement) {
+		player := strings.TrimSpace(e.ChildText("td:nth-child(2) a span.d-none.d-md-inline"))
+		if player == "" {
+			// Multi-round leaderboards have an extra column first
+			player = strings.TrimSpace(e.ChildText("td:nth-child(3) a span.d-none.d-md-inline"))
+		}
+		if player == "" || seen[strings.ToLower(player)] {
+			return
+		}
+		seen[strings.ToLower(player)] = true
+		field = append(field, EventRegistration{
+			Player:     player,
+			BlueGolfID: strings.TrimPrefix(e.Attr("id"), "tr_"),
+		})
+	})
+
+	if err := c.Visit(url); err != nil {
+		return nil, err
+	}
+	c.Wait()
+	return field, nil
+}
+
+// ScrapeWaitlist downloads an event's waitlist page and returns the waiting
+// players in order. The page lists them in a table like the leaderboard,
+// with the full name in the name cell.
+func ScrapeWaitlist(url string) ([]EventRegistration, error) {
+	c := colly.NewCollector(
+		// Optional: make it look like Chrome
+		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) " +
+			"AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/115.0.0.0 Safari/537.36"),
+	)
+
+	c.OnRequest(func(r *colly.Request) {
+		r.Headers.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		r.Headers.Set("Accept-Language", "en-US,en;q=0.9")
+		r.Headers.Set("Cache-Control", "no-cache")
+		fmt.Println("Visiting", r.URL.String())
+	})
+
+	var waitlist []EventRegistration
+	seen := make(map[string]bool)
+	c.OnHTML("table tbody > tr", func(e *colly.HTMLElement) {
+		player := strings.TrimSpace(e.ChildText("td.name span.d-none.d-md-inline"))
+		if player == "" {
+			player = strings.TrimSpace(e.ChildText("td.name"))
+		}
+		if player == "" || seen[strings.ToLower(player)] {
+			return
+		}
+		seen[strings.ToLower(player)] = true
+		waitlist = append(waitlist, EventRegistration{
+			Player:     player,
+			BlueGolfID: strings.TrimPrefix(e.Attr("id"), "tr_"),
+		})
+	})
+
+	if err := c.Visit(url); err != nil {
+		return nil, err
+	}
+	c.Wait()
+	return waitlist, nil
+}
+
+// ScrapeScorecard downloads a contestant scorecard page and returns the hole
+// scores for each round in order. Mobile-only duplicate tables are skipped and
+// a new round starts whenever a hole number repeats.
+func ScrapeScorecard(url string) ([][]HoleScore, error) {
+	c := colly.NewCollector(
+		// Optional: make it look like Chrome
+		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) " +
+			"AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/115.0.0.0 Safari/537.36"),
+	)
+
+	c.OnRequest(func(r *colly.Request) {
+		r.Headers.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		r.Headers.Set("Accept-Language", "en-US,en;q=0.9")
+		r.Headers.Set("Cache-Control", "no-cache")
+		fmt.Println("Visiting", r.URL.String())
+	})
+
+	var rounds [][]HoleScore
+	c.OnHTML("table", func(e *colly.HTMLElement) {
+		if e.DOM.ParentsFiltered(".d-md-none").Length() > 0 {
+			return
+		}
+		holes := parseScorecardTable(e.DOM)
+		if len(holes) == 0 {
+			return
+		}
+		if len(rounds) == 0 {
+			rounds = append(rounds, nil)
+		}
+		seen := make(map[int]bool)
+		for _, h := range rounds[len(rounds)-1] {
+			seen[h.Hole] = true
+		}
+		for _, h := range holes {
+			if seen[h.Hole] {
+				rounds = append(rounds, nil)
+				seen = make(map[int]bool)
+			}
+			seen[h.Hole] = true
+			rounds[len(rounds)-1] = append(rounds[len(rounds)-1], h)
+		}
+	})
+
+	if err := c.Visit(url); err != nil {
+		return nil, err
+	}
+	c.Wait()
+
+	if len(rounds) == 0 {
+		return nil, fmt.Errorf("no scorecard parsed from URL: %s", url)
+	}
+	return rounds, nil
+}
+
+// newScorecard totals the hole scores for a round.
+func newScorecard(eventID, player, url string, round int, holes []HoleScore) *Scorecard {
+	sc := &Scorecard{
+		EventID:      eventID,
+		Player:       player,
+		Round:        round,
+		ScorecardUrl: url,
+		Holes:        holes,
+	}
+	netComplete := true
+	for _, h := range holes {
+		sc.Par += h.Par
+		sc.Gross += h.Gross
+		sc.Net += h.Net
+		if h.Net == 0 {
+			netComplete = false
+		}
+		if h.Par > 0 {
+			switch diff := h.Gross - h.Par; {
+			case diff <= -2:
+				sc.Eagles++
+			case diff == -1:
+				sc.Birdies++
+			}
+		}
+	}
+	if !netComplete {
+		sc.Net = 0
+	}
+	return sc
+}
+
+// updateScorecards fetches the scorecard of every player with a result for
+// the event and replaces the stored scorecards.
+func updateScorecards(db *gorm.DB, eventID string) error {
+	type source struct {
+		Player       string
+		ScorecardUrl string
+	}
+
+	// Net scorecards include the Net Score row so prefer those.
+	var sources []source
+	seen := make(map[string]bool)
+	for _, model := range []any{&NetResult{}, &GrossResult{}, &WGRResult{}, &SkinsPlayerResult{}} {
+		var rows []source
+		if err := db.Model(model).
+			Select("player, scorecard_url").
+			Where("event_id = ? AND scorecard_url <> ''", eventID).
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if r.Player == "" || seen[r.Player] {
+				continue
+			}
+			seen[r.Player] = true
+			sources = append(sources, r)
+		}
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("no scorecard urls for event %s", eventID)
+	}
+
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		scorecards []*Scorecard
+		rerr       error
+	)
+	sem := make(chan struct{}, 4)
+	for _, src := range sources {
+		wg.Add(1)
+		go func(src source) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			rounds, err := ScrapeScorecard(src.ScorecardUrl)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				rerr = err
+				return
+			}
+			for i, holes := range rounds {
+				scorecards = append(scorecards, newScorecard(eventID, src.Player, src.ScorecardUrl, i+1, holes))
+			}
+		}(src)
+	}
+	wg.Wait()
+
+	// Replace the stored cards only when every player scraped; otherwise a
+	// single failed fetch would wipe that player's existing cards.
+	if rerr != nil || len(scorecards) == 0 {
+		return rerr
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&Scorecard{}).Where("event_id = ?", eventID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			if err := tx.Unscoped().Where("scorecard_id IN ?", ids).Delete(&HoleScore{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("id IN ?", ids).Delete(&Scorecard{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&scorecards).Error
+	})
+}
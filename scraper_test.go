@@ -460,6 +460,139 @@ func Test_sortTeeTimes(t *testing.T) {
 	assert.Equal(t, "1:51 PM", teeTimes[3].Time)
 }
 
+func Test_ScrapeScorecard(t *testing.T) {
+	path := filepath.Join("testdata", "scorecard.html")
+	htmlContent, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(htmlContent)
+	}))
+	defer server.Close()
+
+	rounds, err := ScrapeScorecard(server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, rounds, 1)
+	assert.Len(t, rounds[0], 18)
+
+	assert.Equal(t, 1, rounds[0][0].Hole)
+	assert.Equal(t, 5, rounds[0][0].Par)
+	assert.Equal(t, 3, rounds[0][0].Handicap)
+	assert.Equal(t, 5, rounds[0][0].Gross)
+	assert.Equal(t, 4, rounds[0][0].Net)
+	assert.Equal(t, 1, rounds[0][0].Strokes)
+
+	assert.Equal(t, 10, rounds[0][9].Hole)
+	assert.Equal(t, 0, rounds[0][10].Strokes)
+
+	sc := newScorecard("2025-impact-fire-open", "Connor Shaw", server.URL, 1, rounds[0])
+	assert.Equal(t, 71, sc.Par)
+	assert.Equal(t, 76, sc.Gross)
+	assert.Equal(t, 66, sc.Net)
+	assert.Equal(t, 2, sc.Birdies)
+	assert.Equal(t, 0, sc.Eagles)
+}
+
+func Test_updateScorecardsKeepsCardsOnFailure(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = applyMigrations(db)
+	assert.NoError(t, err)
+
+	htmlContent, err := os.ReadFile(filepath.Join("testdata", "scorecard.html"))
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(htmlContent)
+	}))
+	defer server.Close()
+
+	eventID := "2025-impact-fire-open"
+	assert.NoError(t, db.Create(&[]NetResult{
+		{EventID: eventID, Player: "Connor Shaw", ScorecardUrl: server.URL + "/card"},
+		{EventID: eventID, Player: "Sam Ortiz", ScorecardUrl: server.URL + "/missing"},
+	}).Error)
+	assert.NoError(t, db.Create(&[]Scorecard{
+		{EventID: eventID, Player: "Connor Shaw", Round: 1, Gross: 80},
+		{EventID: eventID, Player: "Sam Ortiz", Round: 1, Gross: 82},
+	}).Error)
+
+	// One failed fetch leaves every stored card in place.
+	assert.Error(t, updateScorecards(db, eventID))
+
+	var cards []Scorecard
+	assert.NoError(t, db.Order("player").Find(&cards).Error)
+	assert.Len(t, cards, 2)
+	assert.Equal(t, 80, cards[0].Gross)
+	assert.Equal(t, 82, cards[1].Gross)
+
+	// Once every player scrapes, the cards are replaced.
+	assert.NoError(t, db.Model(&NetResult{}).Where("player = ?", "Sam Ortiz").
+		Update("scorecard_url", server.URL+"/card").Error)
+	assert.NoError(t, updateScorecards(db, eventID))
+
+	cards = nil
+	assert.NoError(t, db.Order("player").Find(&cards).Error)
+	assert.Len(t, cards, 2)
+	assert.Equal(t, 76, cards[0].Gross)
+	assert.Equal(t, 76, cards[1].Gross)
+}
+
+func Test_scorecardValue(t *testing.T) {
+	for in, want := range map[string]int{"5": 5, " 4\u00a0": 4, "5.": 5, "10.": 10} {
+		n, ok := scorecardValue(in)
+		assert.True(t, ok, in)
+		assert.Equal(t, want, n, in)
+	}
+	for _, in := range []string{"", "-", ".", "Out"} {
+		_, ok := scorecardValue(in)
+		assert.False(t, ok, in)
+	}
+}
+
+func Test_ScrapeField(t *testing.T) {
+	path := filepath.Join("testdata", "net-results.html")
+	htmlContent, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(htmlContent)
+	}))
+	defer server.Close()
+
+	field, err := ScrapeField(server.URL)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, field)
+	for _, reg := range field {
+		assert.NotEmpty(t, reg.Player)
+		assert.NotEmpty(t, reg.BlueGolfID)
+	}
+}
+
+func Test_ScrapeWaitlist(t *testing.T) {
+	path := filepath.Join("testdata", "waitlist.html")
+	htmlContent, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(htmlContent)
+	}))
+	defer server.Close()
+
+	waitlist, err := ScrapeWaitlist(server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, waitlist, 3)
+	assert.Equal(t, "Sam Ortiz", waitlist[0].Player)
+	assert.Equal(t, "71", waitlist[0].BlueGolfID)
+	assert.Equal(t, "Mike Ross", waitlist[1].Player)
+	assert.Equal(t, "Dana Whitfield", waitlist[2].Player)
+}
+
 func TestScrapeAndPostToServer(t *testing.T) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	assert.NoError(t, err)
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	shopifyTopicOrderCreated   = "orders/create"
+	shopifyTopicOrderUpdated   = "orders/updated"
+	shopifyTopicOrderPaid      = "orders/paid"
+	shopifyTopicOrderCancelled = "orders/cancelled"
+
+	shopifyMaxBody = 1 << 20
+)
+
+// Line item properties that name the player being registered when it isn't
+// the customer.
+var shopifyPlayerProperties = []string{"player", "player name", "golfer", "name"}
+
+var yearPattern = regexp.MustCompile(`\b(19|20)\d{2}\b`)
+
+type shopifyLineItem struct {
+	ID         int64  `json:"id"`
+	ProductID  int64  `json:"product_id"`
+	Title      string `json:"title"`
+	Quantity   int    `json:"quantity"`
+	Properties []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"properties"`
+}
+
+// shopifyOrder holds the fields of a Shopify order webhook we use.
+type shopifyOrder struct {
+	ID              int64   `json:"id"`
+	Email           string  `json:"email"`
+	FinancialStatus string  `json:"financial_status"`
+	CancelledAt     *string `json:"cancelled_at"`
+	// Shopify doesn't deliver webhooks in order, so this decides which
+	// version of the order is newest.
+	UpdatedAt *time.Time `json:"updated_at"`
+	Customer  struct {
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Email     string `json:"email"`
+	} `json:"customer"`
+	LineItems []shopifyLineItem `json:"line_items"`
+}
+
+// verifyShopifyHMAC checks the X-Shopify-Hmac-Sha256 header: the base64
+// encoded HMAC-SHA256 of the body keyed with the app's secret.
+func verifyShopifyHMAC(secret string, body []byte, header string) bool {
+	got, err := base64.StdEncoding.DecodeString(header)
+	if err != nil || secret == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// player is who a line item registers: a player property if the buyer
+// filled one in, otherwise the customer.
+func (o *shopifyOrder) player(item shopifyLineItem) string {
+	for _, name := range shopifyPlayerProperties {
+		for _, p := range item.Properties {
+			if strings.EqualFold(strings.TrimSpace(p.Name), name) && strings.TrimSpace(p.Value) != "" {
+				return strings.TrimSpace(p.Value)
+			}
+		}
+	}
+	return strings.TrimSpace(o.Customer.FirstName + " " + o.Customer.LastName)
+}
+
+// shopifyTarget finds what a line item registers for: a mapped product
+// first, then an event with the same name, then a match play season named
+// in the title. Anything else, like merchandise, is ignored.
+func shopifyTarget(tx *gorm.DB, item shopifyLineItem) (eventID, matchPlayYear string, err error) {
+	var product ShopifyProduct
+	if item.ProductID != 0 {
+		if err := tx.Where("product_id = ?", item.ProductID).Limit(1).Find(&product).Error; err != nil {
+			return "", "", err
+		}
+		if product.ID != 0 {
+			return product.EventID, product.MatchPlayYear, nil
+		}
+	}
+
+	title := strings.TrimSpace(item.Title)
+	var event Event
+	if err := tx.Where("LOWER(name) = LOWER(?)", title).Order("date_string DESC").Limit(1).Find(&event).Error; err != nil {
+		return "", "", err
+	}
+	if event.EventID != "" {
+		return event.EventID, "", nil
+	}
+
+	if strings.Contains(strings.ToLower(title), "match play") {
+		if year := yearPattern.FindString(title); year != "" {
+			var info MatchPlayInfo
+			if err := tx.Where("year = ?", year).Limit(1).Find(&info).Error; err != nil {
+				return "", "", err
+			}
+			if info.ID != 0 {
+				return "", year, nil
+			}
+		}
+	}
+	return "", "", nil
+}
+
+// recordShopifyOrder saves a registration for each line item of an order
+// that registers for an event or match play, returning them. Payloads
+// older than the one already stored are ignored.
+func recordShopifyOrder(tx *gorm.DB, order *shopifyOrder, now time.Time) ([]Registration, error) {
+	email := order.Email
+	if email == "" {
+		email = order.Customer.Email
+	}
+
+	var recorded []Registration
+	for _, item := range order.LineItems {
+		eventID, year, err := shopifyTarget(tx, item)
+		if err != nil {
+			return nil, err
+		}
+		if eventID == "" && year == "" {
+			continue
+		}
+
+		var reg Registration
+		if err := tx.Where("shopify_order_id = ? AND shopify_line_id = ?", order.ID, item.ID).Limit(1).Find(&reg).Error; err != nil {
+			return nil, err
+		}
+		if reg.ShopifyUpdatedAt != nil && order.UpdatedAt != nil && order.UpdatedAt.Before(*reg.ShopifyUpdatedAt) {
+			continue
+		}
+		target := registrationTarget{EventID: eventID, MatchPlayYear: year}
+		if reg.ID == 0 {
+			// Orders placed once the field is full join the waitlist.
+			reg.Status = registrationRegistered
+			full, err := registrationFull(tx, target, max(item.Quantity, 1))
+			if err != nil {
+				return nil, err
+			}
+			if full {
+				reg.Status = registrationWaitlisted
+			}
+		}
+		reg.ShopifyOrderID, reg.ShopifyLineID = order.ID, item.ID
+		reg.EventID, reg.MatchPlayYear = eventID, year
+		reg.Player = order.player(item)
+		reg.Email = email
+		reg.Quantity = max(item.Quantity, 1)
+		reg.PaymentStatus = order.FinancialStatus
+		if order.UpdatedAt != nil {
+			reg.ShopifyUpdatedAt = order.UpdatedAt
+		}
+		if order.CancelledAt != nil && *order.CancelledAt != "" {
+			if reg.CancelledAt == nil {
+				reg.CancelledAt = &now
+			}
+		} else {
+			reg.CancelledAt = nil
+		}
+		if err := tx.Save(&reg).Error; err != nil {
+			return nil, err
+		}
+		recorded = append(recorded, reg)
+	}
+	return recorded, nil
+}
+
+// POST /api/shopify/orders
+// Receives Shopify's order webhooks. Requests are authenticated with the
+// HMAC Shopify signs them with rather than a login.
+func (s *Server) POSTShopifyOrder(w http.ResponseWriter, r *http.Request) {
+	if s.shopifySecret == "" {
+		http.Error(w, "Shopify webhooks are not configured", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, shopifyMaxBody))
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if !verifyShopifyHMAC(s.shopifySecret, body, r.Header.Get("X-Shopify-Hmac-Sha256")) {
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch topic := r.Header.Get("X-Shopify-Topic"); topic {
+	case shopifyTopicOrderCreated, shopifyTopicOrderUpdated, shopifyTopicOrderPaid, shopifyTopicOrderCancelled:
+	default:
+		// Acknowledge topics we don't handle so Shopify doesn't retry them.
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var order shopifyOrder
+	if err := json.Unmarshal(body, &order); err != nil || order.ID == 0 {
+		http.Error(w, "Bad order JSON", http.StatusBadRequest)
+		return
+	}
+
+	var recorded []Registration
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		recorded, err = recordShopifyOrder(tx, &order, time.Now())
+		return err
+	}); err != nil {
+		http.Error(w, "Could not save registrations", http.StatusInternalServerError)
+		return
+	}
+	s.settleRegistrations(recorded)
+
+	if recorded == nil {
+		recorded = []Registration{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recorded)
+}
+
+// GET /api/shopify/products
+func (s *Server) GETShopifyProducts(w http.ResponseWriter, r *http.Request) {
+	var products []ShopifyProduct
+	if err := s.db.Order("product_id ASC").Find(&products).Error; err != nil {
+		http.Error(w, "Error fetching products", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(products)
+}
+
+// PUT /api/shopify/products
+// Replaces the product mapping.
+func (s *Server) PUTShopifyProducts(w http.ResponseWriter, r *http.Request) {
+	var products []ShopifyProduct
+	if err := json.NewDecoder(r.Body).Decode(&products); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	for i, p := range products {
+		if p.ProductID == 0 || (p.EventID == "") == (p.MatchPlayYear == "") {
+			http.Error(w, fmt.Sprintf("Product %d must map to one event or match play year", i+1), http.StatusBadRequest)
+			return
+		}
+		if p.MatchPlayYear != "" && !validateYear(p.MatchPlayYear) {
+			http.Error(w, "Malformed year", http.StatusBadRequest)
+			return
+		}
+		products[i].ID = 0
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("1 = 1").Delete(&ShopifyProduct{}).Error; err != nil {
+			return err
+		}
+		if len(products) == 0 {
+			return nil
+		}
+		return tx.Create(&products).Error
+	}); err != nil {
+		http.Error(w, "Could not save products", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(products)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_POSTShopifyOrder(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db, shopifySecret: "hush"}
+
+	event := &Event{Name: "Memorial Day Shootout", DateString: "2025-05-24", RegistrationOpen: true, Capacity: 2}
+	assert.NoError(t, db.Create(event).Error)
+	assert.NoError(t, db.Create(&MatchPlayInfo{Year: "2025", RegistrationOpen: true}).Error)
+
+	payload, err := os.ReadFile(filepath.Join("testdata", "shopify-order-paid.json"))
+	assert.NoError(t, err)
+
+	post := func(topic string, body []byte, secret string) *httptest.ResponseRecorder {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/shopify/orders", bytes.NewReader(body))
+		req.Header.Set("X-Shopify-Topic", topic)
+		req.Header.Set("X-Shopify-Hmac-Sha256", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		w := httptest.NewRecorder()
+		s.POSTShopifyOrder(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post(shopifyTopicOrderPaid, payload, "wrong").Code)
+
+	assert.Equal(t, http.StatusOK, post(shopifyTopicOrderPaid, payload, "hush").Code)
+	var regs []Registration
+	assert.NoError(t, db.Order("id").Find(&regs).Error)
+	assert.Len(t, regs, 2) // the hat is not a registration
+	assert.Equal(t, event.EventID, regs[0].EventID)
+	assert.Equal(t, "Connor Shaw", regs[0].Player)
+	assert.Equal(t, "paid", regs[0].PaymentStatus)
+	assert.Equal(t, "2025", regs[1].MatchPlayYear)
+	assert.Equal(t, "Andy Lee", regs[1].Player)
+
+	// Redelivery doesn't duplicate registrations.
+	assert.Equal(t, http.StatusOK, post(shopifyTopicOrderCreated, payload, "hush").Code)
+	var count int64
+	assert.NoError(t, db.Model(&Registration{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	// A late orders/create from before payment doesn't undo it.
+	stale := strings.Replace(string(payload), `"financial_status": "paid"`, `"financial_status": "pending"`, 1)
+	stale = strings.Replace(stale, `"updated_at": "2025-05-02T19:14:09-04:00"`, `"updated_at": "2025-05-02T19:14:07-04:00"`, 1)
+	assert.Equal(t, http.StatusOK, post(shopifyTopicOrderCreated, []byte(stale), "hush").Code)
+	assert.NoError(t, db.First(&regs[0], regs[0].ID).Error)
+	assert.Equal(t, "paid", regs[0].PaymentStatus)
+
+	// A second order fills the event and closes registration.
+	second := bytes.ReplaceAll(payload, []byte("5876134527213"), []byte("5876134527999"))
+	assert.Equal(t, http.StatusOK, post(shopifyTopicOrderPaid, second, "hush").Code)
+	assert.NoError(t, db.First(event, "event_id = ?", event.EventID).Error)
+	assert.False(t, event.RegistrationOpen)
+	var info MatchPlayInfo
+	assert.NoError(t, db.First(&info, "year = ?", "2025").Error)
+	assert.True(t, info.RegistrationOpen) // no capacity set
+
+	// Cancelling an order frees its spots.
+	cancelled := strings.Replace(string(second), `"cancelled_at": null`, `"cancelled_at": "2025-05-03T08:00:00-04:00"`, 1)
+	cancelled = strings.Replace(cancelled, `"financial_status": "paid"`, `"financial_status": "refunded"`, 1)
+	assert.Equal(t, http.StatusOK, post(shopifyTopicOrderCancelled, []byte(cancelled), "hush").Code)
+	n, err := registrationCount(db, registrationTarget{EventID: event.EventID})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// Products can be mapped explicitly.
+	assert.NoError(t, db.Create(&ShopifyProduct{ProductID: 8123456856301, EventID: event.EventID}).Error)
+	eventID, year, err := shopifyTarget(db, shopifyLineItem{ProductID: 8123456856301, Title: "LFG Rope Hat"})
+	assert.NoError(t, err)
+	assert.Equal(t, event.EventID, eventID)
+	assert.Equal(t, "", year)
+}
+
+func TestServer_promoteWaitlist(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	event := &Event{Name: "LFG Open", DateString: "2025-06-14", RegistrationOpen: true, Capacity: 3}
+	assert.NoError(t, db.Create(event).Error)
+	target := registrationTarget{EventID: event.EventID}
+
+	order := func(id int64, player string, quantity int) []Registration {
+		var regs []Registration
+		assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			o := &shopifyOrder{ID: id, FinancialStatus: "paid", LineItems: []shopifyLineItem{{ID: id, Title: "LFG Open", Quantity: quantity}}}
+			o.Customer.FirstName = player
+			regs, err = recordShopifyOrder(tx, o, time.Now())
+			return err
+		}))
+		s.settleRegistrations(regs)
+		return regs
+	}
+	order(1, "Shaw", 2)
+	assert.Equal(t, registrationWaitlisted, order(2, "Ross", 2)[0].Status)
+	// Lee's order would fit but Ross is already waiting.
+	assert.Equal(t, registrationWaitlisted, order(3, "Lee", 1)[0].Status)
+	assert.Equal(t, registrationWaitlisted, order(4, "Tokanel", 1)[0].Status)
+
+	queue, err := waitlist(db, target)
+	assert.NoError(t, err)
+	assert.Len(t, queue, 3)
+	assert.Equal(t, "Ross", queue[0].Player)
+
+	// The spot left isn't enough for Ross, and Lee doesn't skip ahead.
+	assert.NoError(t, s.settleRegistration(target))
+	queue, err = waitlist(db, target)
+	assert.NoError(t, err)
+	assert.Len(t, queue, 3)
+
+	// Shaw cancels. Ross and Lee take the spots and Tokanel waits.
+	cancelled := &shopifyOrder{ID: 1, FinancialStatus: "refunded", LineItems: []shopifyLineItem{{ID: 1, Title: "LFG Open", Quantity: 2}}}
+	var regs []Registration
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		regs, err = recordShopifyOrder(tx, cancelled, time.Now())
+		return err
+	}))
+	s.settleRegistrations(regs)
+	queue, err = waitlist(db, target)
+	assert.NoError(t, err)
+	assert.Len(t, queue, 1)
+	assert.Equal(t, "Tokanel", queue[0].Player)
+	assert.NoError(t, db.First(event, "event_id = ?", event.EventID).Error)
+	assert.False(t, event.RegistrationOpen)
+
+	// Raising the capacity lets Tokanel in.
+	assert.NoError(t, db.Model(event).Update("capacity", 4).Error)
+	assert.NoError(t, s.settleRegistration(target))
+	queue, err = waitlist(db, target)
+	assert.NoError(t, err)
+	assert.Empty(t, queue)
+	n, err := registrationCount(db, target)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+}
+
+func TestServer_applyRegistrationSchedule(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	now := time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC)
+	opens, closes := now.Add(-time.Minute), now.Add(time.Hour)
+	event := &Event{Name: "LFG Open", DateString: "2025-06-14", RegistrationOpensAt: &opens, RegistrationClosesAt: &closes}
+	assert.NoError(t, db.Create(event).Error)
+	assert.NoError(t, db.Create(&MatchPlayInfo{Year: "2025", RegistrationOpen: true, RegistrationClosesAt: &opens}).Error)
+
+	s.applyRegistrationSchedule(now)
+	var opened Event
+	assert.NoError(t, db.First(&opened, "event_id = ?", event.EventID).Error)
+	assert.True(t, opened.RegistrationOpen)
+	assert.Equal(t, opens.Unix(), opened.RegistrationOpensAt.Unix())
+	assert.Equal(t, now.Unix(), opened.RegistrationOpenedAt.Unix())
+	var info MatchPlayInfo
+	assert.NoError(t, db.First(&info, "year = ?", "2025").Error)
+	assert.False(t, info.RegistrationOpen)
+
+	// Closing by hand sticks until the closing time passes.
+	assert.NoError(t, db.Model(event).Update("registration_open", false).Error)
+	s.applyRegistrationSchedule(now.Add(time.Minute))
+	assert.NoError(t, db.First(event, "event_id = ?", event.EventID).Error)
+	assert.False(t, event.RegistrationOpen)
+
+	assert.NoError(t, db.Model(event).Update("registration_open", true).Error)
+	s.applyRegistrationSchedule(closes)
+	assert.NoError(t, db.First(event, "event_id = ?", event.EventID).Error)
+	assert.False(t, event.RegistrationOpen)
+
+	// Moving the opening time later schedules it again.
+	reopens, recloses := closes.Add(time.Hour), closes.Add(2*time.Hour)
+	assert.NoError(t, db.Model(event).UpdateColumns(map[string]any{"registration_opens_at": reopens, "registration_closes_at": recloses}).Error)
+	s.applyRegistrationSchedule(reopens)
+	assert.NoError(t, db.First(event, "event_id = ?", event.EventID).Error)
+	assert.True(t, event.RegistrationOpen)
+	assert.NotNil(t, event.RegistrationOpensAt)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// The scrapers are swapped out in tests.
+var (
+	scrapeTeeTimes = ScrapeTeeTimes
+	scrapeField    = ScrapeField
+	scrapeWaitlist = ScrapeWaitlist
+)
+
+// syncRecord is a table that embeds BlueGolfSync.
+type syncRecord interface {
+	blueGolfSync() *BlueGolfSync
+}
+
+func (b *BlueGolfSync) blueGolfSync() *BlueGolfSync { return b }
+
+// beginSync loads an event's sync row, if it has one, and marks it as
+// checked now.
+func (s *Server) beginSync(row syncRecord, eventID string) {
+	s.db.Where("event_id = ?", eventID).Limit(1).Find(row)
+	sync := row.blueGolfSync()
+	sync.EventID, sync.CheckedAt, sync.Error = eventID, time.Now(), ""
+}
+
+// failSync records a failed scrape on the sync row and returns the error.
+func (s *Server) failSync(row syncRecord, err error) error {
+	sync := row.blueGolfSync()
+	sync.Error = err.Error()
+	if serr := s.db.Save(row).Error; serr != nil {
+		log.Printf("error saving sync for %s: %s", sync.EventID, serr)
+	}
+	return err
+}
+
+// syncOnFirstRequest scrapes an event the first time one of its pages is
+// requested, so public reads are served from the database. The workers keep
+// it current afterwards.
+func (s *Server) syncOnFirstRequest(row syncRecord, eventID string, refresh func() error) error {
+	var checked int64
+	if err := s.db.Model(row).Where("event_id = ?", eventID).Count(&checked).Error; err != nil {
+		return err
+	}
+	if checked > 0 {
+		return nil
+	}
+	return refresh()
+}
+
+// refreshUpcoming runs refresh for every published event with a BlueGolf
+// page dated between from and to.
+func (s *Server) refreshUpcoming(what string, from, to time.Time, refresh func(*Event) error) {
+	var events []Event
+	if err := s.publishedEvents().Where("blue_golf_url <> '' AND date_string BETWEEN ? AND ?",
+		from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Find(&events).Error; err != nil {
+		log.Printf("error loading events for %s: %s", what, err)
+		return
+	}
+	for i := range events {
+		if err := refresh(&events[i]); err != nil {
+			log.Printf("error refreshing %s for %s: %s", what, events[i].EventID, err)
+		}
+	}
+}
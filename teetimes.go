@@ -0,0 +1,302 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	teeTimeAdded   = "added"
+	teeTimeRemoved = "removed"
+	teeTimeMoved   = "moved"
+
+	teeTimePollInterval = 30 * time.Minute
+	// Tee times are checked from a week before an event until the day after.
+	teeTimeLookahead = 7 * 24 * time.Hour
+	teeTimeLookback  = 24 * time.Hour
+)
+
+// teeTimesURL builds the BlueGolf pairings URL for an event.
+func (s *Server) teeTimesURL(event *Event) (string, error) {
+	blueGolfID, err := blueGolfEventID(event)
+	if err != nil {
+		return "", err
+	}
+	site, err := eventBlueGolfSite(s.db, event)
+	if err != nil {
+		return "", err
+	}
+	return site.PairingsURL(blueGolfID), nil
+}
+
+// teeTimesETag is a strong validator for a set of tee times.
+func teeTimesETag(teeTimes []TeeTime) string {
+	b, _ := json.Marshal(teeTimes)
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// diffTeeTimes lists the players whose tee time was added, removed or moved
+// in each round.
+func diffTeeTimes(old, new []TeeTime) []TeeTimeChange {
+	type key struct {
+		round  int
+		player string
+	}
+	index := func(teeTimes []TeeTime) map[key]TeeTimeChange {
+		m := make(map[key]TeeTimeChange)
+		for _, tt := range teeTimes {
+			for _, p := range tt.Players {
+				m[key{tt.Round, strings.ToLower(p)}] = TeeTimeChange{Round: tt.Round, Player: p, NewTime: tt.Time, NewHole: tt.Hole}
+			}
+		}
+		return m
+	}
+	before, after := index(old), index(new)
+
+	var changes []TeeTimeChange
+	for k, was := range before {
+		now, ok := after[k]
+		switch {
+		case !ok:
+			changes = append(changes, TeeTimeChange{Kind: teeTimeRemoved, Round: k.round, Player: was.Player, OldTime: was.NewTime, OldHole: was.NewHole})
+		case now.NewTime != was.NewTime || now.NewHole != was.NewHole:
+			now.Kind, now.OldTime, now.OldHole = teeTimeMoved, was.NewTime, was.NewHole
+			changes = append(changes, now)
+		}
+	}
+	for k, now := range after {
+		if _, ok := before[k]; !ok {
+			now.Kind = teeTimeAdded
+			changes = append(changes, now)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Round != changes[j].Round {
+			return changes[i].Round < changes[j].Round
+		}
+		return changes[i].Player < changes[j].Player
+	})
+	return changes
+}
+
+func (s *Server) loadTeeTimes(eventID string) ([]TeeTime, error) {
+	var teeTimes []TeeTime
+	err := s.db.Where("event_id = ?", eventID).Order("position ASC").Find(&teeTimes).Error
+	return teeTimes, err
+}
+
+// refreshTeeTimes scrapes an event's tee times, stores them and logs what
+// changed since the last check. Changes are only logged once tee times have
+// been posted, and an empty sheet never replaces a posted one.
+func (s *Server) refreshTeeTimes(event *Event) ([]TeeTimeChange, error) {
+	var sync TeeTimeSync
+	s.beginSync(&sync, event.EventID)
+
+	pairingsURL, err := s.teeTimesURL(event)
+	var scraped []TeeTime
+	if err == nil {
+		scraped, err = scrapeTeeTimes(pairingsURL)
+	}
+	if err != nil {
+		return nil, s.failSync(&sync, err)
+	}
+
+	old, err := s.loadTeeTimes(event.EventID)
+	if err != nil {
+		return nil, err
+	}
+	if len(scraped) == 0 && len(old) > 0 {
+		return nil, s.db.Save(&sync).Error
+	}
+
+	for i := range scraped {
+		scraped[i].ID = 0
+		scraped[i].EventID = event.EventID
+		scraped[i].Position = i
+	}
+	etag := teeTimesETag(scraped)
+	if etag == sync.ETag {
+		return nil, s.db.Save(&sync).Error
+	}
+
+	var changes []TeeTimeChange
+	if len(old) > 0 {
+		changes = diffTeeTimes(old, scraped)
+		for i := range changes {
+			changes[i].EventID = event.EventID
+		}
+	}
+	posted := len(old) == 0 && len(scraped) > 0
+	now := time.Now()
+	sync.ETag = etag
+	sync.ChangedAt = &now
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("event_id = ?", event.EventID).Delete(&TeeTime{}).Error; err != nil {
+			return err
+		}
+		if len(scraped) > 0 {
+			if err := tx.Create(&scraped).Error; err != nil {
+				return err
+			}
+		}
+		if len(changes) > 0 {
+			if err := tx.Create(&changes).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(&sync).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	if posted || len(changes) > 0 {
+		s.publishTeeTimes(event, posted, changes)
+	}
+	return changes, nil
+}
+
+// publishTeeTimes is called when an event's tee times are first posted or
+// change afterwards. Nothing is published for drafts.
+func (s *Server) publishTeeTimes(event *Event, posted bool, changes []TeeTimeChange) {
+	if event.Status == eventDraft {
+		return
+	}
+	title := fmt.Sprintf("Tee times updated: %s", event.Name)
+	summary := fmt.Sprintf("%d tee time change(s) for %s.", len(changes), event.Name)
+	if posted {
+		title = fmt.Sprintf("Tee times posted: %s", event.Name)
+		summary = fmt.Sprintf("Tee times for %s are now available.", event.Name)
+	}
+	s.addFeedEntry(&FeedEntry{
+		Kind:    "tee-times",
+		Key:     event.EventID,
+		Title:   title,
+		Summary: summary,
+		Link:    fmt.Sprintf("%s/events/%s", siteURL, event.EventID),
+	})
+	s.emitWebhook(webhookTeeTimesUpdated, map[string]any{
+		"eventID": event.EventID,
+		"name":    event.Name,
+		"posted":  posted,
+		"changes": changes,
+	})
+}
+
+// refreshUpcomingTeeTimes checks the tee times of every event around now.
+func (s *Server) refreshUpcomingTeeTimes(now time.Time) {
+	s.refreshUpcoming("tee times", now.Add(-teeTimeLookback), now.Add(teeTimeLookahead), func(e *Event) error {
+		_, err := s.refreshTeeTimes(e)
+		return err
+	})
+}
+
+func (s *Server) runTeeTimeWorker() {
+	ticker := time.NewTicker(teeTimePollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.refreshUpcomingTeeTimes(time.Now())
+	}
+}
+
+// GET /api/tee-times/{eventID}
+// Served from the database. Tee times are fetched from BlueGolf the first
+// time they are requested and afterwards on a schedule.
+func (s *Server) GetTeeTimes(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	if eventID == "" {
+		http.Error(w, "Missing event ID", http.StatusBadRequest)
+		return
+	}
+
+	var event Event
+	if err := s.visibleEvents(r).First(&event, "event_id = ?", eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Event not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if event.BlueGolfUrl != "" {
+		if err := s.syncOnFirstRequest(&TeeTimeSync{}, eventID, func() error {
+			_, err := s.refreshTeeTimes(&event)
+			return err
+		}); err != nil {
+			http.Error(w, fmt.Sprintf("Error fetching tee times: %s", err.Error()), http.StatusBadGateway)
+			return
+		}
+	}
+
+	teeTimes, err := s.loadTeeTimes(eventID)
+	if err != nil {
+		http.Error(w, "Error fetching tee times", http.StatusInternalServerError)
+		return
+	}
+	if teeTimes == nil {
+		teeTimes = []TeeTime{}
+	}
+
+	etag := teeTimesETag(teeTimes)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(teeTimes)
+}
+
+// POST /api/tee-times/{eventID}/refresh
+func (s *Server) POSTRefreshTeeTimes(w http.ResponseWriter, r *http.Request) {
+	var event Event
+	if err := s.db.First(&event, "event_id = ?", chi.URLParam(r, "eventID")).Error; err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	changes, err := s.refreshTeeTimes(&event)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching tee times: %s", err.Error()), http.StatusBadGateway)
+		return
+	}
+	if changes == nil {
+		changes = []TeeTimeChange{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"eventID": event.EventID,
+		"changes": changes,
+	})
+}
+
+// GET /api/tee-times/{eventID}/changes
+func (s *Server) GETTeeTimeChanges(w http.ResponseWriter, r *http.Request) {
+	var event Event
+	if err := s.visibleEvents(r).First(&event, "event_id = ?", chi.URLParam(r, "eventID")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Event not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+	var changes []TeeTimeChange
+	if err := s.db.Where("event_id = ?", event.EventID).Order("created_at DESC, round ASC, player ASC").Find(&changes).Error; err != nil {
+		http.Error(w, "Error fetching tee time changes", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
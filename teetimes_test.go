@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_diffTeeTimes(t *testing.T) {
+	old := []TeeTime{
+		{Round: 1, Time: "8:00 AM", Hole: "1", Players: []string{"Connor Shaw", "Andy Lee"}},
+		{Round: 1, Time: "8:10 AM", Hole: "1", Players: []string{"Mike Ross"}},
+	}
+	new := []TeeTime{
+		{Round: 1, Time: "8:00 AM", Hole: "1", Players: []string{"Connor Shaw"}},
+		{Round: 1, Time: "8:20 AM", Hole: "10", Players: []string{"Mike Ross", "Jim Tokanel"}},
+	}
+	changes := diffTeeTimes(old, new)
+	assert.Len(t, changes, 3)
+	assert.Equal(t, TeeTimeChange{Kind: teeTimeRemoved, Round: 1, Player: "Andy Lee", OldTime: "8:00 AM", OldHole: "1"}, changes[0])
+	assert.Equal(t, TeeTimeChange{Kind: teeTimeAdded, Round: 1, Player: "Jim Tokanel", NewTime: "8:20 AM", NewHole: "10"}, changes[1])
+	assert.Equal(t, TeeTimeChange{Kind: teeTimeMoved, Round: 1, Player: "Mike Ross", OldTime: "8:10 AM", OldHole: "1", NewTime: "8:20 AM", NewHole: "10"}, changes[2])
+}
+
+func TestServer_GetTeeTimes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	sheet := []TeeTime{{Round: 1, Time: "8:00 AM", Hole: "1", Players: []string{"Connor Shaw", "Andy Lee"}}}
+	scrapes := 0
+	defer func(orig func(string) ([]TeeTime, error)) { scrapeTeeTimes = orig }(scrapeTeeTimes)
+	scrapeTeeTimes = func(url string) ([]TeeTime, error) {
+		scrapes++
+		assert.Equal(t, "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclublivefreegc25/event/lfg-open/pairings.htm", url)
+		out := make([]TeeTime, len(sheet))
+		copy(out, sheet)
+		return out, nil
+	}
+
+	standings := &Standings{CalendarYear: "2025", SeasonStandingsUrl: "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclublivefreegc25/poy/lfgchampiongolferoftheyear/index.htm"}
+	assert.NoError(t, db.Create(standings).Error)
+	event := &Event{Name: "LFG Open", DateString: "2025-06-14", BlueGolfUrl: "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclub/events/lfg-open/index.htm"}
+	assert.NoError(t, db.Create(event).Error)
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		r := chi.NewRouter()
+		r.Get("/api/tee-times/{eventID}", s.GetTeeTimes)
+		req := httptest.NewRequest(http.MethodGet, "/api/tee-times/"+event.EventID, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"round":1,"time":"8:00 AM","hole":"1","players":["Connor Shaw","Andy Lee"]}]`, w.Body.String())
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// Later requests are served from the database.
+	assert.Equal(t, http.StatusNotModified, get(etag).Code)
+	assert.Equal(t, 1, scrapes)
+
+	// Events not on BlueGolf have no tee times to fetch.
+	unlinked := &Event{Name: "Member Social", DateString: "2025-07-12"}
+	assert.NoError(t, db.Create(unlinked).Error)
+	router := chi.NewRouter()
+	router.Get("/api/tee-times/{eventID}", s.GetTeeTimes)
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tee-times/"+unlinked.EventID, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	}
+	assert.Equal(t, 1, scrapes)
+
+	// A changed sheet is stored and the change logged.
+	sheet = []TeeTime{{Round: 1, Time: "8:10 AM", Hole: "1", Players: []string{"Connor Shaw", "Andy Lee"}}}
+	changes, err := s.refreshTeeTimes(event)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, http.StatusOK, get(etag).Code)
+
+	var logged int64
+	assert.NoError(t, db.Model(&TeeTimeChange{}).Where("event_id = ?", event.EventID).Count(&logged).Error)
+	assert.Equal(t, int64(2), logged)
+
+	// An empty sheet does not wipe out posted tee times.
+	sheet = nil
+	_, err = s.refreshTeeTimes(event)
+	assert.NoError(t, err)
+	teeTimes, err := s.loadTeeTimes(event.EventID)
+	assert.NoError(t, err)
+	assert.Len(t, teeTimes, 1)
+
+	// Unparsable URLs are reported rather than panicking.
+	scrapeTeeTimes = func(string) ([]TeeTime, error) { return nil, errors.New("unreachable") }
+	event.BlueGolfUrl = "://bad"
+	_, err = s.refreshTeeTimes(event)
+	assert.ErrorContains(t, err, "invalid BlueGolf URL")
+}
+
+func Test_eventBlueGolfSite(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+
+	standings := &Standings{CalendarYear: "2026", SeasonStandingsUrl: "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclublivefreegc26/poy/lfgchampiongolferoftheyear/index.htm"}
+	assert.NoError(t, db.Create(standings).Error)
+	assert.Equal(t, "nhgaclub", standings.BlueGolfClub)
+	assert.Equal(t, "nhgaclublivefreegc26", standings.BlueGolfSeason)
+
+	event := &Event{Name: "LFG Open", DateString: "2026-06-13", BlueGolfUrl: "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclub/events/nhgaclublivefreegc261/index.htm"}
+	site, err := eventBlueGolfSite(db, event)
+	assert.NoError(t, err)
+	assert.Equal(t, blueGolfSite{Club: "nhgaclub", Season: "nhgaclublivefreegc26"}, site)
+	assert.Equal(t, "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclublivefreegc26/event/nhgaclublivefreegc261/pairings.htm", site.PairingsURL("nhgaclublivefreegc261"))
+	assert.Equal(t, "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclublivefreegc26/event/nhgaclublivefreegc261/contest/4/contestant/123/scorecard.htm",
+		scorecardURL("https://nhgaclub.bluegolf.com/bluegolfw/nhgaclublivefreegc26/event/nhgaclublivefreegc261/contest/4/leaderboard.htm", "123"))
+
+	// An event's own season wins over the standings.
+	event.BlueGolfSeason = "nhgaclubspecial26"
+	site, err = eventBlueGolfSite(db, event)
+	assert.NoError(t, err)
+	assert.Equal(t, "nhgaclubspecial26", site.Season)
+
+	// Without standings the season comes from a URL inside it.
+	event = &Event{Name: "Fall Classic", DateString: "2027-09-18", BlueGolfUrl: "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclublivefreegc27/event/nhgaclublivefreegc274/index.htm"}
+	site, err = eventBlueGolfSite(db, event)
+	assert.NoError(t, err)
+	assert.Equal(t, "nhgaclublivefreegc27", site.Season)
+
+	event.BlueGolfUrl = "https://nhgaclub.bluegolf.com/bluegolfw/nhgaclub/events/nhgaclublivefreegc274/index.htm"
+	_, err = eventBlueGolfSite(db, event)
+	assert.EqualError(t, err, "no BlueGolf season configured for Fall Classic")
+}
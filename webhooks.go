@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	webhookResultsUpdated     = "results.updated"
+	webhookStandingsRefreshed = "standings.refreshed"
+	webhookEventCreated       = "event.created"
+	webhookRegistrationOpened = "registration.opened"
+	webhookRegistrationClosed = "registration.closed"
+	webhookMatchDecided       = "match.decided"
+	webhookColonyCupUpdated   = "colonycup.updated"
+	webhookTeeTimesUpdated    = "teetimes.updated"
+
+	webhookStatusPending   = "pending"
+	webhookStatusDelivered = "delivered"
+	webhookStatusFailed    = "failed"
+
+	webhookMaxAttempts  = 6
+	webhookBaseBackoff  = 30 * time.Second
+	webhookTimeout      = 10 * time.Second
+	webhookPollInterval = 30 * time.Second
+	webhookBatchSize    = 50
+)
+
+var webhookEventTypes = []string{
+	webhookResultsUpdated,
+	webhookStandingsRefreshed,
+	webhookEventCreated,
+	webhookRegistrationOpened,
+	webhookRegistrationClosed,
+	webhookMatchDecided,
+	webhookColonyCupUpdated,
+	webhookTeeTimesUpdated,
+}
+
+// webhookEnvelope is the JSON body POSTed to every subscriber.
+type webhookEnvelope struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// signWebhookPayload returns the value of the X-LFG-Signature header: the
+// hex encoded HMAC-SHA256 of the body keyed with the webhook secret.
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns how long to wait before retrying after the given
+// number of failed attempts: 30s, 1m, 2m, 4m, ...
+func webhookBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return webhookBaseBackoff << (attempts - 1)
+}
+
+func (w *Webhook) subscribed(eventType string) bool {
+	for _, e := range w.Events {
+		if e == eventType || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func validateWebhook(hook *Webhook) error {
+	u, err := url.Parse(hook.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("Webhook url must be an absolute http(s) URL")
+	}
+	if len(hook.Events) == 0 {
+		return errors.New("At least one event type must be provided")
+	}
+	for _, e := range hook.Events {
+		valid := e == "*"
+		for _, t := range webhookEventTypes {
+			if e == t {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("Unknown event type %q", e)
+		}
+	}
+	return nil
+}
+
+// emitWebhook queues a delivery of the event to every active subscriber and
+// wakes the delivery worker.
+func (s *Server) emitWebhook(eventType string, data any) {
+	var hooks []Webhook
+	if err := s.db.Where("active = ?", true).Find(&hooks).Error; err != nil {
+		log.Printf("error loading webhooks: %s", err)
+		return
+	}
+
+	envelope := webhookEnvelope{
+		ID:        randomHex(16),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("error encoding %s webhook: %s", eventType, err)
+		return
+	}
+
+	queued := 0
+	for _, hook := range hooks {
+		if !hook.subscribed(eventType) {
+			continue
+		}
+		delivery := &WebhookDelivery{
+			WebhookID:     hook.ID,
+			DeliveryID:    randomHex(16),
+			EventType:     eventType,
+			Payload:       string(payload),
+			Status:        webhookStatusPending,
+			NextAttemptAt: time.Now(),
+		}
+		if err := s.db.Create(delivery).Error; err != nil {
+			log.Printf("error queueing webhook delivery to %s: %s", hook.Url, err)
+			continue
+		}
+		queued++
+	}
+	if queued > 0 {
+		s.wakeWebhooks()
+	}
+}
+
+func (s *Server) wakeWebhooks() {
+	if s.webhookWake == nil {
+		return
+	}
+	select {
+	case s.webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// runWebhookWorker delivers queued webhooks until the process exits. Pending
+// deliveries are persisted so retries survive restarts.
+func (s *Server) runWebhookWorker() {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		s.deliverPendingWebhooks(time.Now())
+		select {
+		case <-ticker.C:
+		case <-s.webhookWake:
+		}
+	}
+}
+
+func (s *Server) deliverPendingWebhooks(now time.Time) {
+	var due []WebhookDelivery
+	if err := s.db.Where("status = ? AND next_attempt_at <= ?", webhookStatusPending, now).
+		Order("next_attempt_at ASC").
+		Limit(webhookBatchSize).
+		Find(&due).Error; err != nil {
+		log.Printf("error loading webhook deliveries: %s", err)
+		return
+	}
+	for i := range due {
+		s.attemptWebhookDelivery(&due[i])
+	}
+}
+
+func (s *Server) attemptWebhookDelivery(d *WebhookDelivery) {
+	var hook Webhook
+	if err := s.db.First(&hook, d.WebhookID).Error; err != nil || !hook.Active {
+		// The webhook was removed or disabled after this was queued.
+		d.Status = webhookStatusFailed
+		s.db.Save(d)
+		return
+	}
+
+	d.Attempts++
+	attempt := WebhookAttempt{DeliveryID: d.DeliveryID, Attempt: d.Attempts}
+
+	start := time.Now()
+	statusCode, err := s.postWebhook(&hook, d)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	attempt.StatusCode = statusCode
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		d.Status = webhookStatusDelivered
+		d.DeliveredAt = &now
+	case d.Attempts >= webhookMaxAttempts:
+		d.Status = webhookStatusFailed
+	default:
+		d.NextAttemptAt = time.Now().Add(webhookBackoff(d.Attempts))
+	}
+
+	if err := s.db.Create(&attempt).Error; err != nil {
+		log.Printf("error saving webhook attempt: %s", err)
+	}
+	if err := s.db.Save(d).Error; err != nil {
+		log.Printf("error saving webhook delivery: %s", err)
+	}
+}
+
+func (s *Server) postWebhook(hook *Webhook, d *WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequest(http.MethodPost, hook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lfg-server-webhooks/1.0")
+	req.Header.Set("X-LFG-Event", d.EventType)
+	req.Header.Set("X-LFG-Delivery", d.DeliveryID)
+	req.Header.Set("X-LFG-Signature", signWebhookPayload(hook.Secret, body))
+
+	client := s.webhookClient
+	if client == nil {
+		client = &http.Client{Timeout: webhookTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// GET /api/webhooks
+func (s *Server) GETWebhooks(w http.ResponseWriter, r *http.Request) {
+	var hooks []Webhook
+	if err := s.db.Order("id ASC").Find(&hooks).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if hooks == nil {
+		hooks = []Webhook{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"eventTypes": webhookEventTypes,
+		"webhooks":   hooks,
+	})
+}
+
+// POST /api/webhooks
+// Body: {"url":"https://...","events":["results.updated"],"secret":"optional"}
+func (s *Server) POSTWebhook(w http.ResponseWriter, r *http.Request) {
+	var hook Webhook
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	hook.ID = 0
+	hook.Url = strings.TrimSpace(hook.Url)
+	if err := validateWebhook(&hook); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if hook.Secret == "" {
+		hook.Secret = randomHex(32)
+	}
+	hook.Active = true
+
+	if err := s.db.Create(&hook).Error; err != nil {
+		http.Error(w, "Could not save webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hook)
+}
+
+func (s *Server) loadWebhook(w http.ResponseWriter, r *http.Request) (*Webhook, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return nil, false
+	}
+	var hook Webhook
+	if err := s.db.First(&hook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return &hook, true
+}
+
+// PUT /api/webhooks/{id}
+func (s *Server) PUTWebhook(w http.ResponseWriter, r *http.Request) {
+	existing, ok := s.loadWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	// Active is left alone unless it is sent.
+	var input struct {
+		Webhook
+		Active *bool `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	input.Url = strings.TrimSpace(input.Url)
+	if err := validateWebhook(&input.Webhook); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existing.Url = input.Url
+	existing.Description = input.Description
+	existing.Events = input.Events
+	if input.Active != nil {
+		existing.Active = *input.Active
+	}
+	if input.Secret != "" {
+		existing.Secret = input.Secret
+	}
+
+	if err := s.db.Save(existing).Error; err != nil {
+		http.Error(w, "Could not update webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(existing)
+}
+
+// DELETE /api/webhooks/{id}
+func (s *Server) DELETEWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := s.loadWebhook(w, r)
+	if !ok {
+		return
+	}
+	if err := s.db.Unscoped().Delete(hook).Error; err != nil {
+		http.Error(w, "Could not delete webhook", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/webhooks/{id}/deliveries
+// Returns the most recent deliveries for the webhook with every attempt made.
+func (s *Server) GETWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	hook, ok := s.loadWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	var deliveries []WebhookDelivery
+	if err := s.db.Where("webhook_id = ?", hook.ID).Order("id DESC").Limit(100).Find(&deliveries).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]string, 0, len(deliveries))
+	for _, d := range deliveries {
+		ids = append(ids, d.DeliveryID)
+	}
+	var attempts []WebhookAttempt
+	if err := s.db.Where("delivery_id IN ?", ids).Order("attempt ASC").Find(&attempts).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	byDelivery := make(map[string][]WebhookAttempt)
+	for _, a := range attempts {
+		byDelivery[a.DeliveryID] = append(byDelivery[a.DeliveryID], a)
+	}
+
+	type deliveryLog struct {
+		WebhookDelivery
+		AttemptLog []WebhookAttempt `json:"attemptLog"`
+	}
+	out := make([]deliveryLog, 0, len(deliveries))
+	for _, d := range deliveries {
+		attemptLog := byDelivery[d.DeliveryID]
+		if attemptLog == nil {
+			attemptLog = []WebhookAttempt{}
+		}
+		out = append(out, deliveryLog{WebhookDelivery: d, AttemptLog: attemptLog})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
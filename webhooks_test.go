@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_deliverPendingWebhooks(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	err = applyMigrations(db)
+	assert.NoError(t, err)
+
+	calls := 0
+	var signature, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		signature = r.Header.Get("X-LFG-Signature")
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	s := &Server{db: db}
+	assert.NoError(t, db.Create(&Webhook{Url: server.URL, Secret: "shh", Events: []string{webhookResultsUpdated}, Active: true}).Error)
+	assert.NoError(t, db.Create(&Webhook{Url: server.URL, Secret: "shh", Events: []string{webhookEventCreated}, Active: true}).Error)
+
+	s.emitWebhook(webhookResultsUpdated, map[string]string{"eventID": "2025-impact-fire-open"})
+
+	var deliveries []WebhookDelivery
+	assert.NoError(t, db.Find(&deliveries).Error)
+	assert.Len(t, deliveries, 1)
+
+	// First attempt fails and is rescheduled with backoff.
+	s.deliverPendingWebhooks(time.Now())
+	assert.NoError(t, db.First(&deliveries[0]).Error)
+	assert.Equal(t, webhookStatusPending, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.True(t, deliveries[0].NextAttemptAt.After(time.Now()))
+
+	// Nothing is due yet.
+	s.deliverPendingWebhooks(time.Now())
+	assert.Equal(t, 1, calls)
+
+	s.deliverPendingWebhooks(time.Now().Add(webhookBackoff(1)))
+	assert.NoError(t, db.First(&deliveries[0]).Error)
+	assert.Equal(t, webhookStatusDelivered, deliveries[0].Status)
+	assert.Equal(t, signWebhookPayload("shh", []byte(body)), signature)
+
+	var attempts []WebhookAttempt
+	assert.NoError(t, db.Order("attempt ASC").Find(&attempts).Error)
+	assert.Len(t, attempts, 2)
+	assert.Equal(t, http.StatusInternalServerError, attempts[0].StatusCode)
+	assert.Equal(t, http.StatusOK, attempts[1].StatusCode)
+}
+
+func Test_webhookBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhookBackoff(1))
+	assert.Equal(t, time.Minute, webhookBackoff(2))
+	assert.Equal(t, 4*time.Minute, webhookBackoff(4))
+}
+
+func TestServer_PUTWebhook(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, applyMigrations(db))
+	s := &Server{db: db}
+
+	hook := &Webhook{Url: "https://example.com/hook", Secret: "shh", Events: []string{webhookResultsUpdated}, Active: true}
+	assert.NoError(t, db.Create(hook).Error)
+
+	r := chi.NewRouter()
+	r.Put("/api/webhooks/{id}", s.PUTWebhook)
+	put := func(body string) {
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/webhooks/%d", hook.ID), strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	// Leaving out active keeps the hook enabled.
+	put(`{"url": "https://example.com/new", "events": ["*"]}`)
+	var updated Webhook
+	assert.NoError(t, db.First(&updated, hook.ID).Error)
+	assert.Equal(t, "https://example.com/new", updated.Url)
+	assert.True(t, updated.Active)
+	assert.Equal(t, "shh", updated.Secret)
+
+	put(`{"url": "https://example.com/new", "events": ["*"], "active": false}`)
+	updated = Webhook{}
+	assert.NoError(t, db.First(&updated, hook.ID).Error)
+	assert.False(t, updated.Active)
+}